@@ -0,0 +1,118 @@
+// Command mappings bulk loads id mappings from CSV or NDJSON files, reports
+// any rows which fail to import and writes the merged mappings back out in
+// either format, so it can be used to validate, convert and back up mapping
+// data.
+//
+//	mappings [-format csv|ndjson] [-out file] [-out-format csv|ndjson] [-batch n] file...
+//
+// Each input row holds a from id, a to id and an optional valid from and
+// valid to time (RFC3339 or yyyy-mm-dd).
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/distributed-vision/go-resources/ids"
+	_ "github.com/distributed-vision/go-resources/ids/identifier"
+	"github.com/distributed-vision/go-resources/ids/mappings"
+	"github.com/distributed-vision/go-resources/resolvers/localresolver"
+)
+
+func main() {
+	format := flag.String("format", "", "input format: csv or ndjson (default from file extension)")
+	out := flag.String("out", "", "output file (default stdout)")
+	outFormat := flag.String("out-format", "ndjson", "output format: csv or ndjson")
+	batchSize := flag.Int("batch", mappings.DefaultBatchSize, "number of rows per write batch")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: mappings [flags] file...")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	resolver, err := localresolver.New(localresolver.NewResolverInfo(
+		[]ids.TypeIdentifier{mappings.EntityType()}, nil, mappings.KeyExtractor, nil))
+
+	if err != nil {
+		fail(err)
+	}
+
+	mappings.RegisterResolver(resolver)
+
+	failed := 0
+
+	for _, file := range flag.Args() {
+		inFormat, err := fileFormat(file, *format)
+
+		if err != nil {
+			fail(err)
+		}
+
+		in, err := os.Open(file)
+
+		if err != nil {
+			fail(err)
+		}
+
+		report, err := mappings.Import(context.Background(), in,
+			mappings.ImportOpts{Format: inFormat, BatchSize: *batchSize})
+		in.Close()
+
+		if err != nil {
+			fail(fmt.Errorf("%s: %s", file, err))
+		}
+
+		for _, rowErr := range report.Errors {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", file, rowErr)
+		}
+
+		fmt.Fprintf(os.Stderr, "%s: %d rows, %d imported, %d failed\n",
+			file, report.Rows, report.Imported, len(report.Errors))
+		failed += len(report.Errors)
+	}
+
+	exportFormat, err := mappings.ParseFormat(*outFormat)
+
+	if err != nil {
+		fail(err)
+	}
+
+	output := os.Stdout
+
+	if *out != "" {
+		if output, err = os.Create(*out); err != nil {
+			fail(err)
+		}
+	}
+
+	if err = mappings.ExportAll(context.Background(), output, exportFormat); err != nil {
+		fail(err)
+	}
+
+	if err = output.Close(); err != nil {
+		fail(err)
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func fileFormat(file string, format string) (mappings.Format, error) {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(file), ".")
+	}
+
+	return mappings.ParseFormat(format)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "mappings:", err)
+	os.Exit(1)
+}
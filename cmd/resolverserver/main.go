@@ -0,0 +1,89 @@
+// Command resolverserver serves a directory of scheme and domain definition
+// files as a read only registry which httpresolver clients can resolve
+// from. Each json file in the directory, which maps entity ids to entity
+// definitions, is served under its name without the extension:
+//
+//	resolverserver [-addr :8080] [-dir path] [-private] [-reload interval]
+//
+// so that schemeinfo.json is served at /schemeinfo/ and its entities at
+// /schemeinfo/{id}. LOCAL and PRIVATE schemes are only served with -private.
+// With -reload the files are checked for changes at the given interval.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/resolvers/fileresolver"
+	"github.com/distributed-vision/go-resources/resolvers/resolverserver"
+	"github.com/distributed-vision/go-resources/types/gotypeid"
+)
+
+var contentType = gotypeid.IdOf(reflect.TypeOf(map[string]interface{}{}))
+
+func idExtractor(e ...interface{}) (interface{}, bool) {
+	if len(e) == 0 {
+		return nil, false
+	}
+
+	if entity, ok := e[0].(map[string]interface{}); ok {
+		id, ok := entity["id"].(string)
+		return id, ok
+	}
+
+	return nil, false
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	dir := flag.String("dir", os.Getenv("DV_DOMAIN_SCOPE_PATH"), "directory of json definition files")
+	private := flag.Bool("private", false, "serve LOCAL and PRIVATE schemes")
+	reload := flag.Duration("reload", 0, "interval to check files for changes at, 0 disables reloading")
+	flag.Parse()
+
+	if *dir == "" {
+		fmt.Fprintln(os.Stderr, "usage: resolverserver [flags]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	files, err := filepath.Glob(filepath.Join(*dir, "*.json"))
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := resolverserver.Options{EntityType: contentType}
+
+	if *private {
+		opts.Visible = func(request *http.Request, visibility ids.SchemeVisibility) bool {
+			return true
+		}
+	}
+
+	mux := http.NewServeMux()
+
+	values := map[interface{}]interface{}{"reloadInterval": *reload}
+
+	for _, file := range files {
+		resolver, err := fileresolver.New(file, fileresolver.NewResolverInfo(
+			[]ids.TypeIdentifier{contentType}, nil, idExtractor, values))
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		prefix := "/" + strings.TrimSuffix(filepath.Base(file), ".json") + "/"
+		mux.Handle(prefix, http.StripPrefix(prefix, resolverserver.New(resolver, opts)))
+		log.Printf("serving %s at %s", file, prefix)
+	}
+
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/distributed-vision/go-resources/encoding/encodertype"
 	"github.com/distributed-vision/go-resources/ids"
 	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/util"
 	"github.com/distributed-vision/go-resources/util/hton"
 	"github.com/distributed-vision/go-resources/util/ntoh"
 	"github.com/distributed-vision/go-resources/version/versiontype"
@@ -47,27 +48,7 @@ func KeyExtractor(entity ...interface{}) (interface{}, bool) {
 }
 
 func Await(cres chan ids.Domain, cerr chan error) (result ids.Domain, err error) {
-	if cres == nil || cerr == nil {
-		return nil, fmt.Errorf("Await Failed: channels are undefined")
-	}
-
-	resolved := false
-	for !resolved {
-		select {
-		case res, ok := <-cres:
-			if ok {
-				result = res
-				resolved = true
-			}
-		case error, ok := <-cerr:
-			if ok {
-				err = error
-				resolved = true
-			}
-		}
-	}
-
-	return result, err
+	return util.Await(cres, cerr)
 }
 
 var empty = []byte{}
@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
-	"strings"
 	"sync"
 
 	"github.com/distributed-vision/go-resources/encoding/base62"
@@ -42,6 +41,7 @@ func init() {
 
 		mapType := ids.NewLocalTypeId(reflect.TypeOf(map[string]interface{}{}))
 		translators.Register(context.Background(), mapType, domainEntityType, domainMapTranslator)
+		translators.Register(context.Background(), domainEntityType, mapType, mapDomainTranslator)
 
 		PublicResolverType, err = ids.NewTypeId(
 			MustDecodeId(encodertype.BASE62, "T", "0", uint32(0), uint(0), versiontype.SEMANTIC),
@@ -64,8 +64,17 @@ func domainMapTranslator(translationContext context.Context, fromId ids.Identifi
 	cres := make(chan interface{}, 1)
 	cerr := make(chan error, 1)
 
-	json := fromValue.(map[string]interface{})
-	json["id"] = string(fromId.Id())
+	json := make(map[string]interface{})
+
+	for key, value := range fromValue.(map[string]interface{}) {
+		json[key] = value
+	}
+
+	// maps written by mapDomainTranslator carry their root id, others are
+	// keyed by it
+	if _, ok := json["id"]; !ok && fromId != nil {
+		json["id"] = string(fromId.Id())
+	}
 
 	toValue, err := unmarshalJSON(translationContext, json)
 	//fmt.Printf("id: %+v val: %+v err: %s\n", fromId.Id(), toValue, err)
@@ -82,11 +91,51 @@ func domainMapTranslator(translationContext context.Context, fromId ids.Identifi
 	return cres, cerr
 }
 
-type SelectorOpts struct {
-	IgnoreCase       bool
-	IgnoreWhitespace bool
+// mapDomainTranslator translates a domain to the json map form read by
+// domainMapTranslator, the map carries the domain's scheme id so it can be
+// unmarshalled without a scheme in the context
+func mapDomainTranslator(translationContext context.Context, fromId ids.Identifier, fromValue interface{}) (chan interface{}, chan error) {
+	cres := make(chan interface{}, 1)
+	cerr := make(chan error, 1)
+
+	if domain, ok := fromValue.(ids.Domain); ok {
+		cres <- toJSON(domain)
+	} else {
+		cerr <- fmt.Errorf("Can't translate %T to a json map, expected: ids.Domain", fromValue)
+	}
+
+	close(cres)
+	close(cerr)
+
+	return cres, cerr
 }
 
+func toJSON(domain ids.Domain) map[string]interface{} {
+	json := map[string]interface{}{
+		"id":           base62.Encode(domain.IdRoot()),
+		"schemeId":     base62.Encode(domain.SchemeId()),
+		"hasPaths":     domain.HasPaths(),
+		"hasFragments": domain.HasFragments()}
+
+	if domainType, ok := domain.InfoValue("domainType").(string); ok {
+		json["domainType"] = domainType
+	} else if _, ok := domain.(ids.SignatureDomain); ok {
+		json["domainType"] = domaintype.String(domaintype.SIGNATURE)
+	} else {
+		json["domainType"] = domaintype.String(domaintype.IDENTITY)
+	}
+
+	for _, key := range []string{"name", "description", "source"} {
+		if value, ok := domain.InfoValue(key).(string); ok && value != "" {
+			json[key] = value
+		}
+	}
+
+	return json
+}
+
+type SelectorOpts = resolvers.SelectorOpts
+
 type Selector struct {
 	SchemeId []byte
 	IdRoot   []byte
@@ -115,16 +164,8 @@ func (this *Selector) Test(candidate interface{}) bool {
 		return false
 	}
 
-	if this.Name != "" {
-		if this.Opts.IgnoreCase {
-			if strings.ToUpper(this.Name) != strings.ToUpper(domain.Name()) {
-				return false
-			}
-		} else {
-			if this.Name != domain.Name() {
-				return false
-			}
-		}
+	if this.Name != "" && !this.Opts.Equal(this.Name, domain.Name()) {
+		return false
 	}
 
 	return true
@@ -184,6 +225,12 @@ func RegisterResolverFactory(resolverFactory resolvers.ResolverFactory) error {
 	return domainResolver.RegisterComponentFactory(resolverFactory, false)
 }
 
+// TypedResolver returns the domain resolver adapted to deliver ids.Domain
+// values
+func TypedResolver() *resolvers.TypedResolver[ids.Domain] {
+	return resolvers.NewTypedResolver[ids.Domain](domainResolver)
+}
+
 func Get(resolutionContext context.Context, selector Selector) (domain ids.Domain, err error) {
 	return Await(Resolve(resolutionContext, selector))
 }
@@ -214,14 +261,10 @@ func Resolve(resolutionContext context.Context, selector Selector) (chan ids.Dom
 			}
 		}
 
-		res, err := util.Await(domainResolver.Resolve(resolutionContext, &selector))
+		domain, err := TypedResolver().Get(resolutionContext, &selector)
 
 		if err == nil {
-			if domain, ok := res.(ids.Domain); ok {
-				cResOut <- domain
-			} else {
-				cErrOut <- fmt.Errorf("Resolver returned invalid type, expected: ids.Domain got: %s", reflect.TypeOf(res))
-			}
+			cResOut <- domain
 		} else {
 			cErrOut <- err
 		}
@@ -233,6 +276,42 @@ func Resolve(resolutionContext context.Context, selector Selector) (chan ids.Dom
 	return cResOut, cErrOut
 }
 
+// Query streams all of the domains matching selector, if the selector has a
+// SchemeId the scheme's domain resolvers are loaded before the query is run
+func Query(queryContext context.Context, selector Selector, opts resolvers.QueryOpts) (chan ids.Domain, chan error) {
+	cResOut := make(chan ids.Domain)
+	cErrOut := make(chan error, 1)
+
+	go func() {
+		defer close(cErrOut)
+		defer close(cResOut)
+
+		if selector.SchemeId != nil {
+			_, err := util.Await(resolvers.Resolve(queryContext, &schemeSelector{id: selector.SchemeId}))
+
+			if err != nil {
+				cErrOut <- err
+				return
+			}
+		}
+
+		cres, cerr := TypedResolver().Query(queryContext, &selector, opts)
+
+		for domain := range cres {
+			select {
+			case cResOut <- domain:
+			case <-queryContext.Done():
+			}
+		}
+
+		if err, ok := <-cerr; ok && err != nil {
+			cErrOut <- err
+		}
+	}()
+
+	return cResOut, cErrOut
+}
+
 type unmarshaller func(unmarshalContext context.Context, json map[string]interface{}) (ids.Domain, error)
 
 var unmarshalers map[ids.DomainType]unmarshaller = make(map[ids.DomainType]unmarshaller)
@@ -242,7 +321,8 @@ func RegisterJSONUnmarshaller(domainType ids.DomainType, unmarshaller unmarshall
 }
 
 func unmarshalJSON(unmarshalContext context.Context, json map[string]interface{}) (ids.Domain, error) {
-	dt, err := domaintype.Parse(json["domainType"].(string))
+	domainTypeName, _ := json["domainType"].(string)
+	dt, err := domaintype.Parse(domainTypeName)
 	//fmt.Printf("dt=%v\n", dt)
 	if err != nil {
 		return nil, err
@@ -251,7 +331,7 @@ func unmarshalJSON(unmarshalContext context.Context, json map[string]interface{}
 	unmarshaler, ok := unmarshalers[dt]
 
 	if !ok {
-		return nil, errors.New("Unknown domain type: " + json["domainType"].(string))
+		return nil, errors.New("Unknown domain type: " + domainTypeName)
 	}
 
 	return unmarshaler(unmarshalContext, json)
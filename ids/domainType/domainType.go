@@ -18,7 +18,7 @@ func Parse(domainTypeName string) (ids.DomainType, error) {
 	switch strings.ToUpper(domainTypeName) {
 	case "SCOPE":
 		return SCOPE, nil
-	case "INDENTITY":
+	case "IDENTITY", "INDENTITY":
 		return IDENTITY, nil
 	case "SIGNATURE":
 		return SIGNATURE, nil
@@ -28,3 +28,18 @@ func Parse(domainTypeName string) (ids.DomainType, error) {
 
 	return -1, errors.New("Unknown domain type: " + domainTypeName)
 }
+
+func String(domainType ids.DomainType) string {
+	switch domainType {
+	case SCOPE:
+		return "scope"
+	case IDENTITY:
+		return "identity"
+	case SIGNATURE:
+		return "signature"
+	case SEQUENCE:
+		return "sequence"
+	default:
+		return "invalid"
+	}
+}
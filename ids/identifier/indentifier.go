@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"hash/crc32"
+	"strings"
 	"time"
 
 	"github.com/distributed-vision/go-resources/encoding"
@@ -14,6 +15,7 @@ import (
 	"github.com/distributed-vision/go-resources/ids/domain"
 	"github.com/distributed-vision/go-resources/ids/mappings"
 	"github.com/distributed-vision/go-resources/ids/scheme"
+	"github.com/distributed-vision/go-resources/util"
 	"github.com/distributed-vision/go-resources/util/hton"
 	"github.com/distributed-vision/go-resources/util/ntoh"
 	"github.com/distributed-vision/go-resources/version"
@@ -22,31 +24,15 @@ import (
 	"github.com/sigurn/crc8"
 )
 
+func init() {
+	mappings.RegisterIdParser(Parse)
+}
+
 func Init() {
 }
 
 func Await(cres chan ids.Identifier, cerr chan error) (result ids.Identifier, err error) {
-	if cres == nil || cerr == nil {
-		return nil, fmt.Errorf("Await Failed: channels are undefined")
-	}
-
-	resolved := false
-	for !resolved {
-		select {
-		case res, ok := <-cres:
-			if ok {
-				result = res
-				resolved = true
-			}
-		case error, ok := <-cerr:
-			if ok {
-				err = error
-				resolved = true
-			}
-		}
-	}
-
-	return result, err
+	return util.Await(cres, cerr)
 }
 
 type identifier struct {
@@ -244,8 +230,49 @@ func Unwrap(id ids.Identifier) []byte {
 	return id.Value()
 }
 
+// Parse decodes an identifier from either its base62 string form, or from
+// the domain and id parts of its seperated form (domain:id)
 func Parse(id string) (ids.Identifier, error) {
-	return nil, nil
+	parts := strings.Split(strings.TrimSpace(id), ":")
+
+	switch len(parts) {
+	case 1:
+		value, err := encoding.Decode(parts[0], encodertype.BASE62)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid id encoding: %s", err)
+		}
+
+		if len(value) == 0 {
+			return nil, errors.New("Invalid id: undefined")
+		}
+
+		if uint(len(value)) <= domain.DomainOffset(value)+domain.DomainLength(value) {
+			return nil, fmt.Errorf("Invalid id: too short: %v", value)
+		}
+
+		return Wrap(value), nil
+	case 2:
+		domainId, err := encoding.Decode(parts[0], encodertype.BASE62)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid domain encoding: %s", err)
+		}
+
+		if len(domainId) == 0 {
+			return nil, errors.New("Invalid domain: id undefined")
+		}
+
+		idValue, err := encoding.Decode(parts[1], encodertype.BASE62)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid id encoding: %s", err)
+		}
+
+		return New(domainId, idValue)
+	default:
+		return nil, fmt.Errorf("Invalid id: can't parse: %s", id)
+	}
 }
 
 func AsLocator(id ids.Identifier) ids.Locator {
@@ -58,7 +58,31 @@ func unmarshalJSON(unmarshalContext context.Context, json map[string]interface{}
 		info[key] = value
 	}
 
-	return New(unmarshalContext.Value("scheme").(ids.Scheme), rootId, incarnation, crcLength, versionType, hasPaths, hasFragments, info)
+	if scheme, ok := unmarshalContext.Value("scheme").(ids.Scheme); ok {
+		return New(scheme, rootId, incarnation, crcLength, versionType, hasPaths, hasFragments, info)
+	}
+
+	// domains stored outside their scheme's files carry their scheme id
+	schemeIdValue, ok := json["schemeId"].(string)
+
+	if !ok {
+		return nil, errors.New("Can't unmarshal domain without a scheme")
+	}
+
+	schemeId, err := base62.Decode(schemeIdValue)
+
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := domain.New(schemeId, rootId, incarnation, crcLength, versionType, hasPaths, hasFragments, info)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &identityDomain{
+		Domain: base}, nil
 }
 
 func New(scheme ids.Scheme, rootId []byte, incarnation *uint32, crcLength uint, versionType versiontype.VersionType, hasPaths bool, hasFragments bool, infos ...map[interface{}]interface{}) (ids.IdentityDomain, error) {
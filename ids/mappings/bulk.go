@@ -0,0 +1,599 @@
+package mappings
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/ids/domain"
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/util"
+)
+
+type Format int
+
+const (
+	CSV Format = iota
+	NDJSON
+)
+
+func ParseFormat(format string) (Format, error) {
+	switch strings.ToUpper(format) {
+	case "CSV":
+		return CSV, nil
+	case "NDJSON", "JSONL":
+		return NDJSON, nil
+	default:
+		return -1, errors.New("Unknown mapping format: " + format)
+	}
+}
+
+var DefaultBatchSize = 1000
+
+var parseId func(id string) (ids.Identifier, error)
+
+// RegisterIdParser sets the parser used to decode ids read by Import, it is
+// called by the identifier package when it is initialised
+func RegisterIdParser(parser func(id string) (ids.Identifier, error)) {
+	parseId = parser
+}
+
+type Row struct {
+	Line      int
+	From      ids.Identifier
+	To        ids.Identifier
+	ValidFrom time.Time
+	ValidTo   time.Time
+}
+
+func (this *Row) key() string {
+	return this.From.String() + "->" + this.toDomain().String()
+}
+
+func (this *Row) toDomain() ids.IdentityDomain {
+	return domain.Wrap(this.To.DomainId())
+}
+
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("Row %d: %s", e.Line, e.Err)
+}
+
+type jsonRow struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	ValidFrom string `json:"validFrom,omitempty"`
+	ValidTo   string `json:"validTo,omitempty"`
+}
+
+var csvHeader = []string{"from", "to", "validFrom", "validTo"}
+
+type RowReader struct {
+	format  Format
+	parseId func(id string) (ids.Identifier, error)
+	csv     *csv.Reader
+	scanner *bufio.Scanner
+	line    int
+}
+
+func NewRowReader(in io.Reader, format Format, parseId func(id string) (ids.Identifier, error)) *RowReader {
+	reader := &RowReader{format: format, parseId: parseId}
+
+	switch format {
+	case CSV:
+		reader.csv = csv.NewReader(in)
+		reader.csv.FieldsPerRecord = -1
+		reader.csv.TrimLeadingSpace = true
+	default:
+		reader.scanner = bufio.NewScanner(in)
+		reader.scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	}
+
+	return reader
+}
+
+// Read returns the next row from the input, it returns io.EOF when the input
+// is exhausted. Errors for individual rows are returned as *RowError after
+// which reading may continue
+func (this *RowReader) Read() (*Row, error) {
+	if this.parseId == nil {
+		return nil, fmt.Errorf("No id parser registered")
+	}
+
+	switch this.format {
+	case CSV:
+		return this.readCSV()
+	case NDJSON:
+		return this.readNDJSON()
+	default:
+		return nil, fmt.Errorf("Unknown mapping format: %v", this.format)
+	}
+}
+
+func (this *RowReader) readCSV() (*Row, error) {
+	for {
+		record, err := this.csv.Read()
+
+		if err != nil {
+			if parseErr, ok := err.(*csv.ParseError); ok {
+				return nil, &RowError{parseErr.Line, parseErr.Err}
+			}
+			return nil, err
+		}
+
+		line, _ := this.csv.FieldPos(0)
+		this.line++
+
+		if this.line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), csvHeader[0]) {
+			continue
+		}
+
+		if len(record) < 2 || len(record) > 4 {
+			return nil, &RowError{line, fmt.Errorf("Expected 2 to 4 fields got: %d", len(record))}
+		}
+
+		for len(record) < 4 {
+			record = append(record, "")
+		}
+
+		return this.toRow(line, record[0], record[1], record[2], record[3])
+	}
+}
+
+func (this *RowReader) readNDJSON() (*Row, error) {
+	for this.scanner.Scan() {
+		this.line++
+		data := bytes.TrimSpace(this.scanner.Bytes())
+
+		if len(data) == 0 {
+			continue
+		}
+
+		var row jsonRow
+
+		if err := json.Unmarshal(data, &row); err != nil {
+			return nil, &RowError{this.line, err}
+		}
+
+		return this.toRow(this.line, row.From, row.To, row.ValidFrom, row.ValidTo)
+	}
+
+	if err := this.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
+
+func (this *RowReader) toRow(line int, from, to, validFrom, validTo string) (*Row, error) {
+	var err error
+	row := &Row{Line: line}
+
+	if row.From, err = this.parseId(from); err != nil {
+		return nil, &RowError{line, fmt.Errorf("Invalid from id: %s", err)}
+	}
+
+	if row.To, err = this.parseId(to); err != nil {
+		return nil, &RowError{line, fmt.Errorf("Invalid to id: %s", err)}
+	}
+
+	if row.ValidFrom, err = parseTime(validFrom, MinTime); err != nil {
+		return nil, &RowError{line, fmt.Errorf("Invalid valid from time: %s", err)}
+	}
+
+	if row.ValidTo, err = parseTime(validTo, MaxTime); err != nil {
+		return nil, &RowError{line, fmt.Errorf("Invalid valid to time: %s", err)}
+	}
+
+	if !row.ValidFrom.Before(row.ValidTo) {
+		return nil, &RowError{line, fmt.Errorf("Valid from: %v is not before valid to: %v", row.ValidFrom, row.ValidTo)}
+	}
+
+	return row, nil
+}
+
+func parseTime(value string, undefined time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if value == "" {
+		return undefined, nil
+	}
+
+	if result, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return result, nil
+	}
+
+	return time.Parse("2006-01-02", value)
+}
+
+func formatTime(value time.Time, undefined time.Time) string {
+	if value.Equal(undefined) {
+		return ""
+	}
+
+	return value.UTC().Format(time.RFC3339Nano)
+}
+
+type RowWriter struct {
+	format Format
+	out    io.Writer
+	csv    *csv.Writer
+}
+
+func NewRowWriter(out io.Writer, format Format) *RowWriter {
+	writer := &RowWriter{format: format, out: out}
+
+	if format == CSV {
+		writer.csv = csv.NewWriter(out)
+	}
+
+	return writer
+}
+
+func (this *RowWriter) Write(row *Row) error {
+	from := row.From.String()
+	to := row.To.String()
+	validFrom := formatTime(row.ValidFrom, MinTime)
+	validTo := formatTime(row.ValidTo, MaxTime)
+
+	switch this.format {
+	case CSV:
+		if this.csv == nil {
+			return fmt.Errorf("Row writer is not initialised")
+		}
+		return this.csv.Write([]string{from, to, validFrom, validTo})
+	case NDJSON:
+		data, err := json.Marshal(&jsonRow{from, to, validFrom, validTo})
+
+		if err != nil {
+			return err
+		}
+
+		_, err = this.out.Write(append(data, '\n'))
+		return err
+	default:
+		return fmt.Errorf("Unknown mapping format: %v", this.format)
+	}
+}
+
+func (this *RowWriter) WriteHeader() error {
+	if this.format == CSV && this.csv != nil {
+		return this.csv.Write(csvHeader)
+	}
+
+	return nil
+}
+
+func (this *RowWriter) Flush() error {
+	if this.csv != nil {
+		this.csv.Flush()
+		return this.csv.Error()
+	}
+
+	return nil
+}
+
+type ImportOpts struct {
+	Format    Format
+	BatchSize int
+	ParseId   func(id string) (ids.Identifier, error)
+}
+
+type ImportReport struct {
+	Rows     int
+	Imported int
+	Written  int
+	Errors   []*RowError
+}
+
+// Import streams mapping rows from in and writes them to the mutable mapping
+// resolvers in batches, rows sharing a from id and to domain are merged into
+// a single write per batch. Row level failures are recorded in the returned
+// report, the error is only set if the import could not be completed
+func Import(importContext context.Context, in io.Reader, opts ImportOpts) (*ImportReport, error) {
+	report := &ImportReport{Errors: []*RowError{}}
+
+	if opts.ParseId == nil {
+		opts.ParseId = parseId
+	}
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	reader := NewRowReader(in, opts.Format, opts.ParseId)
+	batch := make([]*Row, 0, opts.BatchSize)
+
+	for {
+		if err := importContext.Err(); err != nil {
+			return report, err
+		}
+
+		row, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			if rowErr, ok := err.(*RowError); ok {
+				report.Rows++
+				report.Errors = append(report.Errors, rowErr)
+				continue
+			}
+
+			return report, err
+		}
+
+		report.Rows++
+		batch = append(batch, row)
+
+		if len(batch) >= opts.BatchSize {
+			writeBatch(importContext, batch, report)
+			batch = batch[:0]
+		}
+	}
+
+	if len(batch) > 0 {
+		writeBatch(importContext, batch, report)
+	}
+
+	return report, nil
+}
+
+func writeBatch(importContext context.Context, batch []*Row, report *ImportReport) {
+	groups := make(map[string][]*Row)
+	keys := []string{}
+
+	for _, row := range batch {
+		key := row.key()
+
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		groups[key] = append(groups[key], row)
+	}
+
+	for _, key := range keys {
+		rows := groups[key]
+		var err error
+
+		mutableResolvers := mappingResolver.GetMutableComponents(importContext,
+			&Selector{From: rows[0].From, To: rows[0].toDomain()})
+
+		if len(mutableResolvers) == 0 {
+			err = fmt.Errorf("No mutable mapping resolvers installed for: %s: %w",
+				domain.Wrap(rows[0].From.DomainId()), resolvers.NewNoResolverForType(mappingsEntityType))
+		} else {
+			err = writeRows(importContext, mutableResolvers[0], rows)
+		}
+
+		if err != nil {
+			for _, row := range rows {
+				report.Errors = append(report.Errors, &RowError{row.Line, err})
+			}
+		} else {
+			report.Imported += len(rows)
+			report.Written++
+		}
+	}
+}
+
+// writeRows merges rows which share the same from id and to domain into the
+// stored mappings entity and writes it back with a single Put or Post.
+// BulkMappingResolvers are passed the rows in one call, and MappingResolvers
+// which aren't mutable are called once per row
+func writeRows(writeContext context.Context, resolver resolvers.Resolver, rows []*Row) error {
+	if bulkResolver, ok := resolver.(BulkMappingResolver); ok {
+		return util.AwaitError(bulkResolver.MapRows(writeContext, rows))
+	}
+
+	if _, mutable := resolver.(resolvers.MutableResolver); !mutable {
+		if mappingResolver, ok := resolver.(MappingResolver); ok {
+			for _, row := range rows {
+				err := util.AwaitError(mappingResolver.Map(writeContext, row.From, row.To, row.ValidFrom, row.ValidTo))
+
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+	}
+
+	from, to := rows[0].From, rows[0].toDomain()
+
+	// resolvers which track revisions merge the rows into the current
+	// mappings conditionally, so concurrent writes can't lose each other's
+	// intervals
+	if revisionedResolver, ok := resolver.(resolvers.RevisionedResolver); ok {
+		_, err := resolvers.Update(writeContext, revisionedResolver, &keySelector{from, to}, func(current interface{}) (interface{}, error) {
+			return mergeRows(from, to, current, rows)
+		})
+
+		return err
+	}
+
+	mutableResolver, ok := resolver.(resolvers.MutableResolver)
+
+	if !ok {
+		return fmt.Errorf("Resolver is not mutable: %v", resolver.ResolverInfo().ResolverType())
+	}
+
+	result, err := mutableResolver.Get(writeContext, &keySelector{from, to})
+	exists := err == nil
+
+	if err != nil {
+		if !resolvers.IsNotFound(err) {
+			return err
+		}
+	}
+
+	if !exists {
+		result = nil
+	}
+
+	merged, err := mergeRows(from, to, result, rows)
+
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		_, err = mutableResolver.Post(writeContext, merged)
+	} else {
+		_, err = mutableResolver.Put(writeContext, merged)
+	}
+
+	return err
+}
+
+// mergeRows returns the mappings from from to to with rows merged into
+// current, which is nil if there are no mappings
+func mergeRows(from ids.Identifier, to ids.IdentityDomain, current interface{}, rows []*Row) (interface{}, error) {
+	var mappedIds []mappedId
+
+	if current != nil {
+		currentMappings, ok := current.(Mappings)
+
+		if !ok {
+			return nil, fmt.Errorf("Resolver returned invalid type, expected: mappings.Mappings got: %T", current)
+		}
+
+		mappedIds = append(mappedIds, currentMappings.mappedIds...)
+	}
+
+	for _, row := range rows {
+		mappedIds = mergeMappedId(mappedIds, mappedId{row.ValidFrom, row.ValidTo, row.To})
+	}
+
+	return Mappings{from, to, mappedIds}, nil
+}
+
+// mergeMappedId inserts mid into mids which is ordered by start time, the
+// parts of existing mappings which overlap mid's interval are replaced by it
+func mergeMappedId(mids []mappedId, mid mappedId) []mappedId {
+	merged := make([]mappedId, 0, len(mids)+2)
+
+	for _, existing := range mids {
+		if !existing.to.After(mid.from) || !existing.from.Before(mid.to) {
+			merged = append(merged, existing)
+			continue
+		}
+
+		if existing.from.Before(mid.from) {
+			merged = append(merged, mappedId{existing.from, mid.from, existing.id})
+		}
+
+		if existing.to.After(mid.to) {
+			merged = append(merged, mappedId{mid.to, existing.to, existing.id})
+		}
+	}
+
+	merged = append(merged, mid)
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].from.Before(merged[j].from)
+	})
+
+	return merged
+}
+
+type keySelector struct {
+	from ids.Identifier
+	to   ids.IdentityDomain
+}
+
+func (this *keySelector) Type() ids.TypeIdentifier {
+	return mappingsEntityType
+}
+
+func (this *keySelector) Key() interface{} {
+	return this.from.String() + "->" + this.to.String()
+}
+
+func (this *keySelector) Test(candidate interface{}) bool {
+	mappings, ok := candidate.(Mappings)
+
+	return ok && this.from.Equals(mappings.fromId) &&
+		bytes.Equal(this.to.Id(), mappings.toDomain.Id())
+}
+
+// Enumerable is implemented by mapping stores which can list their content,
+// it is used by ExportAll to back up the installed mutable mapping resolvers
+type Enumerable interface {
+	ForEach(callback func(key interface{}, entity interface{}))
+}
+
+func (this *Mappings) rows() []*Row {
+	rows := make([]*Row, len(this.mappedIds))
+
+	for index, mid := range this.mappedIds {
+		rows[index] = &Row{From: this.fromId, To: mid.id, ValidFrom: mid.from, ValidTo: mid.to}
+	}
+
+	return rows
+}
+
+func Export(exportContext context.Context, out io.Writer, format Format, entities ...Mappings) error {
+	sorted := make([]Mappings, len(entities))
+	copy(sorted, entities)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].fromId.String()+"->"+sorted[i].toDomain.String() <
+			sorted[j].fromId.String()+"->"+sorted[j].toDomain.String()
+	})
+
+	writer := NewRowWriter(out, format)
+
+	if err := writer.WriteHeader(); err != nil {
+		return err
+	}
+
+	for _, entity := range sorted {
+		if err := exportContext.Err(); err != nil {
+			return err
+		}
+
+		for _, row := range entity.rows() {
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	return writer.Flush()
+}
+
+// ExportAll writes the content of all of the installed mutable mapping
+// resolvers which implement Enumerable to out
+func ExportAll(exportContext context.Context, out io.Writer, format Format) error {
+	var entities []Mappings
+
+	for _, resolver := range mappingResolver.GetMutableComponents(exportContext, &Selector{}) {
+		if enumerable, ok := resolver.(Enumerable); ok {
+			enumerable.ForEach(func(key interface{}, entity interface{}) {
+				if mappings, ok := entity.(Mappings); ok {
+					entities = append(entities, mappings)
+				}
+			})
+		}
+	}
+
+	return Export(exportContext, out, format, entities...)
+}
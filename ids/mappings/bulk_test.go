@@ -0,0 +1,227 @@
+package mappings_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/distributed-vision/go-resources/encoding/encodertype"
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/ids/domain"
+	"github.com/distributed-vision/go-resources/ids/identifier"
+	"github.com/distributed-vision/go-resources/ids/mappings"
+	"github.com/distributed-vision/go-resources/resolvers/localresolver"
+	"github.com/distributed-vision/go-resources/util/random"
+)
+
+var testContext = context.Background()
+var fromDomain = domain.MustDecodeId(encodertype.BASE62, "3", "")
+var toDomain = domain.MustDecodeId(encodertype.BASE62, "3", "1")
+
+func TestMain(m *testing.M) {
+	resolver, err := localresolver.New(localresolver.NewResolverInfo(
+		[]ids.TypeIdentifier{mappings.EntityType()}, nil, mappings.KeyExtractor, nil))
+
+	if err != nil {
+		panic(err)
+	}
+
+	mappings.RegisterResolver(resolver)
+	os.Exit(m.Run())
+}
+
+func mustId(domainId []byte, id string) ids.Identifier {
+	result, err := identifier.New(domainId, []byte(id))
+
+	if err != nil {
+		panic(err)
+	}
+
+	return result
+}
+
+func exportedRows(t *testing.T, format mappings.Format, from ...ids.Identifier) []*mappings.Row {
+	var out bytes.Buffer
+
+	if err := mappings.ExportAll(testContext, &out, format); err != nil {
+		t.Fatal("ExportAll failed:", err)
+	}
+
+	reader := mappings.NewRowReader(&out, format, identifier.Parse)
+	rows := []*mappings.Row{}
+
+	for {
+		row, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatal("Reading export failed:", err)
+		}
+
+		for _, id := range from {
+			if row.From.Equals(id) {
+				rows = append(rows, row)
+			}
+		}
+	}
+
+	return rows
+}
+
+func TestImportExport(t *testing.T) {
+	a, b := mustId(fromDomain, random.RandomString(8)), mustId(fromDomain, random.RandomString(8))
+	x, y := mustId(toDomain, random.RandomString(8)), mustId(toDomain, random.RandomString(8))
+
+	csvIn := strings.Join([]string{
+		"from,to,validFrom,validTo",
+		a.String() + "," + x.String() + ",,2018-01-01",
+		a.String() + "," + y.String() + ",2018-01-01,",
+		"not-an-id," + x.String(),
+		b.String() + "," + y.String() + ",2019-01-01,2018-01-01",
+		b.String(),
+		b.String() + "," + y.String() + ",2018-01-01T10:00:00Z,2019-01-01T10:00:00Z",
+	}, "\n")
+
+	report, err := mappings.Import(testContext, strings.NewReader(csvIn),
+		mappings.ImportOpts{Format: mappings.CSV, BatchSize: 2})
+
+	if err != nil {
+		t.Fatal("TestImportExport: Import failed:", err)
+	}
+
+	if report.Rows != 6 || report.Imported != 3 || len(report.Errors) != 3 {
+		t.Fatalf("TestImportExport: unexpected report: rows=%d imported=%d errors=%v",
+			report.Rows, report.Imported, report.Errors)
+	}
+
+	for _, rowErr := range report.Errors {
+		if rowErr.Line != 4 && rowErr.Line != 5 && rowErr.Line != 6 {
+			t.Errorf("TestImportExport: unexpected error line: %v", rowErr)
+		}
+	}
+
+	rows := exportedRows(t, mappings.NDJSON, a, b)
+
+	if len(rows) != 3 {
+		t.Fatalf("TestImportExport: expected 3 exported rows got: %d", len(rows))
+	}
+
+	rows = exportedRows(t, mappings.NDJSON, a)
+
+	if len(rows) != 2 {
+		t.Fatalf("TestImportExport: expected 2 exported rows for a got: %d", len(rows))
+	}
+
+	if !rows[0].To.Equals(x) ||
+		!rows[0].ValidFrom.Equal(mappings.MinTime) ||
+		!rows[0].ValidTo.Equal(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("TestImportExport: unexpected first row: %+v", rows[0])
+	}
+
+	if !rows[1].To.Equals(y) || !rows[1].ValidTo.Equal(mappings.MaxTime) {
+		t.Errorf("TestImportExport: unexpected second row: %+v", rows[1])
+	}
+
+	var backup bytes.Buffer
+
+	if err := mappings.ExportAll(testContext, &backup, mappings.CSV); err != nil {
+		t.Fatal("TestImportExport: ExportAll failed:", err)
+	}
+
+	report, err = mappings.Import(testContext, &backup, mappings.ImportOpts{Format: mappings.CSV})
+
+	if err != nil || len(report.Errors) != 0 {
+		t.Fatal("TestImportExport: re-import failed:", err, report.Errors)
+	}
+
+	if rows := exportedRows(t, mappings.CSV, a, b); len(rows) != 3 {
+		t.Fatalf("TestImportExport: re-import should not duplicate rows got: %d", len(rows))
+	}
+}
+
+func TestMap(t *testing.T) {
+	c, z := mustId(fromDomain, random.RandomString(8)), mustId(toDomain, random.RandomString(8))
+
+	if err := <-mappings.Map(testContext, c, z); err != nil {
+		t.Fatal("TestMap: Map failed:", err)
+	}
+
+	count := 0
+
+	for _, row := range exportedRows(t, mappings.NDJSON, c) {
+		if row.To.Equals(z) {
+			count++
+		}
+	}
+
+	if count != 1 {
+		t.Fatalf("TestMap: expected 1 mapping got: %d", count)
+	}
+}
+
+func TestConcurrentMap(t *testing.T) {
+	c := mustId(fromDomain, random.RandomString(8))
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	errs := make(chan error, 8)
+
+	for i := 0; i < 8; i++ {
+		to, after := mustId(toDomain, random.RandomString(8)), start.AddDate(0, i, 0)
+		go func() {
+			errs <- <-mappings.Map(testContext, c, to, after, after.AddDate(0, 1, 0))
+		}()
+	}
+
+	for i := 0; i < 8; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal("TestConcurrentMap: Map failed:", err)
+		}
+	}
+
+	if rows := exportedRows(t, mappings.NDJSON, c); len(rows) != 8 {
+		t.Fatalf("TestConcurrentMap: expected 8 mappings got: %d", len(rows))
+	}
+}
+
+func TestImportOverlap(t *testing.T) {
+	a := mustId(fromDomain, random.RandomString(8))
+	x, y := mustId(toDomain, random.RandomString(8)), mustId(toDomain, random.RandomString(8))
+
+	for _, csvIn := range []string{
+		"from,to,validFrom,validTo\n" + a.String() + "," + x.String() + ",2018-01-01,2020-01-01",
+		"from,to,validFrom,validTo\n" + a.String() + "," + y.String() + ",2019-01-01,2019-06-01"} {
+		if report, err := mappings.Import(testContext, strings.NewReader(csvIn), mappings.ImportOpts{Format: mappings.CSV}); err != nil || len(report.Errors) != 0 {
+			t.Fatal("TestImportOverlap: Import failed:", err, report)
+		}
+	}
+
+	date := func(year int, month time.Month) time.Time {
+		return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	expected := []struct {
+		to                 ids.Identifier
+		validFrom, validTo time.Time
+	}{
+		{x, date(2018, 1), date(2019, 1)},
+		{y, date(2019, 1), date(2019, 6)},
+		{x, date(2019, 6), date(2020, 1)}}
+
+	rows := exportedRows(t, mappings.NDJSON, a)
+
+	if len(rows) != len(expected) {
+		t.Fatalf("TestImportOverlap: expected %d rows got: %d", len(expected), len(rows))
+	}
+
+	for index, row := range rows {
+		if !row.To.Equals(expected[index].to) || !row.ValidFrom.Equal(expected[index].validFrom) || !row.ValidTo.Equal(expected[index].validTo) {
+			t.Errorf("TestImportOverlap: unexpected row %d: %+v", index, row)
+		}
+	}
+}
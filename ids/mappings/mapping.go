@@ -1,10 +1,14 @@
 package mappings
 
 import (
+	"context"
 	"fmt"
 	"time"
 
+	"github.com/distributed-vision/go-resources/encoding/base62"
 	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/ids/domain"
+	"github.com/distributed-vision/go-resources/util"
 )
 
 func KeyExtractor(entity ...interface{}) (interface{}, bool) {
@@ -17,27 +21,7 @@ func KeyExtractor(entity ...interface{}) (interface{}, bool) {
 }
 
 func AwaitMapping(cres chan ids.Mapping, cerr chan error) (result ids.Mapping, err error) {
-	if cres == nil || cerr == nil {
-		return nil, fmt.Errorf("Await Failed: channels are undefined")
-	}
-
-	resolved := false
-	for !resolved {
-		select {
-		case res, ok := <-cres:
-			if ok {
-				result = res
-				resolved = true
-			}
-		case error, ok := <-cerr:
-			if ok {
-				err = error
-				resolved = true
-			}
-		}
-	}
-
-	return result, err
+	return util.Await(cres, cerr)
 }
 
 var MaxTime = time.Unix(1<<63-62135596801, 999999999)
@@ -55,6 +39,104 @@ type Mappings struct {
 	mappedIds []mappedId
 }
 
+func mapMappingsTranslator(translationContext context.Context, fromId ids.Identifier, fromValue interface{}) (chan interface{}, chan error) {
+	cres := make(chan interface{}, 1)
+	cerr := make(chan error, 1)
+
+	if mappings, ok := fromValue.(Mappings); ok {
+		cres <- mappings.toMap()
+	} else {
+		cerr <- fmt.Errorf("Can't translate %T to a json map, expected: mappings.Mappings", fromValue)
+	}
+
+	close(cres)
+	close(cerr)
+
+	return cres, cerr
+}
+
+// toMap returns the json map form of the mappings, ids and times are
+// encoded as they are in exported rows
+func (this Mappings) toMap() map[string]interface{} {
+	mappedIds := make([]interface{}, len(this.mappedIds))
+
+	for index, mid := range this.mappedIds {
+		mappedIds[index] = map[string]interface{}{
+			"to":        mid.id.String(),
+			"validFrom": formatTime(mid.from, MinTime),
+			"validTo":   formatTime(mid.to, MaxTime)}
+	}
+
+	return map[string]interface{}{
+		"from":      this.fromId.String(),
+		"to":        this.toDomain.String(),
+		"mappedIds": mappedIds}
+}
+
+// fromMap decodes mappings from the json map form returned by toMap
+func fromMap(value interface{}) (Mappings, error) {
+	json, ok := value.(map[string]interface{})
+
+	if !ok {
+		return Mappings{}, fmt.Errorf("Can't translate %T to mappings.Mappings, expected a json map", value)
+	}
+
+	if parseId == nil {
+		return Mappings{}, fmt.Errorf("No id parser registered")
+	}
+
+	from, _ := json["from"].(string)
+	fromId, err := parseId(from)
+
+	if err != nil {
+		return Mappings{}, fmt.Errorf("Invalid from id: %s", err)
+	}
+
+	to, _ := json["to"].(string)
+	toDomain, err := base62.Decode(to)
+
+	if err != nil || len(toDomain) == 0 {
+		return Mappings{}, fmt.Errorf("Invalid to domain: %s", to)
+	}
+
+	jsonIds, _ := json["mappedIds"].([]interface{})
+	mappedIds := make([]mappedId, 0, len(jsonIds))
+
+	for _, jsonId := range jsonIds {
+		mid, ok := jsonId.(map[string]interface{})
+
+		if !ok {
+			return Mappings{}, fmt.Errorf("Invalid mapped id: %v", jsonId)
+		}
+
+		to, _ := mid["to"].(string)
+		validFrom, _ := mid["validFrom"].(string)
+		validTo, _ := mid["validTo"].(string)
+
+		id, err := parseId(to)
+
+		if err != nil {
+			return Mappings{}, fmt.Errorf("Invalid to id: %s", err)
+		}
+
+		fromTime, err := parseTime(validFrom, MinTime)
+
+		if err != nil {
+			return Mappings{}, fmt.Errorf("Invalid valid from time: %s", err)
+		}
+
+		toTime, err := parseTime(validTo, MaxTime)
+
+		if err != nil {
+			return Mappings{}, fmt.Errorf("Invalid valid to time: %s", err)
+		}
+
+		mappedIds = append(mappedIds, mappedId{fromTime, toTime, id})
+	}
+
+	return Mappings{fromId, domain.Wrap(toDomain), mappedIds}, nil
+}
+
 type mapping struct {
 	mappings *Mappings
 	index    int
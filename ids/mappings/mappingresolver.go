@@ -11,7 +11,6 @@ import (
 	"github.com/distributed-vision/go-resources/ids/domain"
 	"github.com/distributed-vision/go-resources/resolvers"
 	"github.com/distributed-vision/go-resources/translators"
-	"github.com/distributed-vision/go-resources/translators/maptranslator"
 	"github.com/distributed-vision/go-resources/util"
 	"github.com/distributed-vision/go-resources/version"
 	"github.com/distributed-vision/go-resources/version/versiontype"
@@ -34,6 +33,7 @@ func init() {
 
 		mapType := ids.NewLocalTypeId(reflect.TypeOf(map[string]interface{}{}))
 		translators.Register(context.Background(), mapType, mappingsEntityType, mappingsMapTranslator)
+		translators.Register(context.Background(), mappingsEntityType, mapType, mapMappingsTranslator)
 
 		PublicResolverType, err = ids.NewTypeId(
 			domain.MustDecodeId(encodertype.BASE62, "T", "0", uint32(0), uint(0), versiontype.SEMANTIC),
@@ -55,9 +55,7 @@ func mappingsMapTranslator(translationContext context.Context, fromId ids.Identi
 	cres := make(chan interface{}, 1)
 	cerr := make(chan error, 1)
 
-	json := fromValue.(map[string]interface{})
-
-	toValue, err := maptranslator.FromMap(json, mappingsType)
+	toValue, err := fromMap(fromValue)
 
 	if err != nil {
 		cerr <- err
@@ -105,6 +103,17 @@ type MappingResolver interface {
 	Map(mappingContext context.Context, from ids.Identifier, to ids.Identifier, between ...time.Time) chan error
 }
 
+// BulkMappingResolver is a MappingResolver which can record several rows
+// sharing a from id and to domain in a single call
+type BulkMappingResolver interface {
+	MappingResolver
+	MapRows(mappingContext context.Context, rows []*Row) chan error
+}
+
+func EntityType() ids.TypeIdentifier {
+	return mappingsEntityType
+}
+
 func RegisterResolver(resolver resolvers.Resolver) error {
 	return mappingResolver.RegisterComponent(resolver)
 }
@@ -113,126 +122,67 @@ func RegisterResolverFactory(resolverFactory resolvers.ResolverFactory) error {
 	return mappingResolver.RegisterComponentFactory(resolverFactory, false)
 }
 
+// TypedResolver returns the mappings resolver adapted to deliver Mappings
+// values
+func TypedResolver() *resolvers.TypedResolver[Mappings] {
+	return resolvers.NewTypedResolver[Mappings](mappingResolver)
+}
+
 func Get(resolutionContext context.Context, selector Selector) (domain ids.Mapping, err error) {
 	return AwaitMapping(Resolve(resolutionContext, selector))
 }
 
 func Resolve(resolutionContext context.Context, selector Selector) (chan ids.Mapping, chan error) {
-	cResOut := make(chan ids.Mapping, 1)
-	cErrOut := make(chan error, 1)
-
-	go func() {
-		res, err := util.Await(mappingResolver.Resolve(resolutionContext, &selector))
-
-		if err == nil {
-			if mappings, ok := res.(Mappings); ok {
-				mappingIndex := -1
-				for index, mappedid := range mappings.mappedIds {
-					if selector.At.After(mappedid.from) && selector.At.Before(mappedid.to) {
-						cResOut <- &mapping{&mappings, index}
-						mappingIndex = index
-					}
-				}
-
-				if mappingIndex < 0 {
-					cErrOut <- fmt.Errorf("Can't find mapping for: %s at: %v", selector.Key(), selector.At)
-				}
-			} else {
-				cErrOut <- fmt.Errorf("Resolver returned invalid type, expected: mappings.Mappings got: %s", reflect.TypeOf(res))
-			}
-		} else {
-			cErrOut <- err
+	return ResolveFuture(resolutionContext, selector).Channels()
+}
+
+// ResolveFuture resolves the mapping selected by selector, cancelling the
+// returned future cancels the resolution
+func ResolveFuture(resolutionContext context.Context, selector Selector) *util.Future[ids.Mapping] {
+	return util.Go(resolutionContext, func(resolutionContext context.Context) (ids.Mapping, error) {
+		mappings, err := TypedResolver().Get(resolutionContext, &selector)
+
+		if err != nil {
+			return nil, err
 		}
 
-		close(cResOut)
-		close(cErrOut)
-	}()
+		for index, mappedid := range mappings.mappedIds {
+			if selector.At.After(mappedid.from) && selector.At.Before(mappedid.to) {
+				return &mapping{&mappings, index}, nil
+			}
+		}
 
-	return cResOut, cErrOut
+		return nil, resolvers.NewEntityNotFound(fmt.Sprintf("Can't find mapping for: %s at: %v", selector.Key(), selector.At), nil)
+	})
 }
 
 func Map(mappingContext context.Context, from ids.Identifier, to ids.Identifier, between ...time.Time) chan error {
-	cErrOut := make(chan error, 1)
-
-	go func() {
-		defer close(cErrOut)
+	return MapFuture(mappingContext, from, to, between...).ErrorChannel()
+}
 
-		selector := Selector{From: from, To: to.Domain()}
-		mutableResolvers := mappingResolver.GetMutableComponents(mappingContext, &selector)
+// MapFuture records a mapping from from to to, valid between the optional
+// times given by between
+func MapFuture(mappingContext context.Context, from ids.Identifier, to ids.Identifier, between ...time.Time) *util.Future[struct{}] {
+	return util.Go(mappingContext, func(mappingContext context.Context) (struct{}, error) {
+		after, before := MinTime, MaxTime
 
-		if len(mutableResolvers) == 0 {
-			cErrOut <- fmt.Errorf("No mutable mapping resolvers installed for: %s", from.Domain())
-			return
+		if len(between) > 0 {
+			after = between[0]
 		}
 
-		var mappingResolver = mutableResolvers[0]
-
-		if len(mutableResolvers) > 1 {
-
+		if len(between) > 1 {
+			before = between[1]
 		}
 
-		switch mappingResolver.(type) {
-		case MappingResolver:
-			err := util.AwaitError(mappingResolver.(MappingResolver).Map(mappingContext, from, to, between...))
-			if err != nil {
-				cErrOut <- err
-			}
-		case resolvers.MutableResolver:
-			result, err := mappingResolver.Get(mappingContext, &selector)
-
-			after, before := MinTime, MaxTime
+		mutableResolvers := mappingResolver.GetMutableComponents(mappingContext,
+			&Selector{From: from, To: domain.Wrap(to.DomainId())})
 
-			if len(between) > 0 {
-				after = between[0]
-			}
-
-			if len(between) > 1 {
-				after = between[1]
-			}
-
-			if result != nil {
-				mappings := result.(Mappings)
-				var currentMids = mappings.mappedIds
-				var updatedMids []mappedId
-
-				for index, mid := range currentMids {
-					if mid.from.After(after) {
-						updatedMids = append(currentMids, mappedId{})
-						copy(updatedMids[index+1:], updatedMids[index:])
-						updatedMids[index] = mappedId{after, before, to}
-						break
-					} else if mid.from.Equal(after) {
-						if mid.to.Equal(after) {
-							currentMids[index] = mappedId{after, before, to}
-							updatedMids = currentMids
-						} else {
-							insertIndex := index
-							if mid.to.Before(before) {
-								insertIndex++
-							}
-							updatedMids = append(currentMids, mappedId{})
-							copy(updatedMids[insertIndex+1:], updatedMids[insertIndex:])
-							updatedMids[insertIndex] = mappedId{after, before, to}
-						}
-						break
-					}
-				}
-				if updatedMids != nil {
-					mappings.mappedIds = updatedMids
-				} else {
-					mappings.mappedIds = append(currentMids, mappedId{after, before, to})
-				}
-				mappingResolver.(resolvers.MutableResolver).Post(mappingContext, mappings)
-			} else if _, ok := err.(*resolvers.EntityNotFound); ok {
-				mappingResolver.(resolvers.MutableResolver).Put(mappingContext,
-					Mappings{from, to.Domain(), []mappedId{mappedId{after, before, to}}})
-			} else {
-				cErrOut <- err
-			}
+		if len(mutableResolvers) == 0 {
+			return struct{}{}, fmt.Errorf("No mutable mapping resolvers installed for: %s: %w",
+				domain.Wrap(from.DomainId()), resolvers.NewNoResolverForType(mappingsEntityType))
 		}
 
-		close(cErrOut)
-	}()
-
-	return cErrOut
+		return struct{}{}, writeRows(mappingContext, mutableResolvers[0],
+			[]*Row{&Row{From: from, To: to, ValidFrom: after, ValidTo: before}})
+	})
 }
@@ -15,6 +15,7 @@ import (
 	"github.com/distributed-vision/go-resources/ids/schemeformat"
 	"github.com/distributed-vision/go-resources/ids/schemevisibility"
 	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/util"
 	"github.com/distributed-vision/go-resources/version"
 	"github.com/distributed-vision/go-resources/version/versiontype"
 )
@@ -39,27 +40,7 @@ func KeyExtractor(entity ...interface{}) (interface{}, bool) {
 }
 
 func Await(cres chan ids.Scheme, cerr chan error) (result ids.Scheme, err error) {
-	if cres == nil || cerr == nil {
-		return nil, fmt.Errorf("Await Failed: channels are undefined")
-	}
-
-	resolved := false
-	for !resolved {
-		select {
-		case res, ok := <-cres:
-			if ok {
-				result = res
-				resolved = true
-			}
-		case error, ok := <-cerr:
-			if ok {
-				err = error
-				resolved = true
-			}
-		}
-	}
-
-	return result, err
+	return util.Await(cres, cerr)
 }
 
 func unmarshalJSON(unmarshalContext context.Context, json map[string]interface{}) (interface{}, error) {
@@ -5,12 +5,13 @@ import (
 	"context"
 	"fmt"
 	"reflect"
-	"strings"
 
 	"github.com/distributed-vision/go-resources/encoding/base62"
 	"github.com/distributed-vision/go-resources/encoding/encodertype"
 	"github.com/distributed-vision/go-resources/ids"
 	"github.com/distributed-vision/go-resources/ids/domain"
+	"github.com/distributed-vision/go-resources/ids/schemeformat"
+	"github.com/distributed-vision/go-resources/ids/schemevisibility"
 	"github.com/distributed-vision/go-resources/resolvers"
 	"github.com/distributed-vision/go-resources/translators"
 	"github.com/distributed-vision/go-resources/util"
@@ -40,6 +41,7 @@ func init() {
 
 		mapType := ids.NewLocalTypeId(reflect.TypeOf(map[string]interface{}{}))
 		translators.Register(context.Background(), mapType, schemeEntityType, schemeMapTranslator)
+		translators.Register(context.Background(), schemeEntityType, mapType, mapSchemeTranslator)
 
 		PublicResolverType, err = ids.NewTypeId(
 			domain.MustDecodeId(encodertype.BASE62, "T", "0", uint32(0), uint(0), versiontype.SEMANTIC),
@@ -65,8 +67,17 @@ func schemeMapTranslator(translationContext context.Context, fromId ids.Identifi
 	cres := make(chan interface{}, 1)
 	cerr := make(chan error, 1)
 
-	json := fromValue.(map[string]interface{})
-	json["id"] = string(fromId.Id())
+	json := make(map[string]interface{})
+
+	for key, value := range fromValue.(map[string]interface{}) {
+		json[key] = value
+	}
+
+	// maps written by mapSchemeTranslator carry their id, others are keyed
+	// by it
+	if _, ok := json["id"]; !ok && fromId != nil {
+		json["id"] = string(fromId.Id())
+	}
 
 	toValue, err := unmarshalJSON(translationContext, json)
 	//fmt.Printf("val: %+v err: %s\n", toValue, err)
@@ -83,11 +94,33 @@ func schemeMapTranslator(translationContext context.Context, fromId ids.Identifi
 	return cres, cerr
 }
 
-type SelectorOpts struct {
-	IgnoreCase       bool
-	IgnoreWhitespace bool
+// mapSchemeTranslator translates a scheme to the json map form read by
+// schemeMapTranslator. The scheme's domain resolver info isn't included, as
+// it is configured by the scheme's files rather than stored with it
+func mapSchemeTranslator(translationContext context.Context, fromId ids.Identifier, fromValue interface{}) (chan interface{}, chan error) {
+	cres := make(chan interface{}, 1)
+	cerr := make(chan error, 1)
+
+	if scheme, ok := fromValue.(ids.Scheme); ok {
+		cres <- map[string]interface{}{
+			"id":          base62.Encode(scheme.IdRoot()),
+			"name":        scheme.Name(),
+			"description": scheme.Description(),
+			"visibility":  schemevisibility.String(scheme.Visibility()),
+			"format":      schemeformat.String(scheme.Format()),
+			"domainInfo":  map[string]interface{}{}}
+	} else {
+		cerr <- fmt.Errorf("Can't translate %T to a json map, expected: ids.Scheme", fromValue)
+	}
+
+	close(cres)
+	close(cerr)
+
+	return cres, cerr
 }
 
+type SelectorOpts = resolvers.SelectorOpts
+
 type Selector struct {
 	Id   []byte
 	Name string
@@ -105,16 +138,8 @@ func (this *Selector) Test(candidate interface{}) bool {
 		return false
 	}
 
-	if this.Name != "" {
-		if this.Opts.IgnoreCase {
-			if strings.ToUpper(this.Name) != strings.ToUpper(scheme.Name()) {
-				return false
-			}
-		} else {
-			if this.Name != scheme.Name() {
-				return false
-			}
-		}
+	if this.Name != "" && !this.Opts.Equal(this.Name, scheme.Name()) {
+		return false
 	}
 
 	return true
@@ -161,39 +186,25 @@ func (this *resolver) Resolve(resolutionContext context.Context, selector resolv
 	return cResOut, cErrOut
 }
 
-func RegisterResolverFactory(resolverFactory resolvers.ResolverFactory) error {
-	return schemeResolver.RegisterComponentFactory(resolverFactory, false)
-}
-
-func Get(resolutionContext context.Context, selector Selector) (ids.Scheme, error) {
-	res, err := schemeResolver.Get(resolutionContext, &selector)
-
-	if err != nil {
-		return nil, err
-	}
-
-	if scheme, ok := res.(ids.Scheme); ok {
-		return scheme, err
-	}
-
-	return nil, fmt.Errorf("Resolver returned invalid type, expected: ids.Domain got: %s", reflect.TypeOf(res))
-}
-
-func Resolve(resolutionContext context.Context, selector Selector) (chan ids.Scheme, chan error) {
-
-	cResOut := make(chan ids.Scheme, 1)
+func (this *resolver) Query(queryContext context.Context, selector resolvers.Selector, opts resolvers.QueryOpts) (<-chan interface{}, <-chan error) {
+	cResOut := make(chan interface{})
 	cErrOut := make(chan error, 1)
 
 	go func() {
-		res, err := util.Await(schemeResolver.Resolve(resolutionContext, &selector))
+		cres, cerr := this.CompositeResolver.Query(queryContext, selector, opts)
 
-		if err == nil {
+		for res := range cres {
 			if scheme, ok := res.(ids.Scheme); ok {
-				cResOut <- scheme
-			} else {
-				cErrOut <- fmt.Errorf("Resolver returned invalid type, expected: ids.Domain got: %s", reflect.TypeOf(res))
+				scheme.RegisterResolvers()
 			}
-		} else {
+
+			select {
+			case cResOut <- res:
+			case <-queryContext.Done():
+			}
+		}
+
+		if err, ok := <-cerr; ok && err != nil {
 			cErrOut <- err
 		}
 
@@ -203,3 +214,25 @@ func Resolve(resolutionContext context.Context, selector Selector) (chan ids.Sch
 
 	return cResOut, cErrOut
 }
+
+func RegisterResolverFactory(resolverFactory resolvers.ResolverFactory) error {
+	return schemeResolver.RegisterComponentFactory(resolverFactory, false)
+}
+
+// TypedResolver returns the scheme resolver adapted to deliver ids.Scheme
+// values
+func TypedResolver() *resolvers.TypedResolver[ids.Scheme] {
+	return resolvers.NewTypedResolver[ids.Scheme](schemeResolver)
+}
+
+func Get(resolutionContext context.Context, selector Selector) (ids.Scheme, error) {
+	return TypedResolver().Get(resolutionContext, &selector)
+}
+
+func Resolve(resolutionContext context.Context, selector Selector) (chan ids.Scheme, chan error) {
+	return TypedResolver().Resolve(resolutionContext, &selector)
+}
+
+func Query(queryContext context.Context, selector Selector, opts resolvers.QueryOpts) (chan ids.Scheme, chan error) {
+	return TypedResolver().Query(queryContext, &selector, opts)
+}
@@ -22,3 +22,14 @@ func Parse(format string) (ids.SchemeFormat, error) {
 		return -1, errors.New("Unknown domain format: " + format)
 	}
 }
+
+func String(format ids.SchemeFormat) string {
+	switch format {
+	case FIXED:
+		return "fixed"
+	case LV:
+		return "lv"
+	default:
+		return "invalid"
+	}
+}
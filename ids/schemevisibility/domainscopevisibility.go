@@ -28,3 +28,18 @@ func Parse(visibility string) (ids.SchemeVisibility, error) {
 		return -1, errors.New("Unknown domain visibility: " + visibility)
 	}
 }
+
+func String(visibility ids.SchemeVisibility) string {
+	switch visibility {
+	case UNTYPED:
+		return "untyped"
+	case LOCAL:
+		return "local"
+	case PRIVATE:
+		return "private"
+	case PUBLIC:
+		return "public"
+	default:
+		return "invalid"
+	}
+}
@@ -44,10 +44,20 @@ func unmarshalJSON(unmarshalContext context.Context, json map[string]interface{}
 	}
 
 	var idScheme ids.Scheme
+	var schemeId []byte
 
 	if hasResolverInfo && resolverInfo.Value("schemeId") != nil {
-		//fmt.Printf("schemeid=%v\n", resolverInfo.Value("schemeId"))
-		idScheme, err = scheme.Get(unmarshalContext, scheme.Selector{Id: resolverInfo.Value("schemeId").([]byte)})
+		schemeId = resolverInfo.Value("schemeId").([]byte)
+	} else if schemeIdValue, ok := json["schemeId"].(string); ok {
+		// domains stored outside their scheme's files carry their scheme id
+		if schemeId, err = base62.Decode(schemeIdValue); err != nil {
+			return nil, err
+		}
+	}
+
+	if schemeId != nil {
+		//fmt.Printf("schemeid=%v\n", schemeId)
+		idScheme, err = scheme.Get(unmarshalContext, scheme.Selector{Id: schemeId})
 		//fmt.Printf("scheme=%v\n", scheme)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,150 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/ids/schemevisibility"
+	"github.com/distributed-vision/go-resources/resolvers/contextkeys"
+	"github.com/distributed-vision/go-resources/resolvers/operationtype"
+)
+
+// Principal identifies the caller an operation is made on behalf of
+type Principal interface {
+	Name() string
+	Roles() []string
+}
+
+type principal struct {
+	name  string
+	roles []string
+}
+
+func NewPrincipal(name string, roles ...string) Principal {
+	return &principal{name, roles}
+}
+
+func (this *principal) Name() string {
+	return this.name
+}
+
+func (this *principal) Roles() []string {
+	return this.roles
+}
+
+func (this *principal) String() string {
+	return this.name
+}
+
+// WithPrincipal returns a copy of resolutionContext which makes operations on
+// behalf of principal
+func WithPrincipal(resolutionContext context.Context, principal Principal) context.Context {
+	return context.WithValue(resolutionContext, contextkeys.PRINCIPAL, principal)
+}
+
+// PrincipalFrom returns the principal of resolutionContext, or nil for
+// anonymous operations
+func PrincipalFrom(resolutionContext context.Context) Principal {
+	principal, _ := resolutionContext.Value(contextkeys.PRINCIPAL).(Principal)
+	return principal
+}
+
+// Access describes an operation to be authorized. EntityType, Domain and
+// Key are nil where they can't be determined, Visibility is the visibility
+// of the domain's scheme, or UNTYPED if it has none
+type Access struct {
+	Principal  Principal
+	Operation  operationtype.OperationType
+	EntityType ids.TypeIdentifier
+	Domain     ids.Domain
+	Visibility ids.SchemeVisibility
+	Key        interface{}
+}
+
+func (this *Access) String() string {
+	name := "anonymous"
+
+	if this.Principal != nil {
+		name = this.Principal.Name()
+	}
+
+	access := fmt.Sprintf("%s %v type=%v", name, this.Operation, this.EntityType)
+
+	if this.Domain != nil {
+		access += fmt.Sprintf(" domain=%s visibility=%s", this.Domain.Name(), schemevisibility.String(this.Visibility))
+	}
+
+	if this.Key != nil {
+		access += fmt.Sprintf(" key=%v", this.Key)
+	}
+
+	return access
+}
+
+// Authorizer decides whether an operation may proceed, Authorize returns nil
+// to allow it and an error, normally an AccessDenied, to deny it
+type Authorizer interface {
+	Authorize(authorizationContext context.Context, access *Access) error
+}
+
+type AuthorizerFunc func(authorizationContext context.Context, access *Access) error
+
+func (this AuthorizerFunc) Authorize(authorizationContext context.Context, access *Access) error {
+	return this(authorizationContext, access)
+}
+
+// NewAccess describes operation on behalf of the principal of
+// resolutionContext, the domain is taken from the first of values which is,
+// or is identified by, a domain
+func NewAccess(resolutionContext context.Context, operation operationtype.OperationType, entityType ids.TypeIdentifier, key interface{}, values ...interface{}) *Access {
+	access := &Access{
+		Principal:  PrincipalFrom(resolutionContext),
+		Operation:  operation,
+		EntityType: entityType,
+		Key:        key,
+		Visibility: schemevisibility.UNTYPED}
+
+	access.Domain = domainOf(append(values, key)...)
+
+	if access.Domain != nil {
+		access.Visibility = visibilityOf(access.Domain)
+	}
+
+	return access
+}
+
+func domainOf(values ...interface{}) ids.Domain {
+	for _, value := range values {
+		var domain ids.Domain
+
+		switch value := value.(type) {
+		case ids.Domain:
+			domain = value
+		case ids.Identifier:
+			domain = value.Domain()
+		case interface{ Domain() ids.Domain }:
+			domain = value.Domain()
+		}
+
+		if domain != nil {
+			return domain
+		}
+	}
+
+	return nil
+}
+
+func visibilityOf(domain ids.Domain) ids.SchemeVisibility {
+	scheme, ok := domain.(ids.Scheme)
+
+	if !ok {
+		scheme = domain.Scheme()
+	}
+
+	if scheme == nil {
+		return schemevisibility.UNTYPED
+	}
+
+	return scheme.Visibility()
+}
@@ -0,0 +1,217 @@
+package accesspolicy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/ids/schemevisibility"
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/operationtype"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ALLOW = "allow"
+	DENY  = "deny"
+)
+
+// ANY_PRINCIPAL matches any principal which isn't anonymous
+const ANY_PRINCIPAL = "*"
+
+// Rule allows or denies the operations it matches. A rule matches an
+// operation if each of its non empty lists matches; principals by name,
+// roles if the principal has any of them, entity types by type id name or
+// encoded id, and domains by name or encoded id
+type Rule struct {
+	Effect       string   `json:"effect" yaml:"effect"`
+	Principals   []string `json:"principals,omitempty" yaml:"principals,omitempty"`
+	Roles        []string `json:"roles,omitempty" yaml:"roles,omitempty"`
+	Operations   []string `json:"operations,omitempty" yaml:"operations,omitempty"`
+	EntityTypes  []string `json:"entityTypes,omitempty" yaml:"entityTypes,omitempty"`
+	Domains      []string `json:"domains,omitempty" yaml:"domains,omitempty"`
+	Visibilities []string `json:"visibilities,omitempty" yaml:"visibilities,omitempty"`
+
+	operations   map[operationtype.OperationType]bool
+	visibilities map[ids.SchemeVisibility]bool
+}
+
+// Policy is an Authorizer which applies the first of its rules to match an
+// operation, operations which no rule matches are denied unless Default is
+// allow
+type Policy struct {
+	Default string  `json:"default,omitempty" yaml:"default,omitempty"`
+	Rules   []*Rule `json:"rules" yaml:"rules"`
+}
+
+// Load reads a policy from a .json, .yaml or .yml file
+func Load(path string) (*Policy, error) {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &Policy{}
+
+	if strings.HasSuffix(path, ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(policy)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(policy)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Load Failed: can't parse %s: %v", path, err)
+	}
+
+	if err := policy.Compile(); err != nil {
+		return nil, fmt.Errorf("Load Failed: %s: %v", path, err)
+	}
+
+	return policy, nil
+}
+
+// Compile validates the policy's rules, it must be called before the
+// policy is used if it isn't created by Load
+func (this *Policy) Compile() error {
+	this.Default = strings.ToLower(this.Default)
+
+	if this.Default != "" && this.Default != ALLOW && this.Default != DENY {
+		return fmt.Errorf("Invalid default effect: %s", this.Default)
+	}
+
+	for index, rule := range this.Rules {
+		if err := rule.compile(); err != nil {
+			return fmt.Errorf("Invalid rule %d: %v", index, err)
+		}
+	}
+
+	return nil
+}
+
+func (this *Rule) compile() error {
+	this.Effect = strings.ToLower(this.Effect)
+
+	if this.Effect != ALLOW && this.Effect != DENY {
+		return fmt.Errorf("Invalid effect: %s", this.Effect)
+	}
+
+	this.operations = make(map[operationtype.OperationType]bool)
+
+	for _, value := range this.Operations {
+		operation, err := operationtype.Parse(value)
+
+		if err != nil {
+			return err
+		}
+
+		this.operations[operation] = true
+	}
+
+	this.visibilities = make(map[ids.SchemeVisibility]bool)
+
+	for _, value := range this.Visibilities {
+		visibility, err := schemevisibility.Parse(value)
+
+		if err != nil {
+			return err
+		}
+
+		this.visibilities[visibility] = true
+	}
+
+	return nil
+}
+
+func (this *Policy) Authorize(authorizationContext context.Context, access *resolvers.Access) error {
+	for index, rule := range this.Rules {
+		if !rule.matches(access) {
+			continue
+		}
+
+		if rule.Effect == ALLOW {
+			return nil
+		}
+
+		return resolvers.NewAccessDenied(access, fmt.Sprintf("denied by rule %d", index))
+	}
+
+	if this.Default == ALLOW {
+		return nil
+	}
+
+	return resolvers.NewAccessDenied(access, "no rule allows access")
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (this *Rule) matches(access *resolvers.Access) bool {
+	if len(this.Principals) > 0 {
+		if access.Principal == nil {
+			return false
+		}
+
+		if !contains(this.Principals, ANY_PRINCIPAL) && !contains(this.Principals, access.Principal.Name()) {
+			return false
+		}
+	}
+
+	if len(this.Roles) > 0 {
+		if access.Principal == nil {
+			return false
+		}
+
+		hasRole := false
+
+		for _, role := range access.Principal.Roles() {
+			if contains(this.Roles, role) {
+				hasRole = true
+				break
+			}
+		}
+
+		if !hasRole {
+			return false
+		}
+	}
+
+	if len(this.operations) > 0 && !this.operations[access.Operation] {
+		return false
+	}
+
+	if len(this.EntityTypes) > 0 {
+		if access.EntityType == nil ||
+			!(contains(this.EntityTypes, string(access.EntityType.Id())) || contains(this.EntityTypes, access.EntityType.String())) {
+			return false
+		}
+	}
+
+	if len(this.Domains) > 0 {
+		if access.Domain == nil ||
+			!(contains(this.Domains, access.Domain.Name()) || contains(this.Domains, access.Domain.String())) {
+			return false
+		}
+	}
+
+	if len(this.visibilities) > 0 && !this.visibilities[access.Visibility] {
+		return false
+	}
+
+	return true
+}
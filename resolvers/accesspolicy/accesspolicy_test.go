@@ -0,0 +1,120 @@
+package accesspolicy_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/ids/schemevisibility"
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/accesspolicy"
+	"github.com/distributed-vision/go-resources/resolvers/operationtype"
+	"github.com/distributed-vision/go-resources/types/gotypeid"
+)
+
+type entity struct{}
+
+var entityType ids.TypeIdentifier = gotypeid.IdOf(reflect.TypeOf(entity{}))
+
+const policyYaml = `
+rules:
+  - effect: deny
+    operations: [delete]
+    visibilities: [public]
+  - effect: allow
+    roles: [admin]
+  - effect: allow
+    principals: ["*"]
+    operations: [get, query]
+    entityTypes: [accesspolicy_test.entity]
+  - effect: allow
+    operations: [get]
+    visibilities: [public]
+`
+
+func writePolicy(t *testing.T, name string, policy string) string {
+	dir, err := ioutil.TempDir("", "accesspolicy")
+
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, name)
+
+	if err := ioutil.WriteFile(path, []byte(policy), 0644); err != nil {
+		t.Fatal("WriteFile failed:", err)
+	}
+
+	return path
+}
+
+func TestPolicy(t *testing.T) {
+	policy, err := accesspolicy.Load(writePolicy(t, "policy.yaml", policyYaml))
+
+	if err != nil {
+		t.Fatal("TestPolicy: Load failed:", err)
+	}
+
+	admin := resolvers.NewPrincipal("root", "admin")
+	user := resolvers.NewPrincipal("user")
+
+	tests := []struct {
+		access  resolvers.Access
+		allowed bool
+	}{
+		{resolvers.Access{Principal: admin, Operation: operationtype.PUT}, true},
+		{resolvers.Access{Principal: admin, Operation: operationtype.DELETE, Visibility: schemevisibility.PUBLIC}, false},
+		{resolvers.Access{Principal: user, Operation: operationtype.QUERY, EntityType: entityType}, true},
+		{resolvers.Access{Principal: user, Operation: operationtype.PUT, EntityType: entityType}, false},
+		{resolvers.Access{Operation: operationtype.GET, EntityType: entityType}, false},
+		{resolvers.Access{Operation: operationtype.GET, Visibility: schemevisibility.PUBLIC}, true},
+	}
+
+	for index, test := range tests {
+		err := policy.Authorize(context.Background(), &test.access)
+
+		if test.allowed && err != nil {
+			t.Errorf("TestPolicy: Expected access %d to be allowed, got: %v", index, err)
+		}
+
+		if !test.allowed && !errors.Is(err, resolvers.ErrAccessDenied) {
+			t.Errorf("TestPolicy: Expected access %d to be denied, got: %v", index, err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	policy, err := accesspolicy.Load(writePolicy(t, "policy.json", `{"default": "Allow", "rules": [{"effect": "deny", "operations": ["put"]}]}`))
+
+	if err != nil {
+		t.Fatal("TestLoad: Load failed:", err)
+	}
+
+	if policy.Authorize(context.Background(), &resolvers.Access{Operation: operationtype.GET}) != nil ||
+		policy.Authorize(context.Background(), &resolvers.Access{Operation: operationtype.PUT}) == nil {
+		t.Fatal("TestLoad: Unexpected json policy decisions")
+	}
+
+	if _, err := accesspolicy.Load(writePolicy(t, "invalid.yaml", "rules: [{effect: deny, operations: [update]}]")); err == nil {
+		t.Fatal("TestLoad: Expected unknown operation to fail")
+	}
+
+	if _, err := accesspolicy.Load(writePolicy(t, "invalid.yaml", "rules: [{effect: maybe}]")); err == nil {
+		t.Fatal("TestLoad: Expected unknown effect to fail")
+	}
+
+	if _, err := accesspolicy.Load(writePolicy(t, "typo.json", `{"rules": [{"effect": "deny", "operation": ["put"]}]}`)); err == nil {
+		t.Fatal("TestLoad: Expected unknown json field to fail")
+	}
+
+	if _, err := accesspolicy.Load(writePolicy(t, "typo.yaml", "rules: [{effect: deny, principal: [admin]}]")); err == nil {
+		t.Fatal("TestLoad: Expected unknown yaml field to fail")
+	}
+}
@@ -0,0 +1,378 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/resolvers/operationtype"
+	"github.com/distributed-vision/go-resources/util"
+)
+
+// AuthorizingResolver enforces an Authorizer's decisions on the operations
+// made through the resolver it wraps. Reads are authorized before the wrapped
+// resolver is called, and again for each entity found which identifies a
+// domain; entities which are denied are dropped from query results, so
+// queries may return short pages
+type AuthorizingResolver struct {
+	resolver   Resolver
+	authorizer Authorizer
+}
+
+// AuthorizingMutableResolver is an AuthorizingResolver which also authorizes
+// the writes made to a MutableResolver, and the entities they replace or
+// delete
+type AuthorizingMutableResolver struct {
+	*AuthorizingResolver
+	mutable MutableResolver
+}
+
+// AuthorizingRevisionedResolver is an AuthorizingMutableResolver which also
+// authorizes the revisioned reads and conditional writes of a
+// RevisionedResolver
+type AuthorizingRevisionedResolver struct {
+	*AuthorizingMutableResolver
+	revisioned RevisionedResolver
+}
+
+// NewAuthorizingResolver wraps resolver so that its operations are authorized
+// by authorizer. Revisioned resolvers are returned as an
+// *AuthorizingRevisionedResolver, other mutable resolvers as an
+// *AuthorizingMutableResolver and the rest as an *AuthorizingResolver
+func NewAuthorizingResolver(resolver Resolver, authorizer Authorizer) (Resolver, error) {
+	if resolver == nil || authorizer == nil {
+		return nil, fmt.Errorf("resolver and authorizer must be defined")
+	}
+
+	authorizing := &AuthorizingResolver{resolver, authorizer}
+
+	if revisioned, ok := resolver.(RevisionedResolver); ok {
+		return &AuthorizingRevisionedResolver{&AuthorizingMutableResolver{authorizing, revisioned}, revisioned}, nil
+	}
+
+	if mutable, ok := resolver.(MutableResolver); ok {
+		return &AuthorizingMutableResolver{authorizing, mutable}, nil
+	}
+
+	return authorizing, nil
+}
+
+type authorizingFactory struct {
+	ResolverFactory
+	authorizer Authorizer
+}
+
+// NewAuthorizingFactory wraps factory so that the resolvers it creates are
+// authorized by authorizer
+func NewAuthorizingFactory(factory ResolverFactory, authorizer Authorizer) (ResolverFactory, error) {
+	if factory == nil || authorizer == nil {
+		return nil, fmt.Errorf("factory and authorizer must be defined")
+	}
+
+	return &authorizingFactory{factory, authorizer}, nil
+}
+
+func (this *authorizingFactory) New(resolutionContext context.Context) (Resolver, error) {
+	resolver, err := this.ResolverFactory.New(resolutionContext)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAuthorizingResolver(resolver, this.authorizer)
+}
+
+// Resolver returns the wrapped resolver
+func (this *AuthorizingResolver) Resolver() Resolver {
+	return this.resolver
+}
+
+func (this *AuthorizingResolver) ResolverInfo() ResolverInfo {
+	return this.resolver.ResolverInfo()
+}
+
+func (this *AuthorizingResolver) authorizeSelector(resolutionContext context.Context, operation operationtype.OperationType, selector Selector) error {
+	if selector == nil {
+		return this.authorizer.Authorize(resolutionContext, NewAccess(resolutionContext, operation, nil, nil))
+	}
+
+	return this.authorizer.Authorize(resolutionContext,
+		NewAccess(resolutionContext, operation, selector.Type(), selector.Key(), selector))
+}
+
+// authorizeEntity authorizes access to an entity which has been found, if it
+// identifies a domain
+func (this *AuthorizingResolver) authorizeEntity(resolutionContext context.Context, operation operationtype.OperationType, selector Selector, entity interface{}) error {
+	if domainOf(entity) == nil {
+		return nil
+	}
+
+	var entityType ids.TypeIdentifier
+
+	if selector != nil {
+		entityType = selector.Type()
+	}
+
+	return this.authorizer.Authorize(resolutionContext,
+		NewAccess(resolutionContext, operation, entityType, this.keyOf(entity), entity))
+}
+
+func (this *AuthorizingResolver) keyOf(entity interface{}) interface{} {
+	if keyExtractor := this.ResolverInfo().KeyExtractor(); keyExtractor != nil {
+		if key, ok := keyExtractor(entity); ok {
+			return key
+		}
+	}
+
+	return nil
+}
+
+// entityType returns the type of the entities written to the resolver, if
+// it resolves a single type
+func (this *AuthorizingResolver) entityType() ids.TypeIdentifier {
+	if resolvableTypes := this.ResolverInfo().ResolvableTypes(); len(resolvableTypes) == 1 {
+		return resolvableTypes[0]
+	}
+
+	return nil
+}
+
+func (this *AuthorizingResolver) Get(resolutionContext context.Context, selector Selector) (interface{}, error) {
+	if err := this.authorizeSelector(resolutionContext, operationtype.GET, selector); err != nil {
+		return nil, err
+	}
+
+	entity, err := this.resolver.Get(resolutionContext, selector)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := this.authorizeEntity(resolutionContext, operationtype.GET, selector, entity); err != nil {
+		return nil, err
+	}
+
+	return entity, nil
+}
+
+func (this *AuthorizingResolver) Resolve(resolutionContext context.Context, selector Selector) (chan interface{}, chan error) {
+	return this.ResolveFuture(resolutionContext, selector).Channels()
+}
+
+// ResolveFuture resolves selector if its principal is allowed to, cancelling
+// the returned future cancels the resolution
+func (this *AuthorizingResolver) ResolveFuture(resolutionContext context.Context, selector Selector) *util.Future[interface{}] {
+	return util.Go(resolutionContext, func(resolutionContext context.Context) (interface{}, error) {
+		return this.Get(resolutionContext, selector)
+	})
+}
+
+// Query streams the entities matching selector which the principal is
+// allowed to see. Resolvers which aren't QueryResolvers are queried with Get
+func (this *AuthorizingResolver) Query(queryContext context.Context, selector Selector, opts QueryOpts) (<-chan interface{}, <-chan error) {
+	cResOut, cErrOut := make(chan interface{}), make(chan error, 1)
+
+	if err := this.authorizeSelector(queryContext, operationtype.QUERY, selector); err != nil {
+		cErrOut <- err
+		close(cResOut)
+		close(cErrOut)
+		return cResOut, cErrOut
+	}
+
+	go func() {
+		defer close(cErrOut)
+		defer close(cResOut)
+
+		send := func(entity interface{}) {
+			if this.authorizeEntity(queryContext, operationtype.QUERY, selector, entity) != nil {
+				return
+			}
+
+			select {
+			case cResOut <- entity:
+			case <-queryContext.Done():
+			}
+		}
+
+		queryResolver, ok := this.resolver.(QueryResolver)
+
+		if !ok {
+			entity, err := this.resolver.Get(queryContext, selector)
+
+			if err == nil {
+				send(entity)
+			} else if !IsNotFound(err) {
+				cErrOut <- err
+			}
+
+			return
+		}
+
+		cres, cerr := queryResolver.Query(queryContext, selector, opts)
+
+		for entity := range cres {
+			send(entity)
+		}
+
+		if err, ok := <-cerr; ok && err != nil {
+			cErrOut <- err
+		}
+	}()
+
+	return cResOut, cErrOut
+}
+
+// GetMany resolves the selectors the principal is allowed to, in a single
+// call if the wrapped resolver is a BatchResolver
+func (this *AuthorizingResolver) GetMany(resolutionContext context.Context, selectors []Selector) []Result {
+	results := make([]Result, len(selectors))
+	allowed := []Selector{}
+	indexes := []int{}
+
+	for index, selector := range selectors {
+		results[index].Selector = selector
+
+		if err := this.authorizeSelector(resolutionContext, operationtype.GET, selector); err != nil {
+			results[index].Err = err
+			continue
+		}
+
+		allowed = append(allowed, selector)
+		indexes = append(indexes, index)
+	}
+
+	var batch []Result
+
+	if batchResolver, ok := this.resolver.(BatchResolver); ok {
+		batch = batchResolver.GetMany(resolutionContext, allowed)
+	} else {
+		batch = make([]Result, len(allowed))
+
+		for index, selector := range allowed {
+			entity, err := this.resolver.Get(resolutionContext, selector)
+			batch[index] = Result{selector, entity, err}
+		}
+	}
+
+	if len(batch) != len(allowed) {
+		err := fmt.Errorf("Resolve Failed: %v returned %d results for %d selectors",
+			this.ResolverInfo().ResolverType(), len(batch), len(allowed))
+
+		for _, index := range indexes {
+			results[index].Err = err
+		}
+
+		return results
+	}
+
+	for position, result := range batch {
+		index := indexes[position]
+
+		if result.Err == nil {
+			result.Err = this.authorizeEntity(resolutionContext, operationtype.GET, results[index].Selector, result.Entity)
+		}
+
+		if result.Err == nil {
+			results[index].Entity = result.Entity
+		} else {
+			results[index].Err = result.Err
+		}
+	}
+
+	return results
+}
+
+// OnChange reports the changes made to the wrapped resolver, if it reports
+// them
+func (this *AuthorizingResolver) OnChange(listener ChangeListener) {
+	subscribe(this.resolver, listener)
+}
+
+// authorizeWrite authorizes writing entity, and operation on the entity it
+// replaces if there is one
+func (this *AuthorizingMutableResolver) authorizeWrite(resolutionContext context.Context, operation operationtype.OperationType, entity interface{}) error {
+	key := this.keyOf(entity)
+
+	if err := this.authorizer.Authorize(resolutionContext,
+		NewAccess(resolutionContext, operation, this.entityType(), key, entity)); err != nil {
+		return err
+	}
+
+	if key == nil {
+		return nil
+	}
+
+	return this.authorizeExisting(resolutionContext, operation,
+		&keySelector{this.entityType(), key, this.ResolverInfo().KeyExtractor()})
+}
+
+// authorizeExisting authorizes operation on the entity selector resolves, so
+// that writes can't replace or delete entities the principal may not access
+func (this *AuthorizingMutableResolver) authorizeExisting(resolutionContext context.Context, operation operationtype.OperationType, selector Selector) error {
+	existing, err := this.resolver.Get(resolutionContext, selector)
+
+	if IsNotFound(err) || errors.Is(err, ErrNoResolverForType) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return this.authorizeEntity(resolutionContext, operation, selector, existing)
+}
+
+func (this *AuthorizingMutableResolver) Put(resolutionContext context.Context, entity interface{}) (interface{}, error) {
+	if err := this.authorizeWrite(resolutionContext, operationtype.PUT, entity); err != nil {
+		return nil, err
+	}
+
+	return this.mutable.Put(resolutionContext, entity)
+}
+
+func (this *AuthorizingMutableResolver) Post(resolutionContext context.Context, entity interface{}) (interface{}, error) {
+	if err := this.authorizeWrite(resolutionContext, operationtype.POST, entity); err != nil {
+		return nil, err
+	}
+
+	return this.mutable.Post(resolutionContext, entity)
+}
+
+func (this *AuthorizingMutableResolver) Delete(resolutionContext context.Context, selector Selector) error {
+	if err := this.authorizeSelector(resolutionContext, operationtype.DELETE, selector); err != nil {
+		return err
+	}
+
+	if err := this.authorizeExisting(resolutionContext, operationtype.DELETE, selector); err != nil {
+		return err
+	}
+
+	return this.mutable.Delete(resolutionContext, selector)
+}
+
+func (this *AuthorizingRevisionedResolver) GetRevision(resolutionContext context.Context, selector Selector) (interface{}, Revision, error) {
+	if err := this.authorizeSelector(resolutionContext, operationtype.GET, selector); err != nil {
+		return nil, NoRevision, err
+	}
+
+	entity, revision, err := this.revisioned.GetRevision(resolutionContext, selector)
+
+	if err != nil {
+		return nil, NoRevision, err
+	}
+
+	if err := this.authorizeEntity(resolutionContext, operationtype.GET, selector, entity); err != nil {
+		return nil, NoRevision, err
+	}
+
+	return entity, revision, nil
+}
+
+func (this *AuthorizingRevisionedResolver) PostIfMatch(resolutionContext context.Context, entity interface{}, revision Revision) (Revision, error) {
+	if err := this.authorizeWrite(resolutionContext, operationtype.POST, entity); err != nil {
+		return NoRevision, err
+	}
+
+	return this.revisioned.PostIfMatch(resolutionContext, entity, revision)
+}
@@ -0,0 +1,304 @@
+package resolvers_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/ids/schemevisibility"
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/localresolver"
+	"github.com/distributed-vision/go-resources/resolvers/operationtype"
+	"github.com/distributed-vision/go-resources/util"
+)
+
+type schemeBase = ids.Scheme
+
+// testScheme and testDomain implement only the methods used to authorize
+// access
+type testScheme struct {
+	schemeBase
+	visibility ids.SchemeVisibility
+}
+
+func (this *testScheme) Visibility() ids.SchemeVisibility {
+	return this.visibility
+}
+
+type testDomain struct {
+	ids.Domain
+	name   string
+	scheme *testScheme
+}
+
+func (this *testDomain) Name() string {
+	return this.name
+}
+
+func (this *testDomain) Scheme() ids.Scheme {
+	return this.scheme
+}
+
+var publicDomain = &testDomain{name: "public", scheme: &testScheme{visibility: schemevisibility.PUBLIC}}
+var privateDomain = &testDomain{name: "private", scheme: &testScheme{visibility: schemevisibility.PRIVATE}}
+
+type scopedEntity struct {
+	key    string
+	domain ids.Domain
+}
+
+func (this scopedEntity) Domain() ids.Domain {
+	return this.domain
+}
+
+type scopedSelector struct {
+	key string
+}
+
+func (this *scopedSelector) Type() ids.TypeIdentifier {
+	return testEntityType
+}
+
+func (this *scopedSelector) Key() interface{} {
+	return this.key
+}
+
+func (this *scopedSelector) Test(candidate interface{}) bool {
+	scoped, ok := candidate.(scopedEntity)
+	return ok && (this.key == "" || this.key == scoped.key)
+}
+
+func scopedExtractor(entities ...interface{}) (interface{}, bool) {
+	scoped, ok := entities[0].(scopedEntity)
+	return scoped.key, ok
+}
+
+// testAuthorizer allows writes only by the writer principal, and access to
+// private domains only by principals with the reader role
+var testAuthorizer = resolvers.AuthorizerFunc(func(authorizationContext context.Context, access *resolvers.Access) error {
+	if access.Operation.IsWrite() && (access.Principal == nil || access.Principal.Name() != "writer") {
+		return resolvers.NewAccessDenied(access, "writes are restricted")
+	}
+
+	if access.Visibility == schemevisibility.PRIVATE {
+		if access.Principal == nil || len(access.Principal.Roles()) == 0 || access.Principal.Roles()[0] != "reader" {
+			return resolvers.NewAccessDenied(access, "domain is private")
+		}
+	}
+
+	return nil
+})
+
+func TestAuthorizingResolver(t *testing.T) {
+	info := resolvers.NewResolverInfo(testResolverType, testResolvableTypes, nil, scopedExtractor, nil)
+	local, err := localresolver.New(info)
+
+	if err != nil {
+		t.Fatal("TestAuthorizingResolver: New failed:", err)
+	}
+
+	wrapped, err := resolvers.NewAuthorizingResolver(local, testAuthorizer)
+
+	if err != nil {
+		t.Fatal("TestAuthorizingResolver: NewAuthorizingResolver failed:", err)
+	}
+
+	resolver, ok := wrapped.(resolvers.MutableResolver)
+
+	if !ok {
+		t.Fatal("TestAuthorizingResolver: Expected a mutable resolver")
+	}
+
+	writer := resolvers.WithPrincipal(testContext, resolvers.NewPrincipal("writer", "reader"))
+	reader := resolvers.WithPrincipal(testContext, resolvers.NewPrincipal("reader", "reader"))
+
+	if _, err := resolver.Put(testContext, scopedEntity{"1", publicDomain}); !errors.Is(err, resolvers.ErrAccessDenied) {
+		t.Fatal("TestAuthorizingResolver: Expected anonymous put to be denied, got:", err)
+	}
+
+	var denied *resolvers.AccessDenied
+
+	if _, err := resolver.Put(reader, scopedEntity{"1", publicDomain}); !errors.As(err, &denied) ||
+		denied.Access.Operation != operationtype.PUT || denied.Access.Domain != publicDomain {
+		t.Fatal("TestAuthorizingResolver: Expected reader put to be denied, got:", err)
+	}
+
+	for _, scoped := range []scopedEntity{{"1", publicDomain}, {"2", privateDomain}} {
+		if _, err := resolver.Put(writer, scoped); err != nil {
+			t.Fatal("TestAuthorizingResolver: Put failed:", err)
+		}
+	}
+
+	if _, err := resolver.Get(testContext, &scopedSelector{"1"}); err != nil {
+		t.Fatal("TestAuthorizingResolver: Expected anonymous get of public entity, got:", err)
+	}
+
+	if _, err := resolver.Get(testContext, &scopedSelector{"2"}); !errors.Is(err, resolvers.ErrAccessDenied) {
+		t.Fatal("TestAuthorizingResolver: Expected anonymous get of private entity to be denied, got:", err)
+	}
+
+	if _, err := util.Await(resolver.Resolve(reader, &scopedSelector{"2"})); err != nil {
+		t.Fatal("TestAuthorizingResolver: Expected reader resolve of private entity, got:", err)
+	}
+
+	queryResolver := wrapped.(resolvers.QueryResolver)
+
+	if entities, err := util.AwaitAll(queryResolver.Query(testContext, &scopedSelector{}, resolvers.QueryOpts{})); err != nil || len(entities) != 1 {
+		t.Fatal("TestAuthorizingResolver: Expected anonymous query to return public entity only, got:", entities, err)
+	}
+
+	if entities, err := util.AwaitAll(queryResolver.Query(reader, &scopedSelector{}, resolvers.QueryOpts{})); err != nil || len(entities) != 2 {
+		t.Fatal("TestAuthorizingResolver: Expected reader query to return all entities, got:", entities, err)
+	}
+
+	results := wrapped.(resolvers.BatchResolver).GetMany(testContext, []resolvers.Selector{&scopedSelector{"1"}, &scopedSelector{"2"}})
+
+	if results[0].Err != nil || !errors.Is(results[1].Err, resolvers.ErrAccessDenied) {
+		t.Fatal("TestAuthorizingResolver: Unexpected GetMany results:", results)
+	}
+
+	revisioned, ok := wrapped.(resolvers.RevisionedResolver)
+
+	if !ok {
+		t.Fatal("TestAuthorizingResolver: Expected a revisioned resolver")
+	}
+
+	if _, err := revisioned.PostIfMatch(reader, scopedEntity{"2", privateDomain}, resolvers.NoRevision); !errors.Is(err, resolvers.ErrAccessDenied) {
+		t.Fatal("TestAuthorizingResolver: Expected reader conditional write to be denied, got:", err)
+	}
+
+	if _, _, err := revisioned.GetRevision(testContext, &scopedSelector{"2"}); !errors.Is(err, resolvers.ErrAccessDenied) {
+		t.Fatal("TestAuthorizingResolver: Expected anonymous GetRevision of private entity to be denied, got:", err)
+	}
+
+	// a writer without the reader role can't replace or delete private entities
+	restricted := resolvers.WithPrincipal(testContext, resolvers.NewPrincipal("writer"))
+
+	if _, err := resolver.Put(restricted, scopedEntity{"2", publicDomain}); !errors.Is(err, resolvers.ErrAccessDenied) {
+		t.Fatal("TestAuthorizingResolver: Expected put over a private entity to be denied, got:", err)
+	}
+
+	if _, err := resolver.Post(restricted, scopedEntity{"2", publicDomain}); !errors.Is(err, resolvers.ErrAccessDenied) {
+		t.Fatal("TestAuthorizingResolver: Expected post over a private entity to be denied, got:", err)
+	}
+
+	if _, err := revisioned.PostIfMatch(restricted, scopedEntity{"2", publicDomain}, resolvers.NoRevision); !errors.Is(err, resolvers.ErrAccessDenied) {
+		t.Fatal("TestAuthorizingResolver: Expected conditional write over a private entity to be denied, got:", err)
+	}
+
+	if err := resolver.Delete(restricted, &scopedSelector{"2"}); !errors.Is(err, resolvers.ErrAccessDenied) {
+		t.Fatal("TestAuthorizingResolver: Expected delete of a private entity to be denied, got:", err)
+	}
+
+	if entity, err := local.Get(testContext, &scopedSelector{"2"}); err != nil || entity.(scopedEntity).domain != privateDomain {
+		t.Fatal("TestAuthorizingResolver: Expected private entity to be unchanged, got:", entity, err)
+	}
+
+	if _, err := resolver.Put(restricted, scopedEntity{"3", publicDomain}); err != nil {
+		t.Fatal("TestAuthorizingResolver: Expected put of a new public entity, got:", err)
+	}
+
+	if err := resolver.Delete(reader, &scopedSelector{"1"}); !errors.Is(err, resolvers.ErrAccessDenied) {
+		t.Fatal("TestAuthorizingResolver: Expected reader delete to be denied, got:", err)
+	}
+
+	if err := resolver.Delete(writer, &scopedSelector{"1"}); err != nil {
+		t.Fatal("TestAuthorizingResolver: Delete failed:", err)
+	}
+
+	stub, _ := resolvers.NewAuthorizingResolver(newStubResolver(1, 0, "", nil), testAuthorizer)
+
+	if _, ok := stub.(resolvers.MutableResolver); ok {
+		t.Fatal("TestAuthorizingResolver: Expected read only resolver not to be mutable")
+	}
+}
+
+func TestAuthorizingComposite(t *testing.T) {
+	composite := newStrategyResolver(t, testInfo, newStubResolver(1, 0, "value", nil))
+	denyAnonymous := resolvers.AuthorizerFunc(func(authorizationContext context.Context, access *resolvers.Access) error {
+		if access.Principal == nil {
+			return resolvers.NewAccessDenied(access, "")
+		}
+		return nil
+	})
+
+	resolver, err := resolvers.NewAuthorizingResolver(composite, denyAnonymous)
+
+	if err != nil {
+		t.Fatal("TestAuthorizingComposite: NewAuthorizingResolver failed:", err)
+	}
+
+	principal := resolvers.WithPrincipal(testContext, resolvers.NewPrincipal("user"))
+
+	if _, err := resolver.Get(principal, &typedSelector{key: "1"}); err != nil {
+		t.Fatal("TestAuthorizingComposite: Get failed:", err)
+	}
+
+	// the entity is now cached by the composite, but must still be authorized
+	if _, err := resolver.Get(testContext, &typedSelector{key: "1"}); !errors.Is(err, resolvers.ErrAccessDenied) {
+		t.Fatal("TestAuthorizingComposite: Expected anonymous get to be denied, got:", err)
+	}
+
+	if resolvers.PrincipalFrom(testContext) != nil || resolvers.PrincipalFrom(principal).Name() != "user" {
+		t.Fatal("TestAuthorizingComposite: Unexpected principal")
+	}
+}
+
+func TestAuthorizedComponents(t *testing.T) {
+	composite, err := resolvers.NewCompositeResolver(testInfo)
+
+	if err != nil {
+		t.Fatal("TestAuthorizedComponents: NewCompositeResolver failed:", err)
+	}
+
+	allowUser := resolvers.AuthorizerFunc(func(authorizationContext context.Context, access *resolvers.Access) error {
+		if access.Principal == nil || access.Principal.Name() != "user" {
+			return resolvers.NewAccessDenied(access, "")
+		}
+		return nil
+	})
+
+	component, _ := resolvers.NewAuthorizingResolver(newStubResolver(1, 20*time.Millisecond, "value", nil), allowUser)
+
+	if err := composite.RegisterComponent(component); err != nil {
+		t.Fatal("TestAuthorizedComponents: RegisterComponent failed:", err)
+	}
+
+	user := resolvers.WithPrincipal(testContext, resolvers.NewPrincipal("user"))
+	other := resolvers.WithPrincipal(testContext, resolvers.NewPrincipal("other"))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			if _, err := composite.Get(user, &typedSelector{key: "1"}); err != nil {
+				errs <- err
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			if _, err := composite.Get(other, &typedSelector{key: "1"}); !errors.Is(err, resolvers.ErrAccessDenied) {
+				errs <- errors.New("expected access denied")
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal("TestAuthorizedComponents: Concurrent get failed:", err)
+	}
+
+	if _, err := composite.Get(testContext, &typedSelector{key: "1"}); !errors.Is(err, resolvers.ErrAccessDenied) {
+		t.Fatal("TestAuthorizedComponents: Expected anonymous get to be denied, got:", err)
+	}
+}
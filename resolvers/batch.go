@@ -0,0 +1,301 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/distributed-vision/go-resources/resolvers/infokeys"
+	"github.com/distributed-vision/go-resources/util"
+)
+
+var DefaultBatchParallelism = 8
+
+// Result is the outcome of resolving one of the selectors passed to GetMany
+type Result struct {
+	Selector Selector
+	Entity   interface{}
+	Err      error
+}
+
+// BatchResolver is implemented by resolvers which can resolve many selectors
+// with a single call to their store. GetMany returns a result for each of
+// selectors, in the order of selectors
+type BatchResolver interface {
+	Resolver
+	GetMany(resolutionContext context.Context, selectors []Selector) []Result
+}
+
+// batchGroup holds the indexes of the selectors resolved by the same
+// components
+type batchGroup struct {
+	entries []*componentEntry
+	indexes []int
+}
+
+func batchGroupKey(entries []*componentEntry) string {
+	keys := make([]string, len(entries))
+
+	for index, entry := range entries {
+		keys[index] = fmt.Sprintf("%p", entry)
+	}
+
+	sort.Strings(keys)
+
+	return strings.Join(keys, ",")
+}
+
+// GetMany resolves each of selectors. Cached entities are returned without
+// consulting the components, the remaining selectors are grouped by the
+// components which resolve them and the components are consulted in priority
+// order, as with the ORDERED strategy. Components which implement
+// BatchResolver receive all of a group's outstanding selectors in one call,
+// others are called for each selector with at most BATCH_PARALLELISM calls in
+// flight
+func (this *CompositeResolver) GetMany(resolutionContext context.Context, selectors []Selector) []Result {
+	results := make([]Result, len(selectors))
+	groups := make(map[string]*batchGroup)
+
+	for index, selector := range selectors {
+		results[index].Selector = selector
+
+		if selector == nil {
+			results[index].Err = fmt.Errorf("Resolve Failed: selector cannot be nil")
+			continue
+		}
+
+		if shareable(resolutionContext) {
+			if entity, err := this.CachingResolver.Get(resolutionContext, selector); err == nil {
+				results[index].Entity = entity
+				continue
+			}
+
+			if err := this.cachedNotFound(selector); err != nil {
+				results[index].Err = err
+				continue
+			}
+		}
+
+		entries, err := this.matchingEntries(selector)
+
+		if err == nil && len(entries) == 0 {
+			err = NewNoResolverForType(selector.Type())
+		}
+
+		if err != nil {
+			results[index].Err = err
+			continue
+		}
+
+		key := batchGroupKey(entries)
+		group, ok := groups[key]
+
+		if !ok {
+			sortByPriority(entries)
+			group = &batchGroup{entries: entries}
+			groups[key] = group
+		}
+
+		group.indexes = append(group.indexes, index)
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(len(groups))
+
+	for _, group := range groups {
+		go func(group *batchGroup) {
+			defer wg.Done()
+			this.resolveGroup(resolutionContext, group, results)
+		}(group)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+func (this *CompositeResolver) resolveGroup(resolutionContext context.Context, group *batchGroup, results []Result) {
+	pending := group.indexes
+	errors := make(map[int][]error)
+
+	for _, entry := range group.entries {
+		if len(pending) == 0 || resolutionContext.Err() != nil {
+			break
+		}
+
+		selectors := make([]Selector, len(pending))
+
+		for index, resultIndex := range pending {
+			selectors[index] = results[resultIndex].Selector
+		}
+
+		remaining := []int{}
+
+		for index, result := range this.resolveBatch(resolutionContext, entry, selectors) {
+			resultIndex := pending[index]
+
+			if result.err == nil {
+				this.cacheResult(resolutionContext, result)
+				results[resultIndex].Entity = result.result
+			} else {
+				errors[resultIndex] = append(errors[resultIndex], result.componentError())
+				remaining = append(remaining, resultIndex)
+			}
+		}
+
+		pending = remaining
+	}
+
+	for _, index := range pending {
+		if len(errors[index]) == 0 {
+			results[index].Err = resolutionContext.Err()
+		} else {
+			results[index].Err = this.resolveError(resolutionContext, results[index].Selector, errors[index])
+		}
+	}
+}
+
+// resolveBatch resolves selectors with a single component, returning a
+// result for each selector
+func (this *CompositeResolver) resolveBatch(resolutionContext context.Context, entry *componentEntry, selectors []Selector) []*componentResult {
+	results := make([]*componentResult, len(selectors))
+
+	failAll := func(err error) []*componentResult {
+		for index := range results {
+			results[index] = &componentResult{entry, nil, err}
+		}
+		return results
+	}
+
+	resolver, err := entry.instance(resolutionContext)
+
+	if err != nil {
+		return failAll(err)
+	}
+
+	if batchResolver, ok := resolver.(BatchResolver); ok {
+		return this.getBatch(resolutionContext, entry, batchResolver, selectors)
+	}
+
+	parallelism := intInfoValue(entry.ResolverInfo(), infokeys.BATCH_PARALLELISM,
+		intInfoValue(this.ResolverInfo(), infokeys.BATCH_PARALLELISM, DefaultBatchParallelism))
+
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	semaphore := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+
+	for index, selector := range selectors {
+		select {
+		case semaphore <- struct{}{}:
+		case <-resolutionContext.Done():
+			results[index] = &componentResult{entry, nil, resolutionContext.Err()}
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(index int, selector Selector) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			results[index] = this.resolveComponent(resolutionContext, entry, selector)
+		}(index, selector)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// getBatch resolves selectors with a single call to a BatchResolver
+// component, applying its TIMEOUT and BREAKER_THRESHOLD policies. Batch calls
+// aren't retried
+func (this *CompositeResolver) getBatch(resolutionContext context.Context, entry *componentEntry, resolver BatchResolver, selectors []Selector) []*componentResult {
+	info := entry.ResolverInfo()
+	timeout := durationInfoValue(info, infokeys.TIMEOUT, 0)
+	threshold := intInfoValue(info, infokeys.BREAKER_THRESHOLD, 0)
+	cooldown := durationInfoValue(info, infokeys.BREAKER_COOLDOWN, DefaultBreakerCooldown)
+
+	results := make([]*componentResult, len(selectors))
+
+	failAll := func(err error) []*componentResult {
+		for index := range results {
+			results[index] = &componentResult{entry, nil, err}
+		}
+		return results
+	}
+
+	if !entry.breaker.allow(this.clock.Now(), cooldown) {
+		return failAll(NewTemporaryError(fmt.Sprintf("Resolve Failed: circuit open for %v", info.ResolverType()), nil))
+	}
+
+	var expired <-chan time.Time
+
+	if timeout > 0 {
+		expired = this.clock.After(timeout)
+	}
+
+	spanContext, span := GetInstrumentation().StartSpan(resolutionContext, OP_RESOLVE, info.ResolverType())
+
+	call := util.Go(spanContext, func(callContext context.Context) ([]Result, error) {
+		return resolver.GetMany(callContext, selectors), nil
+	})
+
+	var batch []Result
+
+	select {
+	case <-call.Done():
+		batch, _ = call.Await()
+	case <-expired:
+		call.Cancel()
+		err := NewTimeout(fmt.Sprintf("Resolve Failed: %v timed out after %v", info.ResolverType(), timeout), nil)
+		span.End(err)
+		entry.breaker.failure(this.clock.Now(), threshold, true)
+		return failAll(err)
+	case <-resolutionContext.Done():
+		call.Cancel()
+		span.End(resolutionContext.Err())
+		entry.breaker.abandon()
+		return failAll(resolutionContext.Err())
+	}
+
+	if len(batch) != len(selectors) {
+		err := fmt.Errorf("Resolve Failed: %v returned %d results for %d selectors", info.ResolverType(), len(batch), len(selectors))
+		span.End(err)
+		entry.breaker.failure(this.clock.Now(), threshold, false)
+		return failAll(err)
+	}
+
+	for index, result := range batch {
+		results[index] = &componentResult{entry, result.Entity, result.Err}
+	}
+
+	// the component has failed only if it neither resolved nor reported as
+	// not found any of the selectors
+	var failure error
+
+	for _, result := range batch {
+		if result.Err == nil || IsNotFound(result.Err) {
+			failure = nil
+			break
+		}
+
+		failure = result.Err
+	}
+
+	span.End(failure)
+
+	if failure != nil {
+		entry.breaker.failure(this.clock.Now(), threshold, false)
+	} else {
+		entry.breaker.success()
+	}
+
+	return results
+}
@@ -0,0 +1,157 @@
+package resolvers_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/infokeys"
+	"github.com/distributed-vision/go-resources/util/random"
+)
+
+// batchResolver resolves the keys in its entities map with GetMany
+type batchResolver struct {
+	*stubResolver
+	entities map[string]string
+	batches  int32
+}
+
+func (this *batchResolver) GetMany(resolutionContext context.Context, selectors []resolvers.Selector) []resolvers.Result {
+	atomic.AddInt32(&this.batches, 1)
+	results := make([]resolvers.Result, len(selectors))
+
+	for index, selector := range selectors {
+		results[index].Selector = selector
+
+		if value, ok := this.entities[selector.Key().(string)]; ok {
+			results[index].Entity = entity{selector.Key().(string), value}
+		} else {
+			results[index].Err = resolvers.NewEntityNotFound("missing", nil)
+		}
+	}
+
+	return results
+}
+
+// concurrentResolver records the largest number of concurrent calls to
+// Resolve
+type concurrentResolver struct {
+	*stubResolver
+	mutex   sync.Mutex
+	current int
+	maximum int
+}
+
+func (this *concurrentResolver) Resolve(resolutionContext context.Context, selector resolvers.Selector) (chan interface{}, chan error) {
+	this.mutex.Lock()
+	this.current++
+	if this.current > this.maximum {
+		this.maximum = this.current
+	}
+	this.mutex.Unlock()
+
+	cres, cerr := this.stubResolver.Resolve(resolutionContext, selector)
+	cResOut, cErrOut := make(chan interface{}, 1), make(chan error, 1)
+
+	go func() {
+		for res := range cres {
+			cResOut <- res
+		}
+		for err := range cerr {
+			cErrOut <- err
+		}
+
+		this.mutex.Lock()
+		this.current--
+		this.mutex.Unlock()
+
+		close(cResOut)
+		close(cErrOut)
+	}()
+
+	return cResOut, cErrOut
+}
+
+func TestGetMany(t *testing.T) {
+	resolver, err := resolvers.NewCompositeResolver(testInfo)
+
+	if err != nil {
+		t.Fatal("TestGetMany: NewCompositeResolver failed:", err)
+	}
+
+	keys := []string{random.RandomString(20), random.RandomString(20), random.RandomString(20)}
+
+	batch := &batchResolver{stubResolver: newStubResolver(2, 0, "", nil),
+		entities: map[string]string{keys[0]: "batch", keys[1]: "batch"}}
+	fallback := newStubResolver(1, 0, "fallback", nil)
+
+	for _, component := range []resolvers.Resolver{batch, fallback} {
+		if err := resolver.RegisterComponent(component); err != nil {
+			t.Fatal("TestGetMany: RegisterComponent failed:", err)
+		}
+	}
+
+	selectors := []resolvers.Selector{&typedSelector{key: keys[0]}, &typedSelector{key: keys[1]}, &typedSelector{key: keys[2]}}
+	results := resolver.GetMany(testContext, selectors)
+
+	for index, expected := range []string{"batch", "batch", "fallback"} {
+		if results[index].Err != nil || results[index].Entity.(entity).value != expected || results[index].Selector != selectors[index] {
+			t.Fatalf("TestGetMany: Expected %s for selector %d got: %+v", expected, index, results[index])
+		}
+	}
+
+	if batches, calls := atomic.LoadInt32(&batch.batches), atomic.LoadInt32(&batch.calls); batches != 1 || calls != 0 {
+		t.Fatal("TestGetMany: Expected a single batch call, got:", batches, calls)
+	}
+
+	if calls := atomic.LoadInt32(&fallback.calls); calls != 1 {
+		t.Fatal("TestGetMany: Expected one fallback call, got:", calls)
+	}
+
+	resolver.GetMany(testContext, selectors)
+
+	if batches := atomic.LoadInt32(&batch.batches); batches != 1 {
+		t.Fatal("TestGetMany: Expected cached results to be served without a batch call, got:", batches)
+	}
+
+	empty, err := resolvers.NewCompositeResolver(testInfo)
+
+	if err != nil {
+		t.Fatal("TestGetMany: NewCompositeResolver failed:", err)
+	}
+
+	if results := empty.GetMany(testContext, selectors[:1]); results[0].Err == nil {
+		t.Fatal("TestGetMany: Expected GetMany without components to fail")
+	}
+}
+
+func TestGetManyParallelism(t *testing.T) {
+	resolver := newStrategyResolver(t, testInfo.WithValue(infokeys.BATCH_PARALLELISM, 2))
+	component := &concurrentResolver{stubResolver: newStubResolver(1, 10*time.Millisecond, "value", nil)}
+
+	if err := resolver.RegisterComponent(component); err != nil {
+		t.Fatal("TestGetManyParallelism: RegisterComponent failed:", err)
+	}
+
+	selectors := []resolvers.Selector{}
+
+	for i := 0; i < 6; i++ {
+		selectors = append(selectors, &typedSelector{key: random.RandomString(20)})
+	}
+
+	for _, result := range resolver.GetMany(testContext, selectors) {
+		if result.Err != nil {
+			t.Fatal("TestGetManyParallelism: GetMany failed:", result.Err)
+		}
+	}
+
+	component.mutex.Lock()
+	defer component.mutex.Unlock()
+
+	if component.maximum != 2 {
+		t.Fatal("TestGetManyParallelism: Expected at most 2 concurrent calls, got:", component.maximum)
+	}
+}
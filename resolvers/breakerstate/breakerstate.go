@@ -0,0 +1,40 @@
+package breakerstate
+
+import (
+	"errors"
+	"strings"
+)
+
+type BreakerState int
+
+const (
+	CLOSED BreakerState = iota
+	OPEN
+	HALF_OPEN
+)
+
+func (this BreakerState) String() string {
+	switch this {
+	case CLOSED:
+		return "closed"
+	case OPEN:
+		return "open"
+	case HALF_OPEN:
+		return "half-open"
+	default:
+		return "invalid"
+	}
+}
+
+func Parse(value string) (BreakerState, error) {
+	switch strings.ToUpper(value) {
+	case "CLOSED":
+		return CLOSED, nil
+	case "OPEN":
+		return OPEN, nil
+	case "HALF-OPEN", "HALF_OPEN":
+		return HALF_OPEN, nil
+	default:
+		return -1, errors.New("Unknown breaker state: " + value)
+	}
+}
@@ -3,8 +3,13 @@ package resolvers
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/distributed-vision/go-resources/ids"
 	"github.com/distributed-vision/go-resources/resolvers/infokeys"
+	"github.com/distributed-vision/go-resources/util/clock"
 	lru "github.com/hashicorp/golang-lru"
 )
 
@@ -22,9 +27,113 @@ type arcCache struct {
 	*lru.ARCCache
 }
 
+// expiringCache removes entries from the underlying cache once they are
+// older than ttl, a zero ttl means entries never expire
+type expiringCache struct {
+	Cache
+	clock    func() clock.Clock
+	ttl      time.Duration
+	mutex    *sync.Mutex
+	expiries map[interface{}]time.Time
+}
+
+func (this *expiringCache) expired(key interface{}) bool {
+	this.mutex.Lock()
+	expiry, ok := this.expiries[key]
+	this.mutex.Unlock()
+
+	if ok && !this.clock().Now().Before(expiry) {
+		this.Remove(key)
+		return true
+	}
+
+	return false
+}
+
+func (this *expiringCache) Get(key interface{}) (interface{}, bool) {
+	if this.expired(key) {
+		return nil, false
+	}
+
+	return this.Cache.Get(key)
+}
+
+func (this *expiringCache) Peek(key interface{}) (interface{}, bool) {
+	if this.expired(key) {
+		return nil, false
+	}
+
+	return this.Cache.Peek(key)
+}
+
+func (this *expiringCache) Keys() []interface{} {
+	keys := []interface{}{}
+
+	for _, key := range this.Cache.Keys() {
+		if !this.expired(key) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+func (this *expiringCache) Add(key interface{}, value interface{}) {
+	this.Cache.Add(key, value)
+
+	if this.ttl <= 0 {
+		return
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.expiries[key] = this.clock().Now().Add(this.ttl)
+
+	// drop the expiries of entries the underlying cache has evicted
+	if len(this.expiries) > 2*this.Cache.Len()+16 {
+		for key := range this.expiries {
+			if _, ok := this.Cache.Peek(key); !ok {
+				delete(this.expiries, key)
+			}
+		}
+	}
+}
+
+func (this *expiringCache) Remove(key interface{}) {
+	this.Cache.Remove(key)
+	this.mutex.Lock()
+	delete(this.expiries, key)
+	this.mutex.Unlock()
+}
+
+func (this *expiringCache) Purge() {
+	this.Cache.Purge()
+	this.mutex.Lock()
+	this.expiries = make(map[interface{}]time.Time)
+	this.mutex.Unlock()
+}
+
+type notFoundEntry struct {
+	entityType ids.TypeIdentifier
+	err        error
+	expiry     time.Time
+}
+
+var DefaultNegativeCacheSize = 100
+
+// CachingResolver holds resolved entities for the ttl given by the CACHE_TTL
+// info value. If NEGATIVE_CACHE_TTL is set, EntityNotFound errors are also
+// cached for that period, keyed by the selector key
 type CachingResolver struct {
+	hits         int64
+	misses       int64
 	resolverInfo ResolverInfo
 	cache        Cache
+	cacheSize    int
+	notFound     *lru.Cache
+	notFoundTTL  time.Duration
+	clock        clock.Clock
 }
 
 func (this *CachingResolver) Cache() Cache {
@@ -36,19 +145,40 @@ func NewCachingResolver(baseInfo ResolverInfo, cacheSize int) (*CachingResolver,
 		return nil, fmt.Errorf("base resolver info must be defined")
 	}
 
-	if cacheSizeValue := baseInfo.Value(infokeys.CACHE_SIZE); cacheSizeValue != nil {
-		if infoCacheSize, ok := cacheSizeValue.(int); ok {
-			cacheSize = infoCacheSize
-		}
-	}
-
+	cacheSize = intInfoValue(baseInfo, infokeys.CACHE_SIZE, cacheSize)
 	cache, err := lru.NewARC(cacheSize)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &CachingResolver{baseInfo.DerivedCopy(), arcCache{cache}}, nil
+	resolver := &CachingResolver{
+		resolverInfo: baseInfo.DerivedCopy(),
+		cacheSize:    cacheSize,
+		notFoundTTL:  durationInfoValue(baseInfo, infokeys.NEGATIVE_CACHE_TTL, 0),
+		clock:        clock.System}
+
+	resolver.cache = &expiringCache{
+		Cache:    arcCache{cache},
+		clock:    func() clock.Clock { return resolver.clock },
+		ttl:      durationInfoValue(baseInfo, infokeys.CACHE_TTL, 0),
+		mutex:    &sync.Mutex{},
+		expiries: make(map[interface{}]time.Time)}
+
+	if resolver.notFoundTTL > 0 {
+		resolver.notFound, err = lru.New(intInfoValue(baseInfo, infokeys.NEGATIVE_CACHE_SIZE, DefaultNegativeCacheSize))
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resolver, nil
+}
+
+// SetClock replaces the clock used to expire cache entries
+func (this *CachingResolver) SetClock(clock clock.Clock) {
+	this.clock = clock
 }
 
 func (this *CachingResolver) ResolverInfo() ResolverInfo {
@@ -60,21 +190,12 @@ func (this *CachingResolver) Get(resolutionContext context.Context, selector Sel
 	key := selector.Key()
 
 	if key != nil {
-		var keyVal string
-
-		switch key.(type) {
-		case string:
-			keyVal = key.(string)
-		case []byte:
-			keyVal = string(key.([]byte))
-		default:
-			keyVal = fmt.Sprintf("%v", key)
-		}
-
-		entity, ok := this.cache.Get(keyVal)
+		entity, ok := this.cache.Get(KeyString(key))
 
 		if ok {
 			if selector.Test(entity) {
+				atomic.AddInt64(&this.hits, 1)
+				GetInstrumentation().CacheLookup(this.resolverInfo.ResolverType(), true)
 				return entity, nil
 			}
 		}
@@ -82,13 +203,82 @@ func (this *CachingResolver) Get(resolutionContext context.Context, selector Sel
 		for _, key := range this.cache.Keys() {
 			if entity, ok := this.cache.Peek(key); ok {
 				if selector.Test(entity) {
+					atomic.AddInt64(&this.hits, 1)
+					GetInstrumentation().CacheLookup(this.resolverInfo.ResolverType(), true)
 					return entity, nil
 				}
 			}
 		}
 	}
 
-	return nil, fmt.Errorf("Can't resolve entity for %v", selector)
+	atomic.AddInt64(&this.misses, 1)
+	GetInstrumentation().CacheLookup(this.resolverInfo.ResolverType(), false)
+
+	return nil, NewEntityNotFound(fmt.Sprintf("Can't resolve entity for %v", selector), nil)
+}
+
+// cachedNotFound returns the EntityNotFound error cached for the selector's
+// key, or nil if there is none
+func (this *CachingResolver) cachedNotFound(selector Selector) error {
+	if this.notFound == nil || selector.Key() == nil {
+		return nil
+	}
+
+	key := KeyString(selector.Key())
+
+	if value, ok := this.notFound.Get(key); ok {
+		entry := value.(*notFoundEntry)
+
+		if !this.clock.Now().Before(entry.expiry) {
+			this.notFound.Remove(key)
+			return nil
+		}
+
+		if entry.entityType == nil || selector.Type() == nil || entry.entityType.Equals(selector.Type()) {
+			return entry.err
+		}
+	}
+
+	return nil
+}
+
+func (this *CachingResolver) addNotFound(selector Selector, err error) {
+	if this.notFound == nil || selector.Key() == nil {
+		return
+	}
+
+	this.notFound.Add(KeyString(selector.Key()),
+		&notFoundEntry{selector.Type(), err, this.clock.Now().Add(this.notFoundTTL)})
+}
+
+// Invalidate removes any cached entity, or cached not found error, for key
+func (this *CachingResolver) Invalidate(key interface{}) {
+	keyString := KeyString(key)
+
+	this.cache.Remove(keyString)
+
+	if this.notFound != nil {
+		this.notFound.Remove(keyString)
+	}
+}
+
+// InvalidateWhere removes the cached entities matching selector. Cached not
+// found errors are removed for the selector's key, or all of them are
+// removed if the selector has no key
+func (this *CachingResolver) InvalidateWhere(selector Selector) {
+	for _, key := range this.cache.Keys() {
+		if entity, ok := this.cache.Peek(key); ok && selector.Test(entity) {
+			this.cache.Remove(key)
+		}
+	}
+
+	if this.notFound != nil {
+		if selector.Key() != nil {
+			this.notFound.Remove(KeyString(selector.Key()))
+		} else {
+			this.notFound.Purge()
+		}
+	}
 }
 
 func (this *CachingResolver) Resolve(resolutionContext context.Context, selector Selector) (chan interface{}, chan error) {
@@ -109,3 +299,21 @@ func (this *CachingResolver) Resolve(resolutionContext context.Context, selector
 
 	return cres, cerr
 }
+
+func (this *CachingResolver) Query(queryContext context.Context, selector Selector, opts QueryOpts) (<-chan interface{}, <-chan error) {
+	stream := NewQueryStream(queryContext, opts, this.resolverInfo.KeyExtractor())
+
+	go func() {
+		for _, key := range this.cache.Keys() {
+			if entity, ok := this.cache.Peek(key); ok && selector.Test(entity) {
+				if !stream.AddKeyed(KeyString(key), entity) {
+					break
+				}
+			}
+		}
+
+		stream.Close(nil)
+	}()
+
+	return stream.Results()
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/distributed-vision/go-resources/ids"
 	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/infokeys"
+	"github.com/distributed-vision/go-resources/resolvers/localresolver"
 	"github.com/distributed-vision/go-resources/types/gotypeid"
 	"github.com/distributed-vision/go-resources/util"
+	"github.com/distributed-vision/go-resources/util/clock"
 	"github.com/distributed-vision/go-resources/util/random"
 )
 
@@ -160,3 +164,110 @@ func TestResolverCache(t *testing.T) {
 	}
 
 }
+
+func TestCacheExpiry(t *testing.T) {
+	resolver, err := resolvers.NewCachingResolver(testInfo.WithValue(infokeys.CACHE_TTL, time.Minute), 128)
+
+	if err != nil {
+		t.Fatal("TestCacheExpiry: NewCachingResolver failed:", err)
+	}
+
+	testClock := clock.NewManual(time.Now())
+	resolver.SetClock(testClock)
+
+	key := random.RandomString(20)
+	resolver.Cache().Add(key, entity{key, "value"})
+
+	testClock.Advance(59 * time.Second)
+
+	if _, err := resolver.Get(testContext, &untypedSelector{key: key}); err != nil {
+		t.Fatal("TestCacheExpiry: entity expired early:", err)
+	}
+
+	testClock.Advance(time.Second)
+
+	if _, err := resolver.Get(testContext, &untypedSelector{key: key}); err == nil {
+		t.Fatal("TestCacheExpiry: entity should have expired")
+	}
+
+	if resolver.Cache().Len() != 0 {
+		t.Fatal("TestCacheExpiry: expired entity should be removed, len:", resolver.Cache().Len())
+	}
+}
+
+func TestNegativeCache(t *testing.T) {
+	missing := newStubResolver(0, 0, "", resolvers.NewEntityNotFound("missing", nil))
+	resolver := newStrategyResolver(t,
+		testInfo.WithValue(infokeys.NEGATIVE_CACHE_TTL, "10s"), missing)
+
+	testClock := clock.NewManual(time.Now())
+	resolver.SetClock(testClock)
+
+	selector := &typedSelector{key: random.RandomString(20)}
+
+	for i := 0; i < 3; i++ {
+		_, err := resolver.Get(testContext, selector)
+
+		if _, ok := err.(*resolvers.EntityNotFound); !ok {
+			t.Fatal("TestNegativeCache: expected EntityNotFound got:", err)
+		}
+	}
+
+	if missing.calls != 1 {
+		t.Fatal("TestNegativeCache: expected 1 component call got:", missing.calls)
+	}
+
+	testClock.Advance(10 * time.Second)
+	resolver.Get(testContext, selector)
+
+	if missing.calls != 2 {
+		t.Fatal("TestNegativeCache: expected expired entry to be re-resolved, calls:", missing.calls)
+	}
+
+	resolver.InvalidateWhere(selector)
+	resolver.Get(testContext, selector)
+
+	if missing.calls != 3 {
+		t.Fatal("TestNegativeCache: expected invalidated entry to be re-resolved, calls:", missing.calls)
+	}
+}
+
+func TestInvalidateOnWrite(t *testing.T) {
+	component, err := localresolver.New(resolvers.NewResolverInfo(testResolverType, testResolvableTypes, nil, testExtractor, nil))
+
+	if err != nil {
+		t.Fatal("TestInvalidateOnWrite: localresolver.New failed:", err)
+	}
+
+	resolver, err := resolvers.NewCompositeResolver(testInfo.WithValue("negativeCacheTTL", "1h"))
+
+	if err != nil {
+		t.Fatal("TestInvalidateOnWrite: NewCompositeResolver failed:", err)
+	}
+
+	resolver.RegisterComponent(component)
+
+	key := random.RandomString(20)
+
+	if _, err := resolver.Get(testContext, &typedSelector{key: key}); err == nil {
+		t.Fatal("TestInvalidateOnWrite: expected missing entity")
+	}
+
+	component.Put(testContext, entity{key, "first"})
+
+	if resolved, err := resolver.Get(testContext, &typedSelector{key: key}); err != nil || resolved.(entity).value != "first" {
+		t.Fatal("TestInvalidateOnWrite: put should invalidate the negative cache got:", resolved, err)
+	}
+
+	component.Post(testContext, entity{key, "second"})
+
+	if resolved, err := resolver.Get(testContext, &typedSelector{key: key}); err != nil || resolved.(entity).value != "second" {
+		t.Fatal("TestInvalidateOnWrite: post should invalidate the cache got:", resolved, err)
+	}
+
+	component.Delete(testContext, &typedSelector{key: key})
+
+	if _, err := resolver.Get(testContext, &typedSelector{key: key}); err == nil {
+		t.Fatal("TestInvalidateOnWrite: delete should invalidate the cache")
+	}
+}
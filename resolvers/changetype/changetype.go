@@ -0,0 +1,40 @@
+package changetype
+
+import (
+	"errors"
+	"strings"
+)
+
+type ChangeType int
+
+const (
+	PUT ChangeType = iota
+	UPDATE
+	DELETE
+)
+
+func (this ChangeType) String() string {
+	switch this {
+	case PUT:
+		return "put"
+	case UPDATE:
+		return "update"
+	case DELETE:
+		return "delete"
+	default:
+		return "invalid"
+	}
+}
+
+func Parse(value string) (ChangeType, error) {
+	switch strings.ToUpper(value) {
+	case "PUT":
+		return PUT, nil
+	case "UPDATE":
+		return UPDATE, nil
+	case "DELETE":
+		return DELETE, nil
+	default:
+		return -1, errors.New("Unknown change type: " + value)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"sync"
 
 	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/resolvers/infokeys"
+	"github.com/distributed-vision/go-resources/resolvers/strategytype"
 	"github.com/distributed-vision/go-resources/util"
 )
 
@@ -13,20 +15,85 @@ type componentEntry struct {
 	entityType ids.TypeIdentifier
 	factory    ResolverFactory
 	resolver   Resolver
+	listener   ChangeListener
+	mutex      *sync.Mutex
+	newMutex   *sync.Mutex
+	breaker    *breaker
+}
+
+func newComponentEntry(entityType ids.TypeIdentifier, factory ResolverFactory, resolver Resolver, listener ChangeListener) *componentEntry {
+	return &componentEntry{entityType, factory, resolver, listener, &sync.Mutex{}, &sync.Mutex{}, newBreaker()}
+}
+
+func (this *componentEntry) current() Resolver {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.resolver
 }
 
 func (this *componentEntry) ResolverInfo() ResolverInfo {
-	if this.resolver != nil {
-		return this.resolver.ResolverInfo()
+	if resolver := this.current(); resolver != nil {
+		return resolver.ResolverInfo()
 	}
 
 	return this.factory.ResolverInfo()
 }
 
+// instance returns the entry's resolver, creating it from the entry's
+// factory on first use. Concurrent callers wait for a single creation
+func (this *componentEntry) instance(resolutionContext context.Context) (Resolver, error) {
+	if resolver := this.current(); resolver != nil {
+		return resolver, nil
+	}
+
+	this.newMutex.Lock()
+	defer this.newMutex.Unlock()
+
+	if resolver := this.current(); resolver != nil {
+		return resolver, nil
+	}
+
+	spanContext, span := GetInstrumentation().StartSpan(resolutionContext, OP_INSTANTIATE, this.factory.ResolverInfo().ResolverType())
+	resolver, err := this.factory.New(spanContext)
+	span.End(err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if this.listener != nil {
+		subscribe(resolver, this.listener)
+	}
+
+	this.mutex.Lock()
+	this.resolver = resolver
+	this.mutex.Unlock()
+
+	return resolver, nil
+}
+
+// mutableResolver returns the entry's resolver if it accepts writes, creating
+// it from the entry's factory if it has not been instantiated yet
+func (this *componentEntry) mutableResolver(resolutionContext context.Context) Resolver {
+	resolver, err := this.instance(resolutionContext)
+
+	if err != nil {
+		return nil
+	}
+
+	if _, ok := resolver.(MutableResolver); ok || resolver.ResolverInfo().IsMutable() {
+		return resolver
+	}
+
+	return nil
+}
+
 type CompositeResolver struct {
 	*CachingResolver
+	*ChangeFeed
 	componentMap      map[string][]*componentEntry
 	componentMapMutex *sync.Mutex
+	flights           *flightGroup
 }
 
 var DefaultCacheSize = 300
@@ -40,8 +107,10 @@ func NewCompositeResolver(baseInfo ResolverInfo) (*CompositeResolver, error) {
 	}
 
 	return &CompositeResolver{base,
+		&ChangeFeed{},
 		make(map[string][]*componentEntry),
-		&sync.Mutex{}}, nil
+		&sync.Mutex{},
+		newFlightGroup()}, nil
 }
 
 func (this *CompositeResolver) RegisterComponentFactory(resolverFactory ResolverFactory, initialiseResolver bool) error {
@@ -63,10 +132,12 @@ func (this *CompositeResolver) RegisterComponentFactory(resolverFactory Resolver
 		if err != nil {
 			return err
 		}
+
+		this.subscribe(resolver)
 	}
 
 	for _, resolvableType := range resolvableTypes {
-		entry := componentEntry{resolvableType, resolverFactory, resolver}
+		entry := newComponentEntry(resolvableType, resolverFactory, resolver, this.invalidate)
 
 		if entries, ok := this.componentMap[string(resolvableType.Value())]; ok {
 			/*for _, entry := range entries {
@@ -77,9 +148,9 @@ func (this *CompositeResolver) RegisterComponentFactory(resolverFactory Resolver
 				}
 			}*/
 
-			this.componentMap[string(resolvableType.Value())] = append(entries, &entry)
+			this.componentMap[string(resolvableType.Value())] = append(entries, entry)
 		} else {
-			this.componentMap[string(resolvableType.Value())] = []*componentEntry{&entry}
+			this.componentMap[string(resolvableType.Value())] = []*componentEntry{entry}
 		}
 	}
 
@@ -96,8 +167,10 @@ func (this *CompositeResolver) RegisterComponent(resolver Resolver) error {
 		return fmt.Errorf("Resolver has no resolvable types")
 	}
 
+	this.subscribe(resolver)
+
 	for _, resolvableType := range resolvableTypes {
-		entry := componentEntry{resolvableType, nil, resolver}
+		entry := newComponentEntry(resolvableType, nil, resolver, this.invalidate)
 
 		if entries, ok := this.componentMap[string(resolvableType.Value())]; ok {
 			/*for _, entry := range entries {
@@ -108,14 +181,42 @@ func (this *CompositeResolver) RegisterComponent(resolver Resolver) error {
 				}
 			}*/
 
-			this.componentMap[string(resolvableType.Value())] = append(entries, &entry)
+			this.componentMap[string(resolvableType.Value())] = append(entries, entry)
 		} else {
-			this.componentMap[string(resolvableType.Value())] = []*componentEntry{&entry}
+			this.componentMap[string(resolvableType.Value())] = []*componentEntry{entry}
 		}
 	}
 
 	return nil
 }
+
+// invalidate removes the entity reported by a component change from the
+// cache, and reports the change to the composite's own watchers
+func (this *CompositeResolver) invalidate(event ChangeEvent) {
+	this.Invalidate(event.Key)
+	this.publish(event)
+}
+
+func (this *CompositeResolver) subscribe(resolver Resolver) {
+	subscribe(resolver, this.invalidate)
+}
+
+// subscribe calls listener with the changes reported by resolver. Changes
+// from resolvers which are only Watchable are delivered asynchronously
+func subscribe(resolver Resolver, listener ChangeListener) {
+	if notifier, ok := resolver.(ChangeNotifier); ok {
+		notifier.OnChange(listener)
+	} else if watchable, ok := resolver.(Watchable); ok {
+		events := watchable.Watch(context.Background(), nil)
+
+		go func() {
+			for event := range events {
+				listener(event)
+			}
+		}()
+	}
+}
+
 func (this *CompositeResolver) GetMutableComponents(getContext context.Context, selector Selector) []Resolver {
 	mutableComponents := []Resolver{}
 
@@ -124,31 +225,20 @@ func (this *CompositeResolver) GetMutableComponents(getContext context.Context,
 		if selector.Type() == nil {
 			for _, entries := range this.componentMap {
 				for _, entry := range entries {
-					if entry.ResolverInfo().Matches(selector) &&
-						entry.ResolverInfo().IsMutable() {
-						if entry.resolver == nil {
-							resolver, err := entry.factory.New(getContext)
-							if err != nil {
-								entry.resolver = resolver
-								mutableComponents = append(mutableComponents, entry.resolver)
-							}
+					if entry.ResolverInfo().Matches(selector) {
+						if resolver := entry.mutableResolver(getContext); resolver != nil {
+							mutableComponents = append(mutableComponents, resolver)
 						}
-						mutableComponents = append(mutableComponents, entry.resolver)
 					}
 				}
 			}
 		} else {
 			if entries, ok := this.componentMap[string(selector.Type().Value())]; ok {
 				for _, entry := range entries {
-					if entry.ResolverInfo().Matches(selector) && entry.ResolverInfo().IsMutable() {
-						if entry.resolver == nil {
-							resolver, err := entry.factory.New(getContext)
-							if err != nil {
-								entry.resolver = resolver
-								mutableComponents = append(mutableComponents, entry.resolver)
-							}
+					if entry.ResolverInfo().Matches(selector) {
+						if resolver := entry.mutableResolver(getContext); resolver != nil {
+							mutableComponents = append(mutableComponents, resolver)
 						}
-						mutableComponents = append(mutableComponents, entry.resolver)
 					}
 				}
 			}
@@ -159,21 +249,7 @@ func (this *CompositeResolver) GetMutableComponents(getContext context.Context,
 	return mutableComponents
 }
 
-func (this *CompositeResolver) Get(resolutionContext context.Context, selector Selector) (entity interface{}, err error) {
-	return util.Await(this.Resolve(resolutionContext, selector))
-}
-
-func (this *CompositeResolver) Resolve(resolutionContext context.Context, selector Selector) (chan interface{}, chan error) {
-	cResOut := make(chan interface{}, 1)
-	cErrOut := make(chan error, 1)
-
-	if result, err := this.CachingResolver.Get(resolutionContext, selector); err == nil {
-		cResOut <- result
-		close(cResOut)
-		close(cErrOut)
-		return cResOut, cErrOut
-	}
-
+func (this *CompositeResolver) matchingEntries(selector Selector) ([]*componentEntry, error) {
 	var resolverEntries = []*componentEntry{}
 	var err error
 
@@ -182,7 +258,6 @@ func (this *CompositeResolver) Resolve(resolutionContext context.Context, select
 		if selector.Type() == nil {
 			for _, entries := range this.componentMap {
 				for _, entry := range entries {
-					//fmt.Println("resolverinfo=", entry.resolver.ResolverInfo())
 					if entry.ResolverInfo().Matches(selector) {
 						resolverEntries = append(resolverEntries, entry)
 					}
@@ -196,7 +271,7 @@ func (this *CompositeResolver) Resolve(resolutionContext context.Context, select
 					}
 				}
 			} else {
-				err = fmt.Errorf("Resolve Failed: no resolver entries for type=%v", selector.Type())
+				err = NewNoResolverForType(selector.Type())
 			}
 		}
 		this.componentMapMutex.Unlock()
@@ -204,89 +279,268 @@ func (this *CompositeResolver) Resolve(resolutionContext context.Context, select
 		err = fmt.Errorf("Resolve Failed: selector cannot be nil")
 	}
 
+	return resolverEntries, err
+}
+
+func (this *CompositeResolver) Get(resolutionContext context.Context, selector Selector) (entity interface{}, err error) {
+	return util.Await(this.Resolve(resolutionContext, selector))
+}
+
+func (this *CompositeResolver) Resolve(resolutionContext context.Context, selector Selector) (chan interface{}, chan error) {
+	return this.ResolveFuture(resolutionContext, selector).Channels()
+}
+
+// ResolveFuture resolves selector as Resolve does, cancelling the returned
+// future cancels the resolution
+func (this *CompositeResolver) ResolveFuture(resolutionContext context.Context, selector Selector) *util.Future[interface{}] {
+	if shareable(resolutionContext) {
+		if result, err := this.CachingResolver.Get(resolutionContext, selector); err == nil {
+			return util.Resolved(result)
+		}
+
+		if err := this.cachedNotFound(selector); err != nil {
+			return util.Rejected[interface{}](err)
+		}
+	}
+
+	resolverEntries, err := this.matchingEntries(selector)
+
 	if err != nil {
-		cErrOut <- err
-		close(cResOut)
-		close(cErrOut)
-		return cResOut, cErrOut
+		return util.Rejected[interface{}](err)
 	}
 
 	if len(resolverEntries) == 0 {
-		cErrOut <- fmt.Errorf("Resolve Failed: no resolver for entity type=%s", selector.Type())
-		close(cResOut)
-		close(cErrOut)
-		return cResOut, cErrOut
+		return util.Rejected[interface{}](NewNoResolverForType(selector.Type()))
 	}
 
-	var wg sync.WaitGroup
+	sortByPriority(resolverEntries)
+
+	resolve := func(resolutionContext context.Context) (interface{}, error) {
+		return this.resolveEntries(resolutionContext, resolverEntries, selector)
+	}
+
+	return util.Go(resolutionContext, func(resolutionContext context.Context) (interface{}, error) {
+		key := flightKey(selector)
+
+		if key == "" || !shareable(resolutionContext) {
+			return resolve(resolutionContext)
+		}
+
+		result, err, shared := this.flights.do(resolutionContext, key, resolve)
+
+		// a shared result was resolved for another selector with the same
+		// key, so may not pass this selector's test
+		if shared && err == nil && !selector.Test(result) {
+			return resolve(resolutionContext)
+		}
+
+		return result, err
+	})
+}
+
+// shareable returns false for resolutions made on behalf of a principal, as
+// their results depend on what the principal may access they are neither
+// cached nor shared with concurrent resolutions
+func shareable(resolutionContext context.Context) bool {
+	return PrincipalFrom(resolutionContext) == nil
+}
+
+// flightKey identifies concurrent resolutions which can share a result
+func flightKey(selector Selector) string {
+	if selector.Key() == nil {
+		return ""
+	}
+
+	key := KeyString(selector.Key())
+
+	if key == "" {
+		return ""
+	}
+
+	if selector.Type() != nil {
+		return string(selector.Type().Value()) + "/" + key
+	}
+
+	return "/" + key
+}
+
+func (this *CompositeResolver) resolveEntries(resolutionContext context.Context, resolverEntries []*componentEntry, selector Selector) (interface{}, error) {
 	mergeContext, cancel := context.WithCancel(resolutionContext)
-	errors := make([]error, 0)
-	resultMutex := &sync.Mutex{}
-	var result interface{}
+	defer cancel()
+
+	var winner *componentResult
+	var errors []error
+
+	switch strategy := strategyInfoValue(this.ResolverInfo(), infokeys.RESOLVE_STRATEGY, strategytype.RACE); strategy {
+	case strategytype.ORDERED:
+		winner, errors = this.resolveOrdered(mergeContext, resolverEntries, selector)
+	case strategytype.HEDGED:
+		winner, errors = this.resolveHedged(mergeContext, resolverEntries, selector,
+			durationInfoValue(this.ResolverInfo(), infokeys.HEDGE_DELAY, DefaultHedgeDelay))
+	case strategytype.QUORUM:
+		winner, errors = this.resolveQuorum(mergeContext, resolverEntries, selector,
+			intInfoValue(this.ResolverInfo(), infokeys.QUORUM, len(resolverEntries)/2+1))
+	default:
+		winner, errors = this.resolveRace(mergeContext, resolverEntries, selector)
+	}
+
+	if winner != nil {
+		this.cacheResult(resolutionContext, winner)
+		return winner.result, nil
+	}
+
+	return nil, this.resolveError(resolutionContext, selector, errors)
+}
+
+// cacheResult adds a component's result to the cache under the key its
+// component extracts from it, unless it was resolved for a principal
+func (this *CompositeResolver) cacheResult(resolutionContext context.Context, result *componentResult) {
+	if !shareable(resolutionContext) {
+		return
+	}
+
+	if keyExtractor := result.entry.ResolverInfo().KeyExtractor(); keyExtractor != nil {
+		if key, ok := keyExtractor(result.result); ok {
+			this.Cache().Add(KeyString(key), result.result)
+		}
+	}
+}
+
+// resolveError combines the errors returned by the components consulted for
+// selector, recording the selector as not found if none of them found it
+// unless it was resolved for a principal
+func (this *CompositeResolver) resolveError(resolutionContext context.Context, selector Selector, errors []error) error {
+	multiError := NewMultiError("Resolve failed", errors)
+
+	if IsNotFound(multiError) {
+		err := NewEntityNotFound("", multiError)
+
+		if shareable(resolutionContext) {
+			this.addNotFound(selector, err)
+		}
+
+		return err
+	}
+
+	if allRetryable(errors) {
+		return NewTemporaryError("", multiError)
+	}
+
+	return multiError
+}
+
+func allRetryable(errors []error) bool {
+	for _, err := range errors {
+		if !IsRetryable(err) {
+			return false
+		}
+	}
 
-	resolve := func(componentEntry *componentEntry) {
+	return len(errors) > 0
+}
+
+// Query streams the entities matching selector from all of the matching
+// components, components which don't implement QueryResolver contribute the
+// result of a Resolve. Entities are de-duplicated by their component's key
+func (this *CompositeResolver) Query(queryContext context.Context, selector Selector, opts QueryOpts) (<-chan interface{}, <-chan error) {
+	stream := NewQueryStream(queryContext, opts, this.ResolverInfo().KeyExtractor())
+	resolverEntries, err := this.matchingEntries(selector)
+
+	if err != nil {
+		stream.Close(err)
+		return stream.Results()
+	}
+
+	componentOpts := QueryOpts{}
+
+	if !opts.isOrdered() {
+		componentOpts.Limit = opts.Limit
+	}
+
+	componentContext, cancel := context.WithCancel(queryContext)
+	errors := []error{}
+	errorsMutex := &sync.Mutex{}
+	var wg sync.WaitGroup
+
+	addError := func(entry *componentEntry, err error) {
+		if IsNotFound(err) || componentContext.Err() != nil {
+			return
+		}
+		errorsMutex.Lock()
+		errors = append(errors, &ComponentError{entry.ResolverInfo().ResolverType(), err})
+		errorsMutex.Unlock()
+	}
+
+	query := func(entry *componentEntry) {
 		defer wg.Done()
 
-		if componentEntry.resolver == nil {
-			resolver, err := componentEntry.factory.New(mergeContext)
+		keyExtractor := entry.ResolverInfo().KeyExtractor()
 
-			if err != nil {
-				errors = append(errors, err)
-				return
+		if keyExtractor == nil {
+			keyExtractor = this.ResolverInfo().KeyExtractor()
+		}
+
+		add := func(entity interface{}) {
+			var key string
+
+			if keyExtractor != nil {
+				if entityKey, ok := keyExtractor(entity); ok {
+					key = KeyString(entityKey)
+				}
 			}
 
-			componentEntry.resolver = resolver
+			if !stream.AddKeyed(key, entity) {
+				cancel()
+			}
 		}
 
-		cres, cerr := componentEntry.resolver.Resolve(mergeContext, selector)
+		resolver, err := entry.instance(componentContext)
 
-		if cres == nil || cerr == nil {
-			errors = append(errors, fmt.Errorf("Resolve Failed: Resolve channels are undefined"))
+		if err != nil {
+			addError(entry, err)
+			return
 		}
 
-		for result == nil {
-			select {
-			case res, ok := <-cres:
-				if ok {
-					if result == nil {
-						resultMutex.Lock()
-						if result == nil {
-							result = res
-							if key, ok := componentEntry.ResolverInfo().KeyExtractor()(result); ok {
-								this.Cache().Add(key, result)
-							}
-							cancel()
-						}
-						resultMutex.Unlock()
-					}
-					return
-				}
-			case err, ok := <-cerr:
-				if ok {
-					errors = append(errors, err)
-					return
-				}
-			case <-mergeContext.Done():
-				return
+		if queryResolver, ok := resolver.(QueryResolver); ok {
+			spanContext, span := GetInstrumentation().StartSpan(componentContext, OP_QUERY, resolver.ResolverInfo().ResolverType())
+			cres, cerr := queryResolver.Query(spanContext, selector, componentOpts)
+
+			for entity := range cres {
+				add(entity)
+			}
+
+			err, _ := <-cerr
+			span.End(err)
+
+			if err != nil {
+				addError(entry, err)
+			}
+		} else {
+			result := this.resolveComponent(componentContext, entry, selector)
+
+			if result.err != nil {
+				addError(entry, result.err)
+			} else {
+				add(result.result)
 			}
 		}
 	}
 
 	wg.Add(len(resolverEntries))
-	for _, r := range resolverEntries {
-		go resolve(r)
+	for _, entry := range resolverEntries {
+		go query(entry)
 	}
 
 	go func() {
 		wg.Wait()
-		if result != nil {
-			cResOut <- result
-		} else if len(errors) > 0 {
-			cErrOut <- fmt.Errorf("Resolve failed with the following errors %v", errors)
+		cancel()
+
+		if len(errors) > 0 {
+			stream.Close(NewMultiError("Query failed", errors))
+		} else {
+			stream.Close(nil)
 		}
-		close(cResOut)
-		close(cErrOut)
 	}()
 
-	return cResOut, cErrOut
+	return stream.Results()
 }
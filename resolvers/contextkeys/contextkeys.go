@@ -2,4 +2,6 @@ package contextkeys
 
 const (
 	KEY_EXTRACTOR int = iota
+	EXPLANATION
+	PRINCIPAL
 )
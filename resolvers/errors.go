@@ -1,6 +1,31 @@
 package resolvers
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/distributed-vision/go-resources/ids"
+)
+
+// Sentinel errors matched by errors.Is against the typed errors below
+var (
+	ErrNotFound          = errors.New("Entity not found")
+	ErrAmbiguous         = errors.New("Entity is ambiguous")
+	ErrConflict          = errors.New("Entity conflicts")
+	ErrUnavailable       = errors.New("Resolver unavailable")
+	ErrTimeout           = errors.New("Resolver timed out")
+	ErrNoResolverForType = errors.New("No resolver for type")
+	ErrAccessDenied      = errors.New("Access denied")
+)
+
+func reasonError(reason string, cause error) string {
+	if cause != nil {
+		return fmt.Sprint(reason, cause.Error())
+	}
+
+	return reason
+}
 
 type EntityNotFound struct {
 	reason string
@@ -8,13 +33,228 @@ type EntityNotFound struct {
 }
 
 func (e *EntityNotFound) Error() string {
-	if e.cause != nil {
-		return fmt.Sprint(e.reason, e.cause.Error())
-	}
+	return reasonError(e.reason, e.cause)
+}
+
+func (e *EntityNotFound) Unwrap() error {
+	return e.cause
+}
 
-	return e.reason
+func (e *EntityNotFound) Is(target error) bool {
+	return target == ErrNotFound
 }
 
 func NewEntityNotFound(reason string, cause error) *EntityNotFound {
 	return &EntityNotFound{reason, cause}
 }
+
+// Ambiguous reports a selector which selects more than one entity where a
+// single entity is expected
+type Ambiguous struct {
+	reason string
+	cause  error
+}
+
+func (e *Ambiguous) Error() string {
+	return reasonError(e.reason, e.cause)
+}
+
+func (e *Ambiguous) Unwrap() error {
+	return e.cause
+}
+
+func (e *Ambiguous) Is(target error) bool {
+	return target == ErrAmbiguous
+}
+
+func NewAmbiguous(reason string, cause error) *Ambiguous {
+	return &Ambiguous{reason, cause}
+}
+
+// Conflict reports a write which conflicts with the current state of an
+// entity
+type Conflict struct {
+	reason string
+	cause  error
+}
+
+func (e *Conflict) Error() string {
+	return reasonError(e.reason, e.cause)
+}
+
+func (e *Conflict) Unwrap() error {
+	return e.cause
+}
+
+func (e *Conflict) Is(target error) bool {
+	return target == ErrConflict
+}
+
+func NewConflict(reason string, cause error) *Conflict {
+	return &Conflict{reason, cause}
+}
+
+// TemporaryError reports a failure which may succeed if it is retried,
+// typically because a resolver or the service behind it is unavailable
+type TemporaryError struct {
+	reason string
+	cause  error
+}
+
+func (e *TemporaryError) Error() string {
+	return reasonError(e.reason, e.cause)
+}
+
+func (e *TemporaryError) Unwrap() error {
+	return e.cause
+}
+
+func (e *TemporaryError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+func (e *TemporaryError) Temporary() bool {
+	return true
+}
+
+func NewTemporaryError(reason string, cause error) *TemporaryError {
+	return &TemporaryError{reason, cause}
+}
+
+// Timeout reports a resolution which didn't complete in time, it matches
+// ErrTimeout, ErrUnavailable and context.DeadlineExceeded
+type Timeout struct {
+	reason string
+	cause  error
+}
+
+func (e *Timeout) Error() string {
+	return reasonError(e.reason, e.cause)
+}
+
+func (e *Timeout) Unwrap() error {
+	return e.cause
+}
+
+func (e *Timeout) Is(target error) bool {
+	return target == ErrTimeout || target == ErrUnavailable || target == context.DeadlineExceeded
+}
+
+func (e *Timeout) Temporary() bool {
+	return true
+}
+
+func (e *Timeout) Timeout() bool {
+	return true
+}
+
+func NewTimeout(reason string, cause error) *Timeout {
+	return &Timeout{reason, cause}
+}
+
+// NoResolverForType reports a selector for an entity type which no resolver
+// is registered for
+type NoResolverForType struct {
+	EntityType ids.TypeIdentifier
+}
+
+func (e *NoResolverForType) Error() string {
+	return fmt.Sprintf("Resolve Failed: no resolver for entity type=%v", e.EntityType)
+}
+
+func (e *NoResolverForType) Is(target error) bool {
+	return target == ErrNoResolverForType
+}
+
+func NewNoResolverForType(entityType ids.TypeIdentifier) *NoResolverForType {
+	return &NoResolverForType{entityType}
+}
+
+// AccessDenied reports an operation which an Authorizer has refused, Access
+// describes the refused operation if it is known
+type AccessDenied struct {
+	Access *Access
+	reason string
+}
+
+func (e *AccessDenied) Error() string {
+	if e.Access == nil {
+		return e.reason
+	}
+
+	if e.reason == "" {
+		return fmt.Sprintf("Access Denied: %v", e.Access)
+	}
+
+	return fmt.Sprintf("Access Denied: %v: %s", e.Access, e.reason)
+}
+
+func (e *AccessDenied) Is(target error) bool {
+	return target == ErrAccessDenied
+}
+
+func NewAccessDenied(access *Access, reason string) *AccessDenied {
+	return &AccessDenied{access, reason}
+}
+
+// ComponentError is the error returned by a component of a composite
+// resolver
+type ComponentError struct {
+	ResolverType ids.TypeIdentifier
+	Err          error
+}
+
+func (e *ComponentError) Error() string {
+	return fmt.Sprintf("%v: %v", e.ResolverType, e.Err)
+}
+
+func (e *ComponentError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError collects the errors returned by each of the components of a
+// composite resolver. It matches a target with errors.Is only if all of its
+// errors do, the individual errors are available from Errors
+type MultiError struct {
+	reason string
+	errors []error
+}
+
+func (e *MultiError) Error() string {
+	return fmt.Sprintf("%s with the following errors %v", e.reason, e.errors)
+}
+
+func (e *MultiError) Errors() []error {
+	return e.errors
+}
+
+func (e *MultiError) Is(target error) bool {
+	for _, err := range e.errors {
+		if !errors.Is(err, target) {
+			return false
+		}
+	}
+
+	return len(e.errors) > 0
+}
+
+func NewMultiError(reason string, errors []error) *MultiError {
+	return &MultiError{reason, errors}
+}
+
+// IsNotFound returns true for errors which report that an entity doesn't
+// exist
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsRetryable returns true for errors which report themselves as temporary
+func IsRetryable(err error) bool {
+	var temporary interface{ Temporary() bool }
+
+	if errors.As(err, &temporary) {
+		return temporary.Temporary()
+	}
+
+	return false
+}
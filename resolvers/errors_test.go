@@ -0,0 +1,106 @@
+package resolvers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/infokeys"
+	"github.com/distributed-vision/go-resources/resolvers/strategytype"
+	"github.com/distributed-vision/go-resources/translators"
+	"github.com/distributed-vision/go-resources/util/random"
+)
+
+func TestErrorTaxonomy(t *testing.T) {
+	cause := fmt.Errorf("cause")
+
+	matches := []struct {
+		err    error
+		target error
+	}{
+		{resolvers.NewEntityNotFound("missing", cause), resolvers.ErrNotFound},
+		{resolvers.NewEntityNotFound("missing", cause), cause},
+		{resolvers.NewAmbiguous("ambiguous", nil), resolvers.ErrAmbiguous},
+		{resolvers.NewConflict("conflict", nil), resolvers.ErrConflict},
+		{resolvers.NewTemporaryError("unavailable", nil), resolvers.ErrUnavailable},
+		{resolvers.NewTimeout("timeout", nil), resolvers.ErrTimeout},
+		{resolvers.NewTimeout("timeout", nil), resolvers.ErrUnavailable},
+		{resolvers.NewTimeout("timeout", nil), context.DeadlineExceeded},
+		{resolvers.NewNoResolverForType(testEntityType), resolvers.ErrNoResolverForType},
+		{translators.NewNoTranslator(testEntityType, testEntityType), translators.ErrNoTranslator},
+		{fmt.Errorf("wrapped: %w", resolvers.NewConflict("conflict", nil)), resolvers.ErrConflict},
+	}
+
+	for _, match := range matches {
+		if !errors.Is(match.err, match.target) {
+			t.Errorf("TestErrorTaxonomy: Expected %v to match %v", match.err, match.target)
+		}
+	}
+
+	if errors.Is(resolvers.NewEntityNotFound("missing", nil), resolvers.ErrConflict) {
+		t.Error("TestErrorTaxonomy: Expected not found not to match conflict")
+	}
+
+	if !resolvers.IsRetryable(resolvers.NewTimeout("timeout", nil)) || resolvers.IsRetryable(resolvers.NewConflict("conflict", nil)) {
+		t.Error("TestErrorTaxonomy: Unexpected IsRetryable result")
+	}
+
+	mixed := resolvers.NewMultiError("Resolve failed", []error{resolvers.NewEntityNotFound("missing", nil), cause})
+
+	if resolvers.IsNotFound(mixed) {
+		t.Error("TestErrorTaxonomy: Expected mixed multi error not to be not found")
+	}
+}
+
+func TestComponentErrors(t *testing.T) {
+	resolver := newStrategyResolver(t,
+		testInfo.WithValue(infokeys.RESOLVE_STRATEGY, strategytype.ORDERED),
+		newStubResolver(2, 0, "", resolvers.NewEntityNotFound("missing", nil)),
+		newStubResolver(1, 0, "", fmt.Errorf("failed")))
+
+	_, err := resolver.Get(testContext, &typedSelector{key: random.RandomString(20)})
+
+	var multiError *resolvers.MultiError
+
+	if !errors.As(err, &multiError) || len(multiError.Errors()) != 2 {
+		t.Fatal("TestComponentErrors: Expected a multi error with 2 errors, got:", err)
+	}
+
+	if resolvers.IsNotFound(err) {
+		t.Fatal("TestComponentErrors: Expected partially not found resolve not to be not found")
+	}
+
+	for _, componentErr := range multiError.Errors() {
+		var component *resolvers.ComponentError
+
+		if !errors.As(componentErr, &component) || !component.ResolverType.Equals(testResolverType) {
+			t.Fatal("TestComponentErrors: Expected component error with resolver type, got:", componentErr)
+		}
+	}
+
+	resolver = newStrategyResolver(t, testInfo,
+		newStubResolver(2, 0, "", resolvers.NewEntityNotFound("missing", nil)),
+		newStubResolver(1, 0, "", resolvers.NewEntityNotFound("missing", nil)))
+
+	_, err = resolver.Get(testContext, &typedSelector{key: random.RandomString(20)})
+
+	if !resolvers.IsNotFound(err) || !errors.As(err, &multiError) {
+		t.Fatal("TestComponentErrors: Expected not found multi error, got:", err)
+	}
+
+	empty, err := resolvers.NewCompositeResolver(testInfo)
+
+	if err != nil {
+		t.Fatal("TestComponentErrors: NewCompositeResolver failed:", err)
+	}
+
+	if _, err := empty.Get(testContext, &typedSelector{key: "key"}); !errors.Is(err, resolvers.ErrNoResolverForType) {
+		t.Fatal("TestComponentErrors: Expected no resolver for type, got:", err)
+	}
+
+	if _, err := translators.TranslateFuture(testContext, testEntityType, nil, nil, testResolverType).Await(); !errors.Is(err, translators.ErrNoTranslator) {
+		t.Fatal("TestComponentErrors: Expected no translator, got:", err)
+	}
+}
@@ -0,0 +1,261 @@
+package fileresolver
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/resolvers"
+)
+
+// directoryResolver resolves entities from a directory containing one file
+// per entity, named by the entity's key with a .json, .yaml or .yml
+// extension. The directory is indexed by filename on first use, and files
+// are only read when the entity they contain is needed
+type directoryResolver struct {
+	*resolvers.ChangeFeed
+	reloader
+	path         string
+	resolverInfo resolvers.ResolverInfo
+	index        map[string]string
+	sources      map[string]fileStat
+	entities     map[string]interface{}
+	targetType   ids.TypeIdentifier
+	mutex        sync.Mutex
+}
+
+var entityExtensions = []string{".json", ".yaml", ".yml"}
+
+func newDirectoryResolver(dirPath string, resolverInfo resolvers.ResolverInfo) (*directoryResolver, error) {
+	return &directoryResolver{ChangeFeed: &resolvers.ChangeFeed{}, path: dirPath, resolverInfo: resolverInfo}, nil
+}
+
+func (this *directoryResolver) ResolverInfo() resolvers.ResolverInfo {
+	return this.resolverInfo
+}
+
+func (this *directoryResolver) getIndex() (map[string]string, error) {
+	if this.index != nil {
+		return this.index, nil
+	}
+
+	index, sources, err := this.readIndex()
+
+	if err != nil {
+		return nil, err
+	}
+
+	this.index = index
+	this.sources = sources
+	this.entities = make(map[string]interface{})
+
+	return index, nil
+}
+
+func (this *directoryResolver) readIndex() (map[string]string, map[string]fileStat, error) {
+	files, err := ioutil.ReadDir(this.path)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	index := make(map[string]string)
+	sources := make(map[string]fileStat)
+
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		for _, extension := range entityExtensions {
+			if strings.HasSuffix(file.Name(), extension) {
+				id := strings.TrimSuffix(file.Name(), extension)
+				index[id] = file.Name()
+				sources[id] = fileStat{file.ModTime(), file.Size()}
+				break
+			}
+		}
+	}
+
+	return index, sources, nil
+}
+
+// load returns the entity stored under id, translated to targetType
+func (this *directoryResolver) load(context context.Context, id string, targetType ids.TypeIdentifier) (interface{}, bool, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	index, err := this.getIndex()
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	if this.targetType == nil {
+		this.targetType = targetType
+	}
+
+	if entity, ok := this.entities[id]; ok {
+		return entity, true, nil
+	}
+
+	fileName, ok := index[id]
+
+	if !ok {
+		return nil, false, nil
+	}
+
+	jsonEntity, err := readEntity(path.Join(this.path, fileName))
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	key, entity, err := toEntity(context, this.resolverInfo, id, jsonEntity, targetType)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	if key == nil {
+		return nil, false, nil
+	}
+
+	this.entities[id] = entity
+
+	return entity, true, nil
+}
+
+func readEntity(filePath string) (interface{}, error) {
+	data, err := ioutil.ReadFile(filePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var jsonEntity interface{}
+
+	if err = unmarshal(filePath, data, &jsonEntity); err != nil {
+		return nil, fmt.Errorf("Can't parse: %s: %s", filePath, err)
+	}
+
+	return jsonEntity, nil
+}
+
+// entityIds returns the ids of all of the entities in the directory
+func (this *directoryResolver) entityIds() ([]string, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	index, err := this.getIndex()
+
+	if err != nil {
+		return nil, err
+	}
+
+	entityIds := make([]string, 0, len(index))
+
+	for id := range index {
+		entityIds = append(entityIds, id)
+	}
+
+	return entityIds, nil
+}
+
+// forEach calls callback with each entity which passes selector's Test
+// until callback returns false
+func (this *directoryResolver) forEach(context context.Context, selector resolvers.Selector, callback func(id string, entity interface{}) bool) error {
+	entityIds, err := this.entityIds()
+
+	if err != nil {
+		return err
+	}
+
+	for _, id := range entityIds {
+		entity, ok, err := this.load(context, id, selector.Type())
+
+		if err != nil {
+			return err
+		}
+
+		if ok && selector.Test(entity) && !callback(id, entity) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (this *directoryResolver) Get(resolutionContext context.Context, selector resolvers.Selector) (interface{}, error) {
+	if this.resolverInfo != nil {
+		resolutionContext = context.WithValue(resolutionContext, "resolverInfo", this.resolverInfo)
+	}
+
+	if key := resolvers.KeyString(selector.Key()); key != "" {
+		entity, ok, err := this.load(resolutionContext, key, selector.Type())
+
+		if err != nil {
+			return nil, err
+		}
+
+		if ok && selector.Test(entity) {
+			return entity, nil
+		}
+	}
+
+	var found interface{}
+
+	err := this.forEach(resolutionContext, selector, func(id string, entity interface{}) bool {
+		found = entity
+		return false
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if found == nil {
+		return nil, resolvers.NewEntityNotFound(fmt.Sprintf("Invalid entity selector: %+v", selector), nil)
+	}
+
+	return found, nil
+}
+
+func (this *directoryResolver) Resolve(resolutionContext context.Context, selector resolvers.Selector) (chan interface{}, chan error) {
+	cres, cerr := make(chan interface{}, 1), make(chan error, 1)
+
+	go func() {
+		entity, err := this.Get(resolutionContext, selector)
+
+		if err != nil {
+			cerr <- err
+		} else {
+			cres <- entity
+		}
+
+		close(cres)
+		close(cerr)
+	}()
+
+	return cres, cerr
+}
+
+func (this *directoryResolver) Query(queryContext context.Context, selector resolvers.Selector, opts resolvers.QueryOpts) (<-chan interface{}, <-chan error) {
+	stream := resolvers.NewQueryStream(queryContext, opts, this.resolverInfo.KeyExtractor())
+
+	if this.resolverInfo != nil {
+		queryContext = context.WithValue(queryContext, "resolverInfo", this.resolverInfo)
+	}
+
+	go func() {
+		stream.Close(this.forEach(queryContext, selector, func(id string, entity interface{}) bool {
+			return stream.Add(entity)
+		}))
+	}()
+
+	return stream.Results()
+}
@@ -0,0 +1,99 @@
+package fileresolver_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/fileresolver"
+	"github.com/distributed-vision/go-resources/types/gotypeid"
+	"github.com/distributed-vision/go-resources/util"
+)
+
+var contentType = gotypeid.IdOf(reflect.TypeOf(map[string]interface{}{}))
+var testContext = context.Background()
+
+func idExtractor(e ...interface{}) (interface{}, bool) {
+	if entity, ok := e[0].(map[string]interface{}); ok {
+		id, ok := entity["id"].(string)
+		return id, ok
+	}
+
+	return nil, false
+}
+
+type selector struct {
+	key  string
+	name string
+}
+
+func (this *selector) Type() ids.TypeIdentifier {
+	return contentType
+}
+
+func (this *selector) Key() interface{} {
+	return this.key
+}
+
+func (this *selector) Test(candidate interface{}) bool {
+	entity, ok := candidate.(map[string]interface{})
+	return ok && (this.key == "" || entity["id"] == this.key) && (this.name == "" || entity["name"] == this.name)
+}
+
+func TestDirectoryResolver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileresolver")
+
+	if err != nil {
+		t.Fatal("TestDirectoryResolver: TempDir failed:", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	entities := filepath.Join(dir, "entities")
+	os.Mkdir(entities, 0755)
+
+	ioutil.WriteFile(filepath.Join(entities, "1.json"), []byte(`{"name": "json"}`), 0644)
+	ioutil.WriteFile(filepath.Join(entities, "2.yaml"), []byte("name: yaml\nvisibility: PUBLIC\n"), 0644)
+	ioutil.WriteFile(filepath.Join(entities, "3.yml"), []byte("name: [broken"), 0644)
+	ioutil.WriteFile(filepath.Join(entities, "README.md"), []byte("not an entity"), 0644)
+
+	resolver, err := fileresolver.New("entities", fileresolver.NewResolverInfo(
+		[]ids.TypeIdentifier{contentType}, nil, idExtractor,
+		map[interface{}]interface{}{"paths": []string{filepath.Join(dir, "missing"), dir}}))
+
+	if err != nil {
+		t.Fatal("TestDirectoryResolver: New failed:", err)
+	}
+
+	defer resolver.(fileresolver.Reloader).Close()
+
+	if entity, err := resolver.Get(testContext, &selector{key: "1"}); err != nil || entity.(map[string]interface{})["name"] != "json" {
+		t.Fatal("TestDirectoryResolver: Get json failed:", entity, err)
+	}
+
+	if entity, err := resolver.Get(testContext, &selector{key: "2"}); err != nil || entity.(map[string]interface{})["visibility"] != "PUBLIC" {
+		t.Fatal("TestDirectoryResolver: Get yaml failed:", entity, err)
+	}
+
+	if _, err := resolver.Get(testContext, &selector{key: "4"}); err == nil {
+		t.Fatal("TestDirectoryResolver: expected missing entity")
+	}
+
+	if _, err := resolver.Get(testContext, &selector{key: "3"}); err == nil {
+		t.Fatal("TestDirectoryResolver: expected a parse error")
+	}
+
+	// files are read on demand, so a fixed file is picked up by later calls
+	ioutil.WriteFile(filepath.Join(entities, "3.yml"), []byte("name: fixed\n"), 0644)
+
+	found, err := util.AwaitAll(resolver.(resolvers.QueryResolver).Query(testContext, &selector{name: "yaml"}, resolvers.QueryOpts{}))
+
+	if err != nil || len(found) != 1 || found[0].(map[string]interface{})["id"] != "2" {
+		t.Fatal("TestDirectoryResolver: unexpected query result:", found, err)
+	}
+}
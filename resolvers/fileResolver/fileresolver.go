@@ -2,7 +2,6 @@ package fileresolver
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -27,12 +26,32 @@ import (
 var contentType ids.TypeIdentifier = gotypeid.IdOf(reflect.TypeOf(map[string]interface{}{}))
 
 type fileResolver struct {
+	*resolvers.ChangeFeed
+	reloader
 	path         string
 	resolverInfo resolvers.ResolverInfo
-	entityMap    map[interface{}]interface{}
+	entityMaps   map[string]*loadedMap
+	targetType   ids.TypeIdentifier
+	source       fileStat
+	mapMutex     sync.Mutex
+	writeMutex   sync.Mutex
 }
 
-var resolverMap map[string]*fileResolver = make(map[string]*fileResolver)
+// loadedMap holds a file's entities translated to targetType
+type loadedMap struct {
+	targetType ids.TypeIdentifier
+	entities   map[interface{}]interface{}
+}
+
+func typeKey(targetType ids.TypeIdentifier) string {
+	if targetType == nil {
+		return ""
+	}
+
+	return targetType.String()
+}
+
+var resolverMap map[string]resolvers.Resolver = make(map[string]resolvers.Resolver)
 var resolverMapMutex = &sync.Mutex{}
 var resolverType ids.TypeIdentifier = gotypeid.IdOf(reflect.TypeOf(fileResolver{}))
 var publicTypeVersion = version.New(0, 0, 1)
@@ -89,12 +108,21 @@ func (this *factory) ResolverInfo() resolvers.ResolverInfo {
 	return this.resolverInfo
 }
 
+// New returns the resolver for the file or directory at locator. Resolvers
+// are shared by locator, so opening a locator again with different
+// "writable" or "reloadInterval" values fails
 func New(locator string, resolverInfo resolvers.ResolverInfo) (resolvers.Resolver, error) {
 	resolverMapMutex.Lock()
 	defer resolverMapMutex.Unlock()
 	resolver, ok := resolverMap[locator]
 
 	if ok {
+		sharedInfo := resolver.ResolverInfo()
+
+		if isWritable(sharedInfo) != isWritable(resolverInfo) || reloadInterval(sharedInfo) != reloadInterval(resolverInfo) {
+			return nil, fmt.Errorf("%s is already open with different writable or reloadInterval values", locator)
+		}
+
 		return resolver, nil
 	}
 
@@ -109,7 +137,7 @@ func New(locator string, resolverInfo resolvers.ResolverInfo) (resolvers.Resolve
 	return resolver, nil
 }
 
-func newResolver(file string, resolverInfo resolvers.ResolverInfo) (*fileResolver, error) {
+func newResolver(file string, resolverInfo resolvers.ResolverInfo) (resolvers.Resolver, error) {
 
 	var filePath string
 
@@ -143,7 +171,30 @@ func newResolver(file string, resolverInfo resolvers.ResolverInfo) (*fileResolve
 		return nil, fmt.Errorf("Can't find: %s in: %v", file, pathsValue)
 	}
 
-	return &fileResolver{path: filePath, resolverInfo: resolverInfo}, nil
+	if info, err := os.Stat(filePath); err == nil && info.IsDir() {
+		resolver, err := newDirectoryResolver(filePath, resolverInfo)
+
+		if err != nil {
+			return nil, err
+		}
+
+		resolver.startPolling(resolverInfo, resolver.Reload)
+
+		if !isWritable(resolverInfo) {
+			return resolver, nil
+		}
+
+		return &writableDirectoryResolver{resolver}, nil
+	}
+
+	resolver := &fileResolver{ChangeFeed: &resolvers.ChangeFeed{}, path: filePath, resolverInfo: resolverInfo}
+	resolver.startPolling(resolverInfo, resolver.Reload)
+
+	if isWritable(resolverInfo) {
+		return &writableFileResolver{resolver}, nil
+	}
+
+	return resolver, nil
 }
 
 func (this *fileResolver) ResolverInfo() resolvers.ResolverInfo {
@@ -164,44 +215,16 @@ func (this *fileResolver) Resolve(resolutionContext context.Context, selector re
 	go func() {
 		entityMap, err := this.getMap(resolutionContext, selector.Type())
 
-		if err != nil {
-			cerr <- err
-		} else if entityMap != nil {
-			//fmt.Printf("sel=%+v\n", selector)
-			//fmt.Printf("entities: %v\n", entityMap)
-			var key string
-
-			switch selector.Key().(type) {
-			case string:
-				key = selector.Key().(string)
-			case []byte:
-				key = string(selector.Key().([]byte))
-			default:
-				key = fmt.Sprintf("%v", selector.Key())
-			}
-
-			entity, ok := entityMap[key]
-
-			//fmt.Printf("key=%v, entity: %v\n", key, entity)
-			if ok {
-				if selector.Test(entity) {
-					cres <- entity
-					close(cres)
-					close(cerr)
-					return
-				}
-			}
+		if err == nil {
+			var entity interface{}
 
-			for _, entity := range entityMap {
-				if selector.Test(entity) {
-					cres <- entity
-					close(cres)
-					close(cerr)
-					return
-				}
+			if entity, err = find(entityMap, selector); err == nil {
+				cres <- entity
 			}
+		}
 
-			cerr <- fmt.Errorf("Invalid entity selector: %+v", selector)
+		if err != nil {
+			cerr <- err
 		}
 
 		close(cres)
@@ -211,18 +234,86 @@ func (this *fileResolver) Resolve(resolutionContext context.Context, selector re
 	return cres, cerr
 }
 
+// GetMany resolves each of selectors from a single read of the resolver's
+// file
+func (this *fileResolver) GetMany(resolutionContext context.Context, selectors []resolvers.Selector) []resolvers.Result {
+	if this.resolverInfo != nil {
+		resolutionContext = context.WithValue(resolutionContext, "resolverInfo", this.resolverInfo)
+	}
+
+	results := make([]resolvers.Result, len(selectors))
+
+	for index, selector := range selectors {
+		results[index].Selector = selector
+
+		entityMap, err := this.getMap(resolutionContext, selector.Type())
+
+		if err == nil {
+			results[index].Entity, err = find(entityMap, selector)
+		}
+
+		results[index].Err = err
+	}
+
+	return results
+}
+
+// find returns the entity in entityMap selected by selector, looking it up by
+// the selector's key before testing each of the entities
+func find(entityMap map[interface{}]interface{}, selector resolvers.Selector) (interface{}, error) {
+	var key string
+
+	switch selector.Key().(type) {
+	case string:
+		key = selector.Key().(string)
+	case []byte:
+		key = string(selector.Key().([]byte))
+	default:
+		key = fmt.Sprintf("%v", selector.Key())
+	}
+
+	if entity, ok := entityMap[key]; ok && selector.Test(entity) {
+		return entity, nil
+	}
+
+	for _, entity := range entityMap {
+		if selector.Test(entity) {
+			return entity, nil
+		}
+	}
+
+	return nil, resolvers.NewEntityNotFound(fmt.Sprintf("Invalid entity selector: %+v", selector), nil)
+}
+
+// getMap returns the file's entities translated to targetType, each type is
+// loaded once and held until the file is written or reloaded. Reloads report
+// the changes to the first type loaded
 func (this *fileResolver) getMap(context context.Context, targetType ids.TypeIdentifier) (map[interface{}]interface{}, error) {
-	if this.entityMap != nil {
-		return this.entityMap, nil
+	this.mapMutex.Lock()
+	defer this.mapMutex.Unlock()
+
+	if loaded, ok := this.entityMaps[typeKey(targetType)]; ok {
+		return loaded.entities, nil
 	}
 
+	// the file is checked before it is read, so changes made while it is
+	// loading are picked up by the next reload
+	source, _ := statFile(this.path)
 	entityMap, err := this.loadMap(context, targetType)
 
-	if err == nil {
-		this.entityMap = entityMap
+	if err != nil {
+		return nil, err
+	}
+
+	if this.entityMaps == nil {
+		this.entityMaps = make(map[string]*loadedMap)
+		this.targetType = targetType
+		this.source = source
 	}
 
-	return entityMap, err
+	this.entityMaps[typeKey(targetType)] = &loadedMap{targetType, entityMap}
+
+	return entityMap, nil
 }
 
 var untypedLocalDomain []byte = domain.MustDecodeId(encodertype.BASE62, "3", "")
@@ -234,48 +325,93 @@ func (this *fileResolver) loadMap(context context.Context, targetType ids.TypeId
 		return nil, err
 	}
 
-	var jsonEntities interface{}
-	err = json.Unmarshal(data, &jsonEntities)
+	var jsonEntityMap map[string]interface{}
 
-	if err != nil {
+	if err = unmarshal(this.path, data, &jsonEntityMap); err != nil {
 		return nil, err
 	}
 
-	jsonEntityMap := jsonEntities.(map[string]interface{})
-
 	//fmt.Printf("Content Type=%+v, Target Type %+v\n", contentType, targetType)
 
-	keyExtractor := this.resolverInfo.KeyExtractor()
 	entityMap := make(map[interface{}]interface{})
 
 	for id, jsonEntity := range jsonEntityMap {
-		if contentType != targetType {
-			//fmt.Printf("id=%s\n", id)
-			entityId, err := identifier.New(untypedLocalDomain, []byte(id), nil)
-			//fmt.Printf("id=%v\n", entityId.Value())
+		key, entity, err := toEntity(context, this.resolverInfo, id, jsonEntity, targetType)
 
-			if err != nil {
-				return nil, err
-			}
+		if err != nil {
+			return nil, err
+		}
 
-			if entity, err := util.Await(
-				translators.Translate(context, contentType,
-					entityId, jsonEntity, targetType)); err == nil {
-				if key, ok := keyExtractor(entity); ok {
-					//fmt.Printf("key(ext)=%v\n", key)
-					entityMap[key] = entity
-				} else {
-					return nil, fmt.Errorf("Can't extract key from: %v", entity)
-				}
-			} else {
-				return nil, err
-			}
-		} else {
-			if key, ok := keyExtractor(jsonEntity); ok {
-				entityMap[key] = jsonEntity
-			}
+		if key != nil {
+			entityMap[key] = entity
 		}
 	}
 
 	return entityMap, nil
 }
+
+// toEntity translates a json entity stored under id to targetType and
+// extracts its key, untranslated entities without a key are skipped
+func toEntity(context context.Context, resolverInfo resolvers.ResolverInfo, id string, jsonEntity interface{}, targetType ids.TypeIdentifier) (interface{}, interface{}, error) {
+	keyExtractor := resolverInfo.KeyExtractor()
+
+	if contentType != targetType {
+		entityId, err := identifier.New(untypedLocalDomain, []byte(id), nil)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		entity, err := translators.TranslateFuture(context, contentType,
+			entityId, jsonEntity, targetType).Await()
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if key, ok := keyExtractor(entity); ok {
+			return key, entity, nil
+		}
+
+		return nil, nil, fmt.Errorf("Can't extract key from: %v", entity)
+	}
+
+	// untranslated entities carry their id in the same way as translated ones
+	if jsonMap, ok := jsonEntity.(map[string]interface{}); ok {
+		if _, ok := jsonMap["id"]; !ok {
+			jsonMap["id"] = id
+		}
+	}
+
+	if key, ok := keyExtractor(jsonEntity); ok {
+		return key, jsonEntity, nil
+	}
+
+	return nil, nil, nil
+}
+
+func (this *fileResolver) Query(queryContext context.Context, selector resolvers.Selector, opts resolvers.QueryOpts) (<-chan interface{}, <-chan error) {
+	stream := resolvers.NewQueryStream(queryContext, opts, this.resolverInfo.KeyExtractor())
+
+	if this.resolverInfo != nil {
+		queryContext = context.WithValue(queryContext, "resolverInfo", this.resolverInfo)
+	}
+
+	go func() {
+		entityMap, err := this.getMap(queryContext, selector.Type())
+
+		if err == nil {
+			for key, entity := range entityMap {
+				if selector.Test(entity) {
+					if !stream.AddKeyed(resolvers.KeyString(key), entity) {
+						break
+					}
+				}
+			}
+		}
+
+		stream.Close(err)
+	}()
+
+	return stream.Results()
+}
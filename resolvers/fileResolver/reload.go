@@ -0,0 +1,323 @@
+package fileresolver
+
+import (
+	"context"
+	"os"
+	"path"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/changetype"
+)
+
+// Reloader is implemented by file resolvers. Reload re-reads the resolver's
+// source if it has changed since it was last read, and reports the entities
+// which changed to the resolver's change listeners. If the source can't be
+// read the last good state is kept and the error is returned, it remains
+// available from ReloadError until a later reload succeeds.
+//
+// Setting the resolver info's "reloadInterval" value polls for changes at
+// that interval until the resolver is closed
+type Reloader interface {
+	Reload(reloadContext context.Context) error
+	ReloadError() error
+	Close() error
+}
+
+type fileStat struct {
+	modTime time.Time
+	size    int64
+}
+
+func (this fileStat) same(other fileStat) bool {
+	return this.modTime.Equal(other.modTime) && this.size == other.size
+}
+
+func statFile(filePath string) (fileStat, error) {
+	info, err := os.Stat(filePath)
+
+	if err != nil {
+		return fileStat{}, err
+	}
+
+	return fileStat{info.ModTime(), info.Size()}, nil
+}
+
+// reloadInterval reads the resolver info's "reloadInterval" value, numeric
+// values are taken to be milliseconds
+func reloadInterval(resolverInfo resolvers.ResolverInfo) time.Duration {
+	switch value := resolverInfo.Value("reloadInterval").(type) {
+	case time.Duration:
+		return value
+	case int:
+		return time.Duration(value) * time.Millisecond
+	case float64:
+		return time.Duration(value * float64(time.Millisecond))
+	case string:
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+		if millis, err := strconv.Atoi(value); err == nil {
+			return time.Duration(millis) * time.Millisecond
+		}
+	}
+
+	return 0
+}
+
+type reloader struct {
+	errorMutex sync.Mutex
+	err        error
+	stop       chan struct{}
+	stopOnce   sync.Once
+}
+
+func (this *reloader) ReloadError() error {
+	this.errorMutex.Lock()
+	defer this.errorMutex.Unlock()
+	return this.err
+}
+
+func (this *reloader) reloaded(err error) error {
+	this.errorMutex.Lock()
+	this.err = err
+	this.errorMutex.Unlock()
+	return err
+}
+
+func (this *reloader) startPolling(resolverInfo resolvers.ResolverInfo, reload func(context.Context) error) {
+	interval := reloadInterval(resolverInfo)
+
+	if interval <= 0 {
+		return
+	}
+
+	this.stop = make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				reload(context.Background())
+			case <-this.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (this *reloader) stopPolling() {
+	this.stopOnce.Do(func() {
+		if this.stop != nil {
+			close(this.stop)
+		}
+	})
+}
+
+// forget removes resolver from the shared resolvers so that a later New
+// for its location creates a new resolver
+func forget(resolver resolvers.Resolver) {
+	resolverMapMutex.Lock()
+	defer resolverMapMutex.Unlock()
+
+	for locator, shared := range resolverMap {
+		if shared == resolver {
+			delete(resolverMap, locator)
+		}
+	}
+}
+
+// diff returns the changes between two entity maps
+func diff(oldEntities map[interface{}]interface{}, newEntities map[interface{}]interface{}) []resolvers.ChangeEvent {
+	var changes []resolvers.ChangeEvent
+
+	for key, entity := range newEntities {
+		if oldEntity, ok := oldEntities[key]; !ok {
+			changes = append(changes, resolvers.ChangeEvent{Type: changetype.PUT, Key: key, Entity: entity})
+		} else if !reflect.DeepEqual(oldEntity, entity) {
+			changes = append(changes, resolvers.ChangeEvent{Type: changetype.UPDATE, Key: key, Entity: entity})
+		}
+	}
+
+	for key, entity := range oldEntities {
+		if _, ok := newEntities[key]; !ok {
+			changes = append(changes, resolvers.ChangeEvent{Type: changetype.DELETE, Key: key, Entity: entity})
+		}
+	}
+
+	return changes
+}
+
+func (this *fileResolver) Reload(reloadContext context.Context) error {
+	source, err := statFile(this.path)
+
+	if err != nil {
+		return this.reloaded(err)
+	}
+
+	if this.resolverInfo != nil {
+		reloadContext = context.WithValue(reloadContext, "resolverInfo", this.resolverInfo)
+	}
+
+	this.mapMutex.Lock()
+
+	// entities which have not been loaded are read on their next resolve
+	if this.entityMaps == nil || source.same(this.source) {
+		this.mapMutex.Unlock()
+		return this.reloaded(nil)
+	}
+
+	entityMaps := make(map[string]*loadedMap, len(this.entityMaps))
+
+	for key, loaded := range this.entityMaps {
+		entityMap, err := this.loadMap(reloadContext, loaded.targetType)
+
+		if err != nil {
+			this.mapMutex.Unlock()
+			return this.reloaded(err)
+		}
+
+		entityMaps[key] = &loadedMap{loaded.targetType, entityMap}
+	}
+
+	published := typeKey(this.targetType)
+	changes := diff(this.entityMaps[published].entities, entityMaps[published].entities)
+	this.entityMaps = entityMaps
+	this.source = source
+	this.mapMutex.Unlock()
+
+	for _, change := range changes {
+		this.Publish(change.Type, change.Key, change.Entity)
+	}
+
+	return this.reloaded(nil)
+}
+
+func (this *fileResolver) Close() error {
+	this.stopPolling()
+	forget(this)
+	return nil
+}
+
+func (this *writableFileResolver) Close() error {
+	this.stopPolling()
+	forget(this)
+	return nil
+}
+
+// Reload re-indexes the directory. New files and changed files whose
+// entities have been loaded are read, files which fail to parse keep their
+// last good entity
+func (this *directoryResolver) Reload(reloadContext context.Context) error {
+	if this.resolverInfo != nil {
+		reloadContext = context.WithValue(reloadContext, "resolverInfo", this.resolverInfo)
+	}
+
+	this.mutex.Lock()
+
+	if this.index == nil {
+		this.mutex.Unlock()
+		return this.reloaded(nil)
+	}
+
+	index, sources, err := this.readIndex()
+
+	if err != nil {
+		this.mutex.Unlock()
+		return this.reloaded(err)
+	}
+
+	var changes []resolvers.ChangeEvent
+	var reloadErr error
+
+	keyExtractor := this.resolverInfo.KeyExtractor()
+
+	for id, fileName := range index {
+		oldFileName, existed := this.index[id]
+
+		if existed && oldFileName == fileName && sources[id].same(this.sources[id]) {
+			continue
+		}
+
+		cached, loaded := this.entities[id]
+
+		if existed && !loaded || this.targetType == nil {
+			continue
+		}
+
+		jsonEntity, err := readEntity(path.Join(this.path, fileName))
+
+		var key, entity interface{}
+
+		if err == nil {
+			key, entity, err = toEntity(reloadContext, this.resolverInfo, id, jsonEntity, this.targetType)
+		}
+
+		if err != nil {
+			reloadErr = err
+
+			if existed {
+				index[id] = oldFileName
+				sources[id] = this.sources[id]
+			} else {
+				delete(index, id)
+			}
+
+			continue
+		}
+
+		if key == nil {
+			delete(this.entities, id)
+			continue
+		}
+
+		this.entities[id] = entity
+
+		if !loaded {
+			changes = append(changes, resolvers.ChangeEvent{Type: changetype.PUT, Key: key, Entity: entity})
+		} else if !reflect.DeepEqual(cached, entity) {
+			changes = append(changes, resolvers.ChangeEvent{Type: changetype.UPDATE, Key: key, Entity: entity})
+		}
+	}
+
+	for id := range this.index {
+		if _, ok := index[id]; !ok {
+			if cached, loaded := this.entities[id]; loaded {
+				if key, ok := keyExtractor(cached); ok {
+					changes = append(changes, resolvers.ChangeEvent{Type: changetype.DELETE, Key: key, Entity: cached})
+				}
+				delete(this.entities, id)
+			} else {
+				changes = append(changes, resolvers.ChangeEvent{Type: changetype.DELETE, Key: id})
+			}
+		}
+	}
+
+	this.index = index
+	this.sources = sources
+	this.mutex.Unlock()
+
+	for _, change := range changes {
+		this.Publish(change.Type, change.Key, change.Entity)
+	}
+
+	return this.reloaded(reloadErr)
+}
+
+func (this *directoryResolver) Close() error {
+	this.stopPolling()
+	forget(this)
+	return nil
+}
+
+func (this *writableDirectoryResolver) Close() error {
+	this.stopPolling()
+	forget(this)
+	return nil
+}
@@ -0,0 +1,169 @@
+package fileresolver_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/changetype"
+	"github.com/distributed-vision/go-resources/resolvers/fileresolver"
+)
+
+type changes struct {
+	mutex   sync.Mutex
+	changed []string
+}
+
+func (this *changes) listener(event resolvers.ChangeEvent) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if event.Type == changetype.DELETE {
+		this.changed = append(this.changed, "-"+event.Key.(string))
+	} else {
+		this.changed = append(this.changed, "+"+event.Key.(string))
+	}
+}
+
+func (this *changes) take() []string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	changed := this.changed
+	this.changed = nil
+	sort.Strings(changed)
+
+	return changed
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileresolver")
+
+	if err != nil {
+		t.Fatal("TestReload: TempDir failed:", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "reload.json")
+	ioutil.WriteFile(file, []byte(`{"1": {"name": "one"}, "2": {"name": "two"}}`), 0644)
+
+	component, err := fileresolver.New(file, fileresolver.NewResolverInfo(
+		[]ids.TypeIdentifier{contentType}, nil, idExtractor, nil))
+
+	if err != nil {
+		t.Fatal("TestReload: New failed:", err)
+	}
+
+	resolver, err := resolvers.NewCompositeResolver(resolvers.RootInfo.WithExtractor(idExtractor))
+
+	if err != nil {
+		t.Fatal("TestReload: NewCompositeResolver failed:", err)
+	}
+
+	resolver.RegisterComponent(component)
+
+	events := &changes{}
+	component.(resolvers.ChangeNotifier).OnChange(events.listener)
+	reloader := component.(fileresolver.Reloader)
+	defer reloader.Close()
+
+	if entity, err := resolver.Get(testContext, &selector{key: "1"}); err != nil || entity.(map[string]interface{})["name"] != "one" {
+		t.Fatal("TestReload: Get failed:", entity, err)
+	}
+
+	ioutil.WriteFile(file, []byte(`{"1": {"name": "uno"}, "3": {"name": "three"}}`), 0644)
+
+	if err := reloader.Reload(testContext); err != nil {
+		t.Fatal("TestReload: Reload failed:", err)
+	}
+
+	if changed := events.take(); len(changed) != 3 || changed[0] != "+1" || changed[1] != "+3" || changed[2] != "-2" {
+		t.Fatal("TestReload: unexpected changes:", changed)
+	}
+
+	if entity, err := resolver.Get(testContext, &selector{key: "1"}); err != nil || entity.(map[string]interface{})["name"] != "uno" {
+		t.Fatal("TestReload: expected the composite cache to be invalidated got:", entity, err)
+	}
+
+	ioutil.WriteFile(file, []byte(`{"1": {"name": `), 0644)
+
+	if err := reloader.Reload(testContext); err == nil || reloader.ReloadError() == nil {
+		t.Fatal("TestReload: expected a parse error")
+	}
+
+	if entity, err := component.Get(testContext, &selector{key: "3"}); err != nil || entity.(map[string]interface{})["name"] != "three" {
+		t.Fatal("TestReload: expected the last good state to be kept got:", entity, err)
+	}
+
+	ioutil.WriteFile(file, []byte(`{"1": {"name": "uno"}}`), 0644)
+
+	if err := reloader.Reload(testContext); err != nil || reloader.ReloadError() != nil {
+		t.Fatal("TestReload: expected the reload error to be cleared got:", err)
+	}
+
+	if changed := events.take(); len(changed) != 1 || changed[0] != "-3" {
+		t.Fatal("TestReload: unexpected changes after recovery:", changed)
+	}
+}
+
+func TestReloadDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileresolver")
+
+	if err != nil {
+		t.Fatal("TestReloadDirectory: TempDir failed:", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "1.json"), []byte(`{"name": "one"}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "2.json"), []byte(`{"name": "two"}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "3.json"), []byte(`{"name": "three"}`), 0644)
+
+	component, err := fileresolver.New(dir, fileresolver.NewResolverInfo(
+		[]ids.TypeIdentifier{contentType}, nil, idExtractor,
+		map[interface{}]interface{}{"reloadInterval": "10ms"}))
+
+	if err != nil {
+		t.Fatal("TestReloadDirectory: New failed:", err)
+	}
+
+	defer component.(fileresolver.Reloader).Close()
+
+	events := &changes{}
+	component.(resolvers.ChangeNotifier).OnChange(events.listener)
+
+	for _, key := range []string{"1", "2"} {
+		if _, err := component.Get(testContext, &selector{key: key}); err != nil {
+			t.Fatal("TestReloadDirectory: Get failed:", err)
+		}
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "1.json"), []byte(`{"name": "uno"}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "3.json"), []byte(`{"name": "tres"}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "4.yaml"), []byte("name: four\n"), 0644)
+	os.Remove(filepath.Join(dir, "2.json"))
+
+	var changed []string
+
+	for deadline := time.Now().Add(5 * time.Second); len(changed) < 3 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		changed = append(changed, events.take()...)
+	}
+
+	sort.Strings(changed)
+
+	if len(changed) != 3 || changed[0] != "+1" || changed[1] != "+4" || changed[2] != "-2" {
+		t.Fatal("TestReloadDirectory: unexpected changes:", changed)
+	}
+
+	if entity, err := component.Get(testContext, &selector{key: "1"}); err != nil || entity.(map[string]interface{})["name"] != "uno" {
+		t.Fatal("TestReloadDirectory: expected the reloaded entity got:", entity, err)
+	}
+}
@@ -0,0 +1,388 @@
+package fileresolver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/changetype"
+	"github.com/distributed-vision/go-resources/translators"
+	"gopkg.in/yaml.v3"
+)
+
+// isWritable returns true if the resolver info's "writable" value is set,
+// file resolvers are read only by default
+func isWritable(resolverInfo resolvers.ResolverInfo) bool {
+	switch writable := resolverInfo.Value("writable").(type) {
+	case bool:
+		return writable
+	case string:
+		value, _ := strconv.ParseBool(writable)
+		return value
+	}
+
+	return false
+}
+
+// toJSON converts an entity to the map form it is stored in, the entity's
+// id is dropped as it is carried by the file key
+func toJSON(encodeContext context.Context, resolverInfo resolvers.ResolverInfo, id string, entity interface{}) (map[string]interface{}, error) {
+	jsonMap, err := translators.ToMap(encodeContext, entity, resolverInfo.ResolvableTypes()...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[string]interface{}, len(jsonMap))
+
+	for field, value := range jsonMap {
+		if field != "id" || value != id {
+			stored[field] = value
+		}
+	}
+
+	return stored, nil
+}
+
+func entityId(resolverInfo resolvers.ResolverInfo, entity interface{}) (interface{}, string, error) {
+	key, ok := resolverInfo.KeyExtractor()(entity)
+
+	if !ok {
+		return nil, "", fmt.Errorf("Cannot extract key from: %v", entity)
+	}
+
+	return key, resolvers.KeyString(key), nil
+}
+
+// writeAtomic replaces the content of filePath by writing to a temporary
+// file in the same directory and renaming it, so readers never see a
+// partially written file
+func writeAtomic(filePath string, data []byte) error {
+	file, err := ioutil.TempFile(path.Dir(filePath), "."+path.Base(filePath)+".")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(file.Name())
+
+	mode := os.FileMode(0644)
+
+	if info, err := os.Stat(filePath); err == nil {
+		mode = info.Mode()
+	}
+
+	if _, err = file.Write(data); err == nil {
+		err = file.Sync()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Chmod(file.Name(), mode)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), filePath)
+}
+
+// marshal encodes a file's content in the format implied by its extension,
+// map keys are written in sorted order to keep diffs stable
+func marshal(filePath string, content interface{}) ([]byte, error) {
+	if extension := path.Ext(filePath); extension == ".yaml" || extension == ".yml" {
+		return yaml.Marshal(content)
+	}
+
+	data, err := json.MarshalIndent(content, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+// unmarshal decodes a file's content in the format implied by its extension
+func unmarshal(filePath string, data []byte, content interface{}) error {
+	if extension := path.Ext(filePath); extension == ".yaml" || extension == ".yml" {
+		return yaml.Unmarshal(data, content)
+	}
+
+	return json.Unmarshal(data, content)
+}
+
+// writableFileResolver is a file resolver which implements MutableResolver,
+// each write rewrites the whole file
+type writableFileResolver struct {
+	*fileResolver
+}
+
+func (this *writableFileResolver) readRaw() (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(this.path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]interface{})
+
+	if err := unmarshal(this.path, data, &raw); err != nil {
+		return nil, err
+	}
+
+	return raw, nil
+}
+
+// errUnchanged is returned by an update's change to leave the file as it is
+var errUnchanged = errors.New("Unchanged")
+
+// update applies change to the file's content and rewrites it, the loaded
+// entities are discarded so that they are re-read on the next resolve
+func (this *writableFileResolver) update(change func(raw map[string]interface{}) error) error {
+	this.writeMutex.Lock()
+	defer this.writeMutex.Unlock()
+
+	raw, err := this.readRaw()
+
+	if err != nil {
+		return err
+	}
+
+	if err := change(raw); err != nil {
+		return err
+	}
+
+	data, err := marshal(this.path, raw)
+
+	if err != nil {
+		return err
+	}
+
+	if err := writeAtomic(this.path, data); err != nil {
+		return err
+	}
+
+	this.mapMutex.Lock()
+	this.entityMaps = nil
+	this.mapMutex.Unlock()
+
+	return nil
+}
+
+func (this *writableFileResolver) write(resolutionContext context.Context, entity interface{}, mustExist bool) (interface{}, error) {
+	key, id, err := entityId(this.resolverInfo, entity)
+
+	if err != nil {
+		return nil, err
+	}
+
+	jsonEntity, err := toJSON(resolutionContext, this.resolverInfo, id, entity)
+
+	if err != nil {
+		return nil, err
+	}
+
+	changeType := changetype.PUT
+
+	err = this.update(func(raw map[string]interface{}) error {
+		if _, ok := raw[id]; ok {
+			changeType = changetype.UPDATE
+		} else if mustExist {
+			return resolvers.NewEntityNotFound(fmt.Sprintf("Can't resolve entity for %v", key), nil)
+		}
+
+		raw[id] = jsonEntity
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	this.Publish(changeType, key, entity)
+
+	return entity, nil
+}
+
+func (this *writableFileResolver) Put(resolutionContext context.Context, entity interface{}) (interface{}, error) {
+	return this.write(resolutionContext, entity, false)
+}
+
+func (this *writableFileResolver) Post(resolutionContext context.Context, entity interface{}) (interface{}, error) {
+	return this.write(resolutionContext, entity, true)
+}
+
+// Delete removes the selected entity, deleting a missing entity succeeds
+// without rewriting the file or reporting a change
+func (this *writableFileResolver) Delete(resolutionContext context.Context, selector resolvers.Selector) error {
+	id := resolvers.KeyString(selector.Key())
+
+	err := this.update(func(raw map[string]interface{}) error {
+		if _, ok := raw[id]; !ok {
+			return errUnchanged
+		}
+
+		delete(raw, id)
+		return nil
+	})
+
+	if err == errUnchanged {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	this.Publish(changetype.DELETE, selector.Key(), nil)
+
+	return nil
+}
+
+// writableDirectoryResolver is a directory resolver which implements
+// MutableResolver, each write replaces a single entity file
+type writableDirectoryResolver struct {
+	*directoryResolver
+}
+
+func validFileId(id string) error {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, "/\\") {
+		return fmt.Errorf("Invalid entity file name: %s", id)
+	}
+
+	return nil
+}
+
+func (this *writableDirectoryResolver) write(resolutionContext context.Context, entity interface{}, mustExist bool) (interface{}, error) {
+	key, id, err := entityId(this.resolverInfo, entity)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validFileId(id); err != nil {
+		return nil, err
+	}
+
+	jsonEntity, err := toJSON(resolutionContext, this.resolverInfo, id, entity)
+
+	if err != nil {
+		return nil, err
+	}
+
+	changeType, err := this.writeFile(id, jsonEntity, mustExist)
+
+	if err != nil {
+		if resolvers.IsNotFound(err) {
+			return nil, resolvers.NewEntityNotFound(fmt.Sprintf("Can't resolve entity for %v", key), nil)
+		}
+
+		return nil, err
+	}
+
+	this.Publish(changeType, key, entity)
+
+	return entity, nil
+}
+
+func (this *writableDirectoryResolver) writeFile(id string, jsonEntity map[string]interface{}, mustExist bool) (changetype.ChangeType, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	index, err := this.getIndex()
+
+	if err != nil {
+		return -1, err
+	}
+
+	changeType := changetype.UPDATE
+	fileName, ok := index[id]
+
+	if !ok {
+		if mustExist {
+			return -1, resolvers.NewEntityNotFound(id, nil)
+		}
+
+		changeType = changetype.PUT
+		fileName = id + ".json"
+	}
+
+	data, err := marshal(fileName, jsonEntity)
+
+	if err != nil {
+		return -1, err
+	}
+
+	if err := writeAtomic(path.Join(this.path, fileName), data); err != nil {
+		return -1, err
+	}
+
+	index[id] = fileName
+	delete(this.entities, id)
+
+	return changeType, nil
+}
+
+func (this *writableDirectoryResolver) Put(resolutionContext context.Context, entity interface{}) (interface{}, error) {
+	return this.write(resolutionContext, entity, false)
+}
+
+func (this *writableDirectoryResolver) Post(resolutionContext context.Context, entity interface{}) (interface{}, error) {
+	return this.write(resolutionContext, entity, true)
+}
+
+// Delete removes the selected entity's file, deleting a missing entity
+// succeeds without reporting a change
+func (this *writableDirectoryResolver) Delete(resolutionContext context.Context, selector resolvers.Selector) error {
+	entity, removed, err := this.removeFile(resolvers.KeyString(selector.Key()))
+
+	if err != nil || !removed {
+		return err
+	}
+
+	this.Publish(changetype.DELETE, selector.Key(), entity)
+
+	return nil
+}
+
+// removeFile removes the file for id, returning its entity if it was loaded
+// and whether there was a file to remove
+func (this *writableDirectoryResolver) removeFile(id string) (interface{}, bool, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	index, err := this.getIndex()
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	fileName, ok := index[id]
+
+	if !ok {
+		return nil, false, nil
+	}
+
+	entity := this.entities[id]
+
+	if err := os.Remove(path.Join(this.path, fileName)); err != nil && !os.IsNotExist(err) {
+		return nil, false, err
+	}
+
+	delete(index, id)
+	delete(this.entities, id)
+
+	return entity, true, nil
+}
@@ -0,0 +1,303 @@
+package fileresolver_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/changetype"
+	"github.com/distributed-vision/go-resources/resolvers/fileresolver"
+	"github.com/distributed-vision/go-resources/translators"
+	"github.com/distributed-vision/go-resources/types/gotypeid"
+	"github.com/distributed-vision/go-resources/util"
+)
+
+func newWritable(t *testing.T, location string, writable interface{}) resolvers.Resolver {
+	resolver, err := fileresolver.New(location, fileresolver.NewResolverInfo(
+		[]ids.TypeIdentifier{contentType}, nil, idExtractor,
+		map[interface{}]interface{}{"writable": writable}))
+
+	if err != nil {
+		t.Fatal("New failed:", err)
+	}
+
+	return resolver
+}
+
+func TestFileWrites(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileresolver")
+
+	if err != nil {
+		t.Fatal("TestFileWrites: TempDir failed:", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	readOnly := filepath.Join(dir, "readonly.json")
+	ioutil.WriteFile(readOnly, []byte(`{"1": {"name": "one"}}`), 0644)
+
+	if _, ok := newWritable(t, readOnly, nil).(resolvers.MutableResolver); ok {
+		t.Fatal("TestFileWrites: file resolvers should be read only by default")
+	}
+
+	if _, err := fileresolver.New(readOnly, fileresolver.NewResolverInfo(
+		[]ids.TypeIdentifier{contentType}, nil, idExtractor,
+		map[interface{}]interface{}{"writable": true})); err == nil {
+		t.Fatal("TestFileWrites: reopening a file with a different writable value should fail")
+	}
+
+	file := filepath.Join(dir, "entities.json")
+	ioutil.WriteFile(file, []byte(`{"1": {"name": "one"}}`), 0644)
+
+	resolver, ok := newWritable(t, file, true).(resolvers.MutableResolver)
+
+	if !ok {
+		t.Fatal("TestFileWrites: expected a MutableResolver")
+	}
+
+	if _, err := resolver.Get(testContext, &selector{key: "1"}); err != nil {
+		t.Fatal("TestFileWrites: Get failed:", err)
+	}
+
+	if _, err := resolver.Post(testContext, map[string]interface{}{"id": "2", "name": "two"}); err == nil {
+		t.Fatal("TestFileWrites: Post of a missing entity should fail")
+	}
+
+	var wait sync.WaitGroup
+
+	for _, id := range []string{"3", "2", "4"} {
+		wait.Add(1)
+		go func(id string) {
+			defer wait.Done()
+			if _, err := resolver.Put(testContext, map[string]interface{}{"id": id, "name": "entity " + id}); err != nil {
+				t.Error("TestFileWrites: Put failed:", err)
+			}
+		}(id)
+	}
+
+	wait.Wait()
+
+	if _, err := resolver.Post(testContext, map[string]interface{}{"id": "1", "name": "uno"}); err != nil {
+		t.Fatal("TestFileWrites: Post failed:", err)
+	}
+
+	deletes := 0
+	resolver.(resolvers.ChangeNotifier).OnChange(func(event resolvers.ChangeEvent) {
+		if event.Type == changetype.DELETE {
+			deletes++
+		}
+	})
+
+	if err := resolver.Delete(testContext, &selector{key: "4"}); err != nil {
+		t.Fatal("TestFileWrites: Delete failed:", err)
+	}
+
+	if err := resolver.Delete(testContext, &selector{key: "missing"}); err != nil || deletes != 1 {
+		t.Fatal("TestFileWrites: Delete of a missing entity should not report a change:", deletes, err)
+	}
+
+	expected := `{
+  "1": {
+    "name": "uno"
+  },
+  "2": {
+    "name": "entity 2"
+  },
+  "3": {
+    "name": "entity 3"
+  }
+}
+`
+
+	if data, _ := ioutil.ReadFile(file); string(data) != expected {
+		t.Fatal("TestFileWrites: unexpected file content:", string(data))
+	}
+
+	if entity, err := resolver.Get(testContext, &selector{key: "1"}); err != nil || entity.(map[string]interface{})["name"] != "uno" {
+		t.Fatal("TestFileWrites: expected the rewritten entity got:", entity, err)
+	}
+
+	if matches, _ := filepath.Glob(filepath.Join(dir, ".*")); len(matches) != 0 {
+		t.Fatal("TestFileWrites: temporary files should be removed:", matches)
+	}
+}
+
+func TestDirectoryWrites(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileresolver")
+
+	if err != nil {
+		t.Fatal("TestDirectoryWrites: TempDir failed:", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "1.yaml"), []byte("name: one\n"), 0644)
+
+	resolver, ok := newWritable(t, dir, "true").(resolvers.MutableResolver)
+
+	if !ok {
+		t.Fatal("TestDirectoryWrites: expected a MutableResolver")
+	}
+
+	if _, err := resolver.Post(testContext, map[string]interface{}{"id": "1", "name": "uno"}); err != nil {
+		t.Fatal("TestDirectoryWrites: Post failed:", err)
+	}
+
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "1.yaml")); string(data) != "name: uno\n" {
+		t.Fatal("TestDirectoryWrites: expected yaml to be rewritten got:", string(data))
+	}
+
+	if _, err := resolver.Put(testContext, map[string]interface{}{"id": "2", "name": "two"}); err != nil {
+		t.Fatal("TestDirectoryWrites: Put failed:", err)
+	}
+
+	if entity, err := resolver.Get(testContext, &selector{key: "2"}); err != nil || entity.(map[string]interface{})["name"] != "two" {
+		t.Fatal("TestDirectoryWrites: Get failed:", entity, err)
+	}
+
+	if _, err := resolver.Put(testContext, map[string]interface{}{"id": "../escape"}); err == nil {
+		t.Fatal("TestDirectoryWrites: keys should not escape the directory")
+	}
+
+	if err := resolver.Delete(testContext, &selector{key: "1"}); err != nil {
+		t.Fatal("TestDirectoryWrites: Delete failed:", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "1.yaml")); !os.IsNotExist(err) {
+		t.Fatal("TestDirectoryWrites: expected the entity file to be removed")
+	}
+
+	if _, err := resolver.Get(testContext, &selector{key: "1"}); err == nil {
+		t.Fatal("TestDirectoryWrites: expected deleted entity to be missing")
+	}
+}
+
+type untranslatedEntity struct {
+	id string
+}
+
+func TestYamlFileWrites(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileresolver")
+
+	if err != nil {
+		t.Fatal("TestYamlFileWrites: TempDir failed:", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "entities.yaml")
+	ioutil.WriteFile(file, []byte("\"1\":\n  name: one\n"), 0644)
+
+	resolver, ok := newWritable(t, file, true).(resolvers.MutableResolver)
+
+	if !ok {
+		t.Fatal("TestYamlFileWrites: expected a MutableResolver")
+	}
+
+	if entity, err := resolver.Get(testContext, &selector{key: "1"}); err != nil || entity.(map[string]interface{})["name"] != "one" {
+		t.Fatal("TestYamlFileWrites: Get failed:", entity, err)
+	}
+
+	if _, err := resolver.Put(testContext, map[string]interface{}{"id": "2", "name": "two"}); err != nil {
+		t.Fatal("TestYamlFileWrites: Put failed:", err)
+	}
+
+	if _, err := resolver.Put(testContext, map[string]interface{}{"id": "3", "name": "three"}); err != nil {
+		t.Fatal("TestYamlFileWrites: second Put failed:", err)
+	}
+
+	if data, _ := ioutil.ReadFile(file); string(data) != "\"1\":\n    name: one\n\"2\":\n    name: two\n\"3\":\n    name: three\n" {
+		t.Fatal("TestYamlFileWrites: unexpected file content:", string(data))
+	}
+
+	if entity, err := resolver.Get(testContext, &selector{key: "2"}); err != nil || entity.(map[string]interface{})["name"] != "two" {
+		t.Fatal("TestYamlFileWrites: expected the written entity got:", entity, err)
+	}
+
+	untranslatedFile := filepath.Join(dir, "untranslated.json")
+	ioutil.WriteFile(untranslatedFile, []byte(`{}`), 0644)
+
+	untranslated, err := fileresolver.New(untranslatedFile, fileresolver.NewResolverInfo(nil, nil,
+		func(e ...interface{}) (interface{}, bool) { return "untranslated", true },
+		map[interface{}]interface{}{"writable": true}))
+
+	if err != nil {
+		t.Fatal("TestYamlFileWrites: New failed:", err)
+	}
+
+	if _, err := untranslated.(resolvers.MutableResolver).Put(testContext, untranslatedEntity{"untranslated"}); !errors.Is(err, translators.ErrNoTranslator) {
+		t.Fatal("TestYamlFileWrites: expected NoTranslator got:", err)
+	}
+}
+
+type named struct {
+	id   string
+	name string
+}
+
+type namedSelector struct {
+	key string
+}
+
+func (this *namedSelector) Type() ids.TypeIdentifier {
+	return namedType
+}
+
+func (this *namedSelector) Key() interface{} {
+	return this.key
+}
+
+func (this *namedSelector) Test(candidate interface{}) bool {
+	entity, ok := candidate.(named)
+	return ok && entity.id == this.key
+}
+
+var namedType = gotypeid.IdOf(reflect.TypeOf(named{}))
+
+func TestFileTargetTypes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileresolver")
+
+	if err != nil {
+		t.Fatal("TestFileTargetTypes: TempDir failed:", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	translators.Register(testContext, contentType, namedType, func(translationContext context.Context, fromId ids.Identifier, fromValue interface{}) (chan interface{}, chan error) {
+		entity := fromValue.(map[string]interface{})
+		return util.Resolved[interface{}](named{string(fromId.Id()), entity["name"].(string)}).Channels()
+	})
+
+	extractor := func(e ...interface{}) (interface{}, bool) {
+		if entity, ok := e[0].(named); ok {
+			return entity.id, true
+		}
+
+		return idExtractor(e...)
+	}
+
+	file := filepath.Join(dir, "types.json")
+	ioutil.WriteFile(file, []byte(`{"1": {"name": "one"}}`), 0644)
+
+	resolver, err := fileresolver.New(file, fileresolver.NewResolverInfo(
+		[]ids.TypeIdentifier{contentType, namedType}, nil, extractor, nil))
+
+	if err != nil {
+		t.Fatal("TestFileTargetTypes: New failed:", err)
+	}
+
+	if entity, err := resolver.Get(testContext, &selector{key: "1"}); err != nil || entity.(map[string]interface{})["name"] != "one" {
+		t.Fatal("TestFileTargetTypes: Get of a json entity failed:", entity, err)
+	}
+
+	if entity, err := resolver.Get(testContext, &namedSelector{"1"}); err != nil || entity.(named).name != "one" {
+		t.Fatal("TestFileTargetTypes: Get of a translated entity failed:", entity, err)
+	}
+}
@@ -0,0 +1,72 @@
+package resolvers
+
+import (
+	"context"
+	"sync"
+)
+
+type flight struct {
+	done    chan struct{}
+	result  interface{}
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// flightGroup collapses concurrent calls with the same key into a single
+// call whose result is shared by all of the callers. The call runs with a
+// context which keeps the first caller's values but is only cancelled once
+// every waiting caller has given up
+type flightGroup struct {
+	mutex   *sync.Mutex
+	flights map[string]*flight
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{&sync.Mutex{}, make(map[string]*flight)}
+}
+
+func (this *flightGroup) do(callContext context.Context, key string, call func(context.Context) (interface{}, error)) (result interface{}, err error, shared bool) {
+	this.mutex.Lock()
+
+	current, shared := this.flights[key]
+
+	if !shared {
+		flightContext, cancel := context.WithCancel(context.WithoutCancel(callContext))
+		current = &flight{done: make(chan struct{}), cancel: cancel}
+		this.flights[key] = current
+
+		go func() {
+			current.result, current.err = call(flightContext)
+
+			this.mutex.Lock()
+			if this.flights[key] == current {
+				delete(this.flights, key)
+			}
+			this.mutex.Unlock()
+
+			cancel()
+			close(current.done)
+		}()
+	}
+
+	current.waiters++
+	this.mutex.Unlock()
+
+	select {
+	case <-current.done:
+		return current.result, current.err, shared
+	case <-callContext.Done():
+		this.mutex.Lock()
+		current.waiters--
+		if current.waiters == 0 {
+			current.cancel()
+			if this.flights[key] == current {
+				delete(this.flights, key)
+			}
+		}
+		this.mutex.Unlock()
+
+		return nil, callContext.Err(), shared
+	}
+}
@@ -0,0 +1,129 @@
+package resolvers_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/util/random"
+)
+
+type stubFactory struct {
+	resolverInfo resolvers.ResolverInfo
+	resolver     *stubResolver
+	instances    int32
+}
+
+func (this *stubFactory) New(resolutionContext context.Context) (resolvers.Resolver, error) {
+	atomic.AddInt32(&this.instances, 1)
+	// give concurrent callers the chance to race on instantiation
+	time.Sleep(time.Millisecond)
+	return this.resolver, nil
+}
+
+func (this *stubFactory) ResolverType() ids.TypeIdentifier {
+	return testResolverType
+}
+
+func (this *stubFactory) ResolverInfo() resolvers.ResolverInfo {
+	return this.resolverInfo
+}
+
+func TestCoalescedResolve(t *testing.T) {
+	component := newStubResolver(0, 20*time.Millisecond, "value", nil)
+	factory := &stubFactory{resolverInfo: component.ResolverInfo(), resolver: component}
+
+	resolver := newStrategyResolver(t, testInfo)
+
+	if err := resolver.RegisterComponentFactory(factory, false); err != nil {
+		t.Fatal("TestCoalescedResolve: RegisterComponentFactory failed:", err)
+	}
+
+	key := random.RandomString(20)
+	var wg sync.WaitGroup
+	var failures int32
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			resolved, err := resolver.Get(testContext, &typedSelector{key: key})
+
+			if err != nil || resolved.(entity).key != key {
+				atomic.AddInt32(&failures, 1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if failures != 0 {
+		t.Fatal("TestCoalescedResolve: failed resolutions:", failures)
+	}
+
+	if factory.instances != 1 {
+		t.Fatal("TestCoalescedResolve: expected 1 component instance got:", factory.instances)
+	}
+
+	if component.calls != 1 {
+		t.Fatal("TestCoalescedResolve: expected 1 component call got:", component.calls)
+	}
+}
+
+func TestCoalescedResolveStress(t *testing.T) {
+	component := newStubResolver(0, time.Millisecond, "value", nil)
+	factory := &stubFactory{resolverInfo: component.ResolverInfo(), resolver: component}
+
+	resolver := newStrategyResolver(t, testInfo)
+	resolver.RegisterComponentFactory(factory, false)
+
+	keys := make([]string, 16)
+
+	for i := range keys {
+		keys[i] = random.RandomString(20)
+	}
+
+	var wg sync.WaitGroup
+	var failures int32
+
+	for i := 0; i < 400; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			resolutionContext := testContext
+
+			// some callers give up early, which must not fail the others
+			if i%10 == 0 {
+				var cancel context.CancelFunc
+				resolutionContext, cancel = context.WithCancel(testContext)
+				cancel()
+			}
+
+			resolved, err := resolver.Get(resolutionContext, &typedSelector{key: keys[i%len(keys)]})
+
+			if i%10 != 0 && (err != nil || resolved.(entity).key != keys[i%len(keys)]) {
+				atomic.AddInt32(&failures, 1)
+			}
+
+			if i%3 == 0 {
+				resolver.Invalidate(keys[i%len(keys)])
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if failures != 0 {
+		t.Fatal("TestCoalescedResolveStress: failed resolutions:", failures)
+	}
+
+	if factory.instances != 1 {
+		t.Fatal("TestCoalescedResolveStress: expected 1 component instance got:", factory.instances)
+	}
+}
@@ -0,0 +1,586 @@
+package httpresolver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/distributed-vision/go-resources/encoding/encodertype"
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/ids/domain"
+	"github.com/distributed-vision/go-resources/ids/identifier"
+	"github.com/distributed-vision/go-resources/ids/mappings"
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/changetype"
+	"github.com/distributed-vision/go-resources/resolvers/operatortype"
+	"github.com/distributed-vision/go-resources/translators"
+	"github.com/distributed-vision/go-resources/types"
+	"github.com/distributed-vision/go-resources/types/gotypeid"
+	"github.com/distributed-vision/go-resources/types/publictypeid"
+	"github.com/distributed-vision/go-resources/util"
+	"github.com/distributed-vision/go-resources/version"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+var contentType ids.TypeIdentifier = gotypeid.IdOf(reflect.TypeOf(map[string]interface{}{}))
+
+var resolverType ids.TypeIdentifier = gotypeid.IdOf(reflect.TypeOf(HttpResolver{}))
+var publicTypeVersion = version.New(0, 0, 1)
+
+var PublicType = types.MustNewId(publictypeid.ResolverDomain, []byte("HttpResolver"), publicTypeVersion)
+
+func init() {
+	mappings.Map(context.Background(), resolverType, PublicType)
+	resolvers.ResisterNewFactoryFunction(PublicType, NewResolverFactory)
+}
+
+func ResolverType() ids.TypeIdentifier {
+	return resolverType
+}
+
+func NewResolverInfo(resolvableTypes []ids.TypeIdentifier, resolvableDomains []ids.Domain,
+	keyExtractor resolvers.KeyExtractor, values map[interface{}]interface{}) resolvers.ResolverInfo {
+	return resolvers.NewResolverInfo(PublicType,
+		resolvableTypes, resolvableDomains, keyExtractor, values)
+}
+
+type factory struct {
+	resolverInfo resolvers.ResolverInfo
+}
+
+func NewResolverFactory(resolverInfo resolvers.ResolverInfo) (resolvers.ResolverFactory, error) {
+	return &factory{resolverInfo}, nil
+}
+
+func (this *factory) New(resolutionContext context.Context) (resolvers.Resolver, error) {
+	baseUrl, ok := this.resolverInfo.Value("baseUrl").(string)
+
+	if !ok || baseUrl == "" {
+		return nil, fmt.Errorf("ResolverInfo 'baseUrl' value can't be empty")
+	}
+
+	return New(baseUrl, this.resolverInfo)
+}
+
+func (this *factory) ResolverType() ids.TypeIdentifier {
+	return resolverType
+}
+
+func (this *factory) ResolverInfo() resolvers.ResolverInfo {
+	return this.resolverInfo
+}
+
+// DefaultEtagCacheSize is the number of entities held for conditional
+// reads when the resolver info has no "etagCacheSize" value
+const DefaultEtagCacheSize = 1024
+
+type cacheKey struct {
+	entityType string
+	entityUrl  string
+}
+
+type cacheEntry struct {
+	etag   string
+	entity interface{}
+}
+
+// HttpResolver resolves entities from a remote registry. An entity is read
+// with a GET of baseUrl/key, and written with a PUT, POST or DELETE to the
+// same url. Responses carrying an ETag are held, per url and entity type, so
+// that later reads can be made conditional. Any "headers" info value is added
+// to every request
+type HttpResolver struct {
+	*resolvers.ChangeFeed
+	baseUrl      string
+	resolverInfo resolvers.ResolverInfo
+	client       *http.Client
+	etags        *lru.Cache
+}
+
+func New(baseUrl string, resolverInfo resolvers.ResolverInfo) (*HttpResolver, error) {
+	if resolverInfo == nil {
+		return nil, fmt.Errorf("base resolver info must be defined")
+	}
+
+	if _, err := url.Parse(baseUrl); err != nil {
+		return nil, err
+	}
+
+	etags, err := lru.New(etagCacheSize(resolverInfo))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &HttpResolver{
+		ChangeFeed:   &resolvers.ChangeFeed{},
+		baseUrl:      strings.TrimRight(baseUrl, "/"),
+		resolverInfo: resolverInfo,
+		client:       http.DefaultClient,
+		etags:        etags}, nil
+}
+
+func etagCacheSize(resolverInfo resolvers.ResolverInfo) int {
+	switch size := resolverInfo.Value("etagCacheSize").(type) {
+	case int:
+		return size
+	case int64:
+		return int(size)
+	case float64:
+		return int(size)
+	}
+
+	return DefaultEtagCacheSize
+}
+
+func (this *HttpResolver) SetClient(client *http.Client) {
+	this.client = client
+}
+
+func (this *HttpResolver) ResolverInfo() resolvers.ResolverInfo {
+	return this.resolverInfo
+}
+
+func (this *HttpResolver) entityUrl(key interface{}) (string, error) {
+	keyString := resolvers.KeyString(key)
+
+	if keyString == "" {
+		return "", resolvers.NewEntityNotFound("Resolve Failed: HttpResolver requires a selector key", nil)
+	}
+
+	return this.baseUrl + "/" + url.PathEscape(keyString), nil
+}
+
+func (this *HttpResolver) newRequest(requestContext context.Context, method string, url string, body io.Reader) (*http.Request, error) {
+	request, err := http.NewRequest(method, url, body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	request = request.WithContext(requestContext)
+	request.Header.Set("Accept", "application/json")
+
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	if headers, ok := this.resolverInfo.Value("headers").(map[string]interface{}); ok {
+		for name, value := range headers {
+			request.Header.Set(name, fmt.Sprint(value))
+		}
+	}
+
+	return request, nil
+}
+
+// responseError converts a failed response to an error, not found responses
+// become EntityNotFound, conflicting and ambiguous responses Conflict and
+// Ambiguous, and responses which may succeed later TemporaryError or Timeout
+func responseError(method string, url string, response *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+	reason := fmt.Sprintf("%s %s Failed: %s %s", method, url, response.Status, strings.TrimSpace(string(body)))
+
+	switch {
+	case response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusGone:
+		return resolvers.NewEntityNotFound(reason, nil)
+	case response.StatusCode == http.StatusForbidden || response.StatusCode == http.StatusUnauthorized:
+		return resolvers.NewAccessDenied(nil, reason)
+	case response.StatusCode == http.StatusConflict || response.StatusCode == http.StatusPreconditionFailed:
+		return resolvers.NewConflict(reason, nil)
+	case response.StatusCode == http.StatusMultipleChoices:
+		return resolvers.NewAmbiguous(reason, nil)
+	case response.StatusCode == http.StatusGatewayTimeout || response.StatusCode == http.StatusRequestTimeout:
+		return resolvers.NewTimeout(reason, nil)
+	case response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500:
+		return resolvers.NewTemporaryError(reason, nil)
+	default:
+		return fmt.Errorf("%s", reason)
+	}
+}
+
+// requestError converts a failed request to an error, requests which timed
+// out become Timeout and other failures TemporaryError
+func requestError(reason string, err error) error {
+	if timeout, ok := err.(interface{ Timeout() bool }); ok && timeout.Timeout() {
+		return resolvers.NewTimeout(reason, err)
+	}
+
+	return resolvers.NewTemporaryError(reason, err)
+}
+
+func (this *HttpResolver) Get(resolutionContext context.Context, selector resolvers.Selector) (interface{}, error) {
+	return util.Await(this.Resolve(resolutionContext, selector))
+}
+
+func (this *HttpResolver) Resolve(resolutionContext context.Context, selector resolvers.Selector) (chan interface{}, chan error) {
+	cres, cerr := make(chan interface{}, 1), make(chan error, 1)
+
+	go func() {
+		entity, _, err := this.get(resolutionContext, selector)
+
+		if err != nil {
+			cerr <- err
+		} else {
+			cres <- entity
+		}
+
+		close(cres)
+		close(cerr)
+	}()
+
+	return cres, cerr
+}
+
+// Query streams the entities selected by selector. A keyed selector is read
+// with a GET of its entity url, otherwise the selector's equality fields are
+// sent as query parameters with a GET of baseUrl, which must respond with a
+// json array of entities. Entities are passed to the selector's Test so
+// registries may ignore parameters they don't support
+func (this *HttpResolver) Query(queryContext context.Context, selector resolvers.Selector, opts resolvers.QueryOpts) (<-chan interface{}, <-chan error) {
+	stream := resolvers.NewQueryStream(queryContext, opts, this.resolverInfo.KeyExtractor())
+
+	go func() {
+		if resolvers.KeyString(selector.Key()) != "" {
+			entity, _, err := this.get(queryContext, selector)
+
+			if err == nil {
+				stream.Add(entity)
+			} else if resolvers.IsNotFound(err) {
+				err = nil
+			}
+
+			stream.Close(err)
+			return
+		}
+
+		stream.Close(this.query(queryContext, selector, stream))
+	}()
+
+	return stream.Results()
+}
+
+func (this *HttpResolver) query(queryContext context.Context, selector resolvers.Selector, stream *resolvers.QueryStream) error {
+	queryUrl := this.baseUrl
+
+	if parameters := queryParameters(selector); len(parameters) > 0 {
+		queryUrl += "?" + parameters.Encode()
+	}
+
+	request, err := this.newRequest(queryContext, http.MethodGet, queryUrl, nil)
+
+	if err != nil {
+		return err
+	}
+
+	response, err := this.client.Do(request)
+
+	if err != nil {
+		return requestError(fmt.Sprintf("GET %s Failed: ", queryUrl), err)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return responseError(http.MethodGet, queryUrl, response)
+	}
+
+	var jsonEntities []interface{}
+
+	if err := json.NewDecoder(response.Body).Decode(&jsonEntities); err != nil {
+		return err
+	}
+
+	for _, jsonEntity := range jsonEntities {
+		var key string
+
+		if jsonKey, ok := this.resolverInfo.KeyExtractor()(jsonEntity); ok {
+			key = resolvers.KeyString(jsonKey)
+		}
+
+		entity, err := this.translate(queryContext, selector.Type(), key, jsonEntity)
+
+		if err != nil {
+			return err
+		}
+
+		if selector.Test(entity) && !stream.Add(entity) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// queryParameters maps the equality fields every selected entity must have
+// to query parameters, fields compared with other operators, or ignoring
+// case or whitespace, are only applied by the selector's Test
+func queryParameters(selector resolvers.Selector) url.Values {
+	parameters := url.Values{}
+	fields, _ := resolvers.Conjuncts(selector)
+
+	for _, field := range fields {
+		if field.Accessor != nil || field.Operator != operatortype.EQ ||
+			field.Opts.IgnoreCase || field.Opts.IgnoreWhitespace || field.Value == nil {
+			continue
+		}
+
+		parameters.Add(field.Field, fmt.Sprint(field.Value))
+	}
+
+	return parameters
+}
+
+// GetRevision returns the selected entity with its ETag as its revision
+func (this *HttpResolver) GetRevision(resolutionContext context.Context, selector resolvers.Selector) (interface{}, resolvers.Revision, error) {
+	entity, etag, err := this.get(resolutionContext, selector)
+
+	if err != nil {
+		return nil, resolvers.NoRevision, err
+	}
+
+	return entity, toRevision(etag), nil
+}
+
+func toRevision(etag string) resolvers.Revision {
+	return resolvers.Revision(strings.Trim(strings.TrimPrefix(etag, "W/"), `"`))
+}
+
+func (this *HttpResolver) get(resolutionContext context.Context, selector resolvers.Selector) (interface{}, string, error) {
+	entityUrl, err := this.entityUrl(selector.Key())
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	request, err := this.newRequest(resolutionContext, http.MethodGet, entityUrl, nil)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := cacheKey{typeString(selector.Type()), entityUrl}
+	var cached *cacheEntry
+
+	if value, ok := this.etags.Get(key); ok {
+		cached = value.(*cacheEntry)
+	}
+
+	if cached != nil {
+		request.Header.Set("If-None-Match", cached.etag)
+	}
+
+	response, err := this.client.Do(request)
+
+	if err != nil {
+		return nil, "", requestError(fmt.Sprintf("GET %s Failed: ", entityUrl), err)
+	}
+
+	defer response.Body.Close()
+
+	var entity interface{}
+	var etag string
+
+	switch {
+	case response.StatusCode == http.StatusNotModified && cached != nil:
+		entity, etag = cached.entity, cached.etag
+	case response.StatusCode == http.StatusOK:
+		var jsonEntity interface{}
+
+		if err := json.NewDecoder(response.Body).Decode(&jsonEntity); err != nil {
+			return nil, "", err
+		}
+
+		entity, err = this.translate(resolutionContext, selector.Type(), resolvers.KeyString(selector.Key()), jsonEntity)
+
+		if err != nil {
+			return nil, "", err
+		}
+
+		etag = response.Header.Get("ETag")
+
+		if etag != "" {
+			this.etags.Add(key, &cacheEntry{etag, entity})
+		} else {
+			this.etags.Remove(key)
+		}
+	default:
+		if response.StatusCode == http.StatusNotFound {
+			this.forget(entityUrl)
+		}
+
+		return nil, "", responseError(http.MethodGet, entityUrl, response)
+	}
+
+	if !selector.Test(entity) {
+		return nil, "", resolvers.NewEntityNotFound(fmt.Sprintf("Can't resolve entity for %v", selector), nil)
+	}
+
+	return entity, etag, nil
+}
+
+var untypedLocalDomain []byte = domain.MustDecodeId(encodertype.BASE62, "3", "")
+
+// translate converts a decoded json entity to targetType
+func (this *HttpResolver) translate(resolutionContext context.Context, targetType ids.TypeIdentifier, key string, jsonEntity interface{}) (interface{}, error) {
+	if targetType == nil || targetType.Equals(contentType) {
+		return jsonEntity, nil
+	}
+
+	entityId, err := identifier.New(untypedLocalDomain, []byte(key), nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resolutionContext = context.WithValue(resolutionContext, "resolverInfo", this.resolverInfo)
+
+	return translators.TranslateFuture(resolutionContext, contentType, entityId, jsonEntity, targetType).Await()
+}
+
+func typeString(entityType ids.TypeIdentifier) string {
+	if entityType == nil {
+		return ""
+	}
+
+	return entityType.String()
+}
+
+// forget removes the entities held for entityUrl for every entity type
+func (this *HttpResolver) forget(entityUrl string) {
+	for _, key := range this.etags.Keys() {
+		if key.(cacheKey).entityUrl == entityUrl {
+			this.etags.Remove(key)
+		}
+	}
+}
+
+// write sends the json map form of entity with method, adding any
+// precondition headers, and returns the ETag of the written entity if the
+// response has one
+func (this *HttpResolver) write(resolutionContext context.Context, method string, entity interface{}, precondition http.Header) (interface{}, string, error) {
+	key, ok := this.resolverInfo.KeyExtractor()(entity)
+
+	if !ok {
+		return nil, "", fmt.Errorf("Cannot extract key from: %v", entity)
+	}
+
+	entityUrl, err := this.entityUrl(key)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	jsonEntity, err := translators.ToMap(resolutionContext, entity, this.resolverInfo.ResolvableTypes()...)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	body, err := json.Marshal(jsonEntity)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	request, err := this.newRequest(resolutionContext, method, entityUrl, bytes.NewReader(body))
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	for name, values := range precondition {
+		request.Header[name] = values
+	}
+
+	response, err := this.client.Do(request)
+
+	if err != nil {
+		return nil, "", requestError(fmt.Sprintf("%s %s Failed: ", method, entityUrl), err)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, "", responseError(method, entityUrl, response)
+	}
+
+	this.forget(entityUrl)
+
+	if method == http.MethodPost {
+		this.Publish(changetype.UPDATE, key, entity)
+	} else {
+		this.Publish(changetype.PUT, key, entity)
+	}
+
+	return entity, response.Header.Get("ETag"), nil
+}
+
+func (this *HttpResolver) Put(resolutionContext context.Context, entity interface{}) (interface{}, error) {
+	entity, _, err := this.write(resolutionContext, http.MethodPut, entity, nil)
+	return entity, err
+}
+
+func (this *HttpResolver) Post(resolutionContext context.Context, entity interface{}) (interface{}, error) {
+	entity, _, err := this.write(resolutionContext, http.MethodPost, entity, nil)
+	return entity, err
+}
+
+// PostIfMatch posts entity with an If-Match of revision, or puts it with an
+// If-None-Match of * if revision is NoRevision. The remote registry's
+// Precondition Failed response is returned as a Conflict
+func (this *HttpResolver) PostIfMatch(resolutionContext context.Context, entity interface{}, revision resolvers.Revision) (resolvers.Revision, error) {
+	method, precondition := http.MethodPost, http.Header{}
+
+	if revision == resolvers.NoRevision {
+		method = http.MethodPut
+		precondition.Set("If-None-Match", "*")
+	} else {
+		precondition.Set("If-Match", `"`+string(revision)+`"`)
+	}
+
+	_, etag, err := this.write(resolutionContext, method, entity, precondition)
+
+	if err != nil {
+		return resolvers.NoRevision, err
+	}
+
+	return toRevision(etag), nil
+}
+
+func (this *HttpResolver) Delete(resolutionContext context.Context, selector resolvers.Selector) error {
+	entityUrl, err := this.entityUrl(selector.Key())
+
+	if err != nil {
+		return err
+	}
+
+	request, err := this.newRequest(resolutionContext, http.MethodDelete, entityUrl, nil)
+
+	if err != nil {
+		return err
+	}
+
+	response, err := this.client.Do(request)
+
+	if err != nil {
+		return requestError(fmt.Sprintf("DELETE %s Failed: ", entityUrl), err)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return responseError(http.MethodDelete, entityUrl, response)
+	}
+
+	this.forget(entityUrl)
+	this.Publish(changetype.DELETE, selector.Key(), nil)
+
+	return nil
+}
@@ -0,0 +1,311 @@
+package httpresolver_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/httpresolver"
+	"github.com/distributed-vision/go-resources/resolvers/operatortype"
+	"github.com/distributed-vision/go-resources/translators"
+	"github.com/distributed-vision/go-resources/types/gotypeid"
+	"github.com/distributed-vision/go-resources/util"
+)
+
+type selector struct {
+	key string
+}
+
+func (this *selector) Type() ids.TypeIdentifier {
+	return nil
+}
+
+func (this *selector) Key() interface{} {
+	return this.key
+}
+
+func (this *selector) Test(candidate interface{}) bool {
+	entity, ok := candidate.(map[string]interface{})
+	return ok && entity["key"] == this.key
+}
+
+func extractor(e ...interface{}) (interface{}, bool) {
+	key, ok := e[0].(map[string]interface{})["key"].(string)
+	return key, ok
+}
+
+var testContext = context.Background()
+
+// registry is a minimal entity registry which versions each entity so that
+// it can answer conditional requests
+type registry struct {
+	mutex       sync.Mutex
+	entities    map[string]map[string]interface{}
+	versions    map[string]int
+	gets        int
+	queries     []string
+	notModified int
+	failures    int
+}
+
+func newRegistry() *registry {
+	return &registry{entities: map[string]map[string]interface{}{}, versions: map[string]int{}}
+}
+
+func (this *registry) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if request.Header.Get("Authorization") != "token" {
+		response.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if this.failures > 0 {
+		this.failures--
+		response.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	if request.URL.Path == "/entities" && request.Method == http.MethodGet {
+		this.queries = append(this.queries, request.URL.RawQuery)
+		entities := []map[string]interface{}{}
+
+		for _, entity := range this.entities {
+			matches := true
+
+			for field, values := range request.URL.Query() {
+				matches = matches && entity[field] == values[0]
+			}
+
+			if matches {
+				entities = append(entities, entity)
+			}
+		}
+
+		json.NewEncoder(response).Encode(entities)
+		return
+	}
+
+	key := strings.TrimPrefix(request.URL.Path, "/entities/")
+	entity, exists := this.entities[key]
+	etag := fmt.Sprintf(`"%d"`, this.versions[key])
+
+	switch request.Method {
+	case http.MethodGet:
+		this.gets++
+
+		if !exists {
+			response.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if request.Header.Get("If-None-Match") == etag {
+			this.notModified++
+			response.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		response.Header().Set("ETag", etag)
+		json.NewEncoder(response).Encode(entity)
+	case http.MethodPut, http.MethodPost:
+		if request.Method == http.MethodPost && !exists {
+			response.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var entity map[string]interface{}
+		json.NewDecoder(request.Body).Decode(&entity)
+		this.entities[key] = entity
+		this.versions[key]++
+		response.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		delete(this.entities, key)
+		this.versions[key]++
+		response.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestHttpResolver(t *testing.T) {
+	registry := newRegistry()
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	info := httpresolver.NewResolverInfo(nil, nil, extractor, map[interface{}]interface{}{
+		"baseUrl": server.URL + "/entities/",
+		"headers": map[string]interface{}{"Authorization": "token"}})
+
+	factory, err := resolvers.NewResolverFactory(info)
+
+	if err != nil {
+		t.Fatal("TestHttpResolver: NewResolverFactory failed:", err)
+	}
+
+	component, err := factory.New(testContext)
+
+	if err != nil {
+		t.Fatal("TestHttpResolver: factory.New failed:", err)
+	}
+
+	resolver, ok := component.(resolvers.MutableResolver)
+
+	if !ok {
+		t.Fatal("TestHttpResolver: expected a MutableResolver")
+	}
+
+	if _, err := resolver.Get(testContext, &selector{"a key"}); err == nil {
+		t.Fatal("TestHttpResolver: expected missing entity")
+	} else if _, ok := err.(*resolvers.EntityNotFound); !ok {
+		t.Fatal("TestHttpResolver: expected EntityNotFound got:", err)
+	}
+
+	if _, err := resolver.Post(testContext, map[string]interface{}{"key": "a key", "value": "1"}); err == nil {
+		t.Fatal("TestHttpResolver: Post of a missing entity should fail")
+	}
+
+	if _, err := resolver.Put(testContext, map[string]interface{}{"key": "a key", "value": "1"}); err != nil {
+		t.Fatal("TestHttpResolver: Put failed:", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		entity, err := resolver.Get(testContext, &selector{"a key"})
+
+		if err != nil || entity.(map[string]interface{})["value"] != "1" {
+			t.Fatal("TestHttpResolver: Get failed:", entity, err)
+		}
+	}
+
+	if registry.notModified != 2 {
+		t.Fatal("TestHttpResolver: expected 2 conditional hits got:", registry.notModified)
+	}
+
+	if _, err := resolver.Post(testContext, map[string]interface{}{"key": "a key", "value": "2"}); err != nil {
+		t.Fatal("TestHttpResolver: Post failed:", err)
+	}
+
+	if entity, err := resolver.Get(testContext, &selector{"a key"}); err != nil || entity.(map[string]interface{})["value"] != "2" {
+		t.Fatal("TestHttpResolver: Get after Post failed:", entity, err)
+	}
+
+	if _, err := resolver.Put(testContext, map[string]interface{}{"key": "b key", "value": "3"}); err != nil {
+		t.Fatal("TestHttpResolver: Put failed:", err)
+	}
+
+	if _, err := resolver.Get(testContext, resolvers.Eq("value", "2")); !resolvers.IsNotFound(err) {
+		t.Fatal("TestHttpResolver: expected a keyless Get to be not found got:", err)
+	}
+
+	queryResolver, ok := component.(resolvers.QueryResolver)
+
+	if !ok {
+		t.Fatal("TestHttpResolver: expected a QueryResolver")
+	}
+
+	results, err := util.AwaitAll(queryResolver.Query(testContext,
+		resolvers.And(resolvers.Eq("value", "2"), resolvers.Where("key", operatortype.PREFIX, "a")), resolvers.QueryOpts{}))
+
+	if err != nil || len(results) != 1 || results[0].(map[string]interface{})["key"] != "a key" {
+		t.Fatal("TestHttpResolver: Query failed:", results, err)
+	}
+
+	if len(registry.queries) != 1 || registry.queries[0] != "value=2" {
+		t.Fatal("TestHttpResolver: expected equality fields as query parameters got:", registry.queries)
+	}
+
+	results, err = util.AwaitAll(queryResolver.Query(testContext, &selector{"b key"}, resolvers.QueryOpts{}))
+
+	if err != nil || len(results) != 1 || results[0].(map[string]interface{})["value"] != "3" {
+		t.Fatal("TestHttpResolver: keyed Query failed:", results, err)
+	}
+
+	registry.failures = 1
+
+	if _, err := resolver.Get(testContext, &selector{"a key"}); !resolvers.IsRetryable(err) {
+		t.Fatal("TestHttpResolver: expected a retryable error got:", err)
+	}
+
+	if err := resolver.Delete(testContext, &selector{"a key"}); err != nil {
+		t.Fatal("TestHttpResolver: Delete failed:", err)
+	}
+
+	if _, err := resolver.Get(testContext, &selector{"a key"}); err == nil {
+		t.Fatal("TestHttpResolver: expected deleted entity to be missing")
+	}
+}
+
+// typedEntity has only unexported fields, so it must be translated to be
+// sent
+type typedEntity struct {
+	key   string
+	value string
+}
+
+type untranslatedEntity struct {
+	key string
+}
+
+func typedExtractor(e ...interface{}) (interface{}, bool) {
+	switch entity := e[0].(type) {
+	case typedEntity:
+		return entity.key, true
+	case untranslatedEntity:
+		return entity.key, true
+	}
+
+	return nil, false
+}
+
+func TestHttpResolverTypedEntity(t *testing.T) {
+	registry := newRegistry()
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	entityType := gotypeid.IdOf(reflect.TypeOf(typedEntity{}))
+	mapType := gotypeid.IdOf(reflect.TypeOf(map[string]interface{}{}))
+
+	translators.Register(testContext, entityType, mapType, func(translationContext context.Context, fromId ids.Identifier, fromValue interface{}) (chan interface{}, chan error) {
+		entity := fromValue.(typedEntity)
+		return util.Resolved[interface{}](map[string]interface{}{"key": entity.key, "value": entity.value}).Channels()
+	})
+
+	resolver, err := httpresolver.New(server.URL+"/entities/", httpresolver.NewResolverInfo(
+		[]ids.TypeIdentifier{entityType}, nil, typedExtractor, map[interface{}]interface{}{
+			"headers": map[string]interface{}{"Authorization": "token"}}))
+
+	if err != nil {
+		t.Fatal("TestHttpResolverTypedEntity: New failed:", err)
+	}
+
+	if _, err := resolver.Put(testContext, typedEntity{"typed", "value"}); err != nil {
+		t.Fatal("TestHttpResolverTypedEntity: Put failed:", err)
+	}
+
+	if stored := registry.entities["typed"]; stored["value"] != "value" {
+		t.Fatal("TestHttpResolverTypedEntity: expected translated entity to be sent got:", stored)
+	}
+
+	untyped, _ := httpresolver.New(server.URL+"/entities/", httpresolver.NewResolverInfo(
+		nil, nil, typedExtractor, map[interface{}]interface{}{
+			"headers": map[string]interface{}{"Authorization": "token"}}))
+
+	if _, err := untyped.Put(testContext, typedEntity{"untyped", "value"}); err != nil || registry.entities["untyped"]["value"] != "value" {
+		t.Fatal("TestHttpResolverTypedEntity: expected entity to be translated from its own type, got:", err)
+	}
+
+	if _, err := untyped.Put(testContext, untranslatedEntity{"untranslated"}); !errors.Is(err, translators.ErrNoTranslator) {
+		t.Fatal("TestHttpResolverTypedEntity: expected entity without a translator to fail, got:", err)
+	}
+
+	if _, ok := registry.entities["untranslated"]; ok {
+		t.Fatal("TestHttpResolverTypedEntity: untranslated entity should not be sent")
+	}
+}
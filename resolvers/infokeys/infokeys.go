@@ -2,4 +2,54 @@ package infokeys
 
 const (
 	CACHE_SIZE int = iota
+	RESOLVE_STRATEGY
+	PRIORITY
+	HEDGE_DELAY
+	QUORUM
+	CACHE_TTL
+	NEGATIVE_CACHE_SIZE
+	NEGATIVE_CACHE_TTL
+	TIMEOUT
+	RETRIES
+	RETRY_BACKOFF
+	BREAKER_THRESHOLD
+	BREAKER_COOLDOWN
+	BATCH_PARALLELISM
 )
+
+// Name returns the key used for an info value when it is declared in a json
+// resolver configuration
+func Name(key int) string {
+	switch key {
+	case CACHE_SIZE:
+		return "cacheSize"
+	case RESOLVE_STRATEGY:
+		return "resolveStrategy"
+	case PRIORITY:
+		return "priority"
+	case HEDGE_DELAY:
+		return "hedgeDelay"
+	case QUORUM:
+		return "quorum"
+	case CACHE_TTL:
+		return "cacheTTL"
+	case NEGATIVE_CACHE_SIZE:
+		return "negativeCacheSize"
+	case NEGATIVE_CACHE_TTL:
+		return "negativeCacheTTL"
+	case TIMEOUT:
+		return "timeout"
+	case RETRIES:
+		return "retries"
+	case RETRY_BACKOFF:
+		return "retryBackoff"
+	case BREAKER_THRESHOLD:
+		return "breakerThreshold"
+	case BREAKER_COOLDOWN:
+		return "breakerCooldown"
+	case BATCH_PARALLELISM:
+		return "batchParallelism"
+	default:
+		return ""
+	}
+}
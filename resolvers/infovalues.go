@@ -0,0 +1,76 @@
+package resolvers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/distributed-vision/go-resources/resolvers/infokeys"
+	"github.com/distributed-vision/go-resources/resolvers/strategytype"
+)
+
+func infoValue(info ResolverInfo, key int) interface{} {
+	if info == nil {
+		return nil
+	}
+
+	if value := info.Value(key); value != nil {
+		return value
+	}
+
+	if name := infokeys.Name(key); name != "" {
+		return info.Value(name)
+	}
+
+	return nil
+}
+
+func intInfoValue(info ResolverInfo, key int, defaultValue int) int {
+	switch value := infoValue(info, key).(type) {
+	case int:
+		return value
+	case int64:
+		return int(value)
+	case float64:
+		return int(value)
+	case string:
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+	}
+
+	return defaultValue
+}
+
+// durationInfoValue reads a duration from an info value, numeric values
+// are taken to be milliseconds
+func durationInfoValue(info ResolverInfo, key int, defaultValue time.Duration) time.Duration {
+	switch value := infoValue(info, key).(type) {
+	case time.Duration:
+		return value
+	case int:
+		return time.Duration(value) * time.Millisecond
+	case int64:
+		return time.Duration(value) * time.Millisecond
+	case float64:
+		return time.Duration(value * float64(time.Millisecond))
+	case string:
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+
+	return defaultValue
+}
+
+func strategyInfoValue(info ResolverInfo, key int, defaultValue strategytype.StrategyType) strategytype.StrategyType {
+	switch value := infoValue(info, key).(type) {
+	case strategytype.StrategyType:
+		return value
+	case string:
+		if strategy, err := strategytype.Parse(value); err == nil {
+			return strategy
+		}
+	}
+
+	return defaultValue
+}
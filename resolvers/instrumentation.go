@@ -0,0 +1,78 @@
+package resolvers
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/translators"
+)
+
+// Operations reported to Instrumentation.StartSpan
+const (
+	OP_RESOLVE     = "resolve"
+	OP_QUERY       = "query"
+	OP_TRANSLATE   = "translate"
+	OP_INSTANTIATE = "instantiate"
+)
+
+// Span is ended with the error, if any, of the operation it measures
+type Span interface {
+	End(err error)
+}
+
+// Instrumentation receives the spans around component calls, translations
+// and resolver instantiation, and the outcome of resolver cache lookups.
+// The subject of a span is the resolver type of the component, or the type
+// being translated to or instantiated
+type Instrumentation interface {
+	StartSpan(spanContext context.Context, operation string, subject ids.TypeIdentifier) (context.Context, Span)
+	CacheLookup(resolverType ids.TypeIdentifier, hit bool)
+}
+
+type nopSpan struct{}
+
+func (nopSpan) End(err error) {}
+
+type nopInstrumentation struct{}
+
+func (nopInstrumentation) StartSpan(spanContext context.Context, operation string, subject ids.TypeIdentifier) (context.Context, Span) {
+	return spanContext, nopSpan{}
+}
+
+func (nopInstrumentation) CacheLookup(resolverType ids.TypeIdentifier, hit bool) {}
+
+type instrumentationHolder struct {
+	Instrumentation
+}
+
+var instrumentation atomic.Value
+
+func init() {
+	instrumentation.Store(instrumentationHolder{nopInstrumentation{}})
+}
+
+// SetInstrumentation installs the instrumentation used by all resolvers and
+// translators, passing nil removes it
+func SetInstrumentation(value Instrumentation) {
+	if value == nil {
+		translators.SetSpanStarter(nil)
+		value = nopInstrumentation{}
+	} else {
+		translators.SetSpanStarter(func(spanContext context.Context, toType ids.TypeIdentifier) (context.Context, translators.Span) {
+			return value.StartSpan(spanContext, OP_TRANSLATE, toType)
+		})
+	}
+
+	instrumentation.Store(instrumentationHolder{value})
+}
+
+func GetInstrumentation() Instrumentation {
+	return instrumentation.Load().(instrumentationHolder).Instrumentation
+}
+
+// IsInstrumented returns true if instrumentation has been installed
+func IsInstrumented() bool {
+	_, nop := GetInstrumentation().(nopInstrumentation)
+	return !nop
+}
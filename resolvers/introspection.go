@@ -0,0 +1,362 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/resolvers/contextkeys"
+	"github.com/distributed-vision/go-resources/resolvers/infokeys"
+	"github.com/distributed-vision/go-resources/resolvers/outcometype"
+	"github.com/distributed-vision/go-resources/resolvers/strategytype"
+)
+
+// ComponentDescription describes a component entry of a composite resolver.
+// Nested holds the description of components which are themselves composite
+// resolvers
+type ComponentDescription struct {
+	EntityType        ids.TypeIdentifier
+	ResolverType      ids.TypeIdentifier
+	ResolvableTypes   []ids.TypeIdentifier
+	ResolvableDomains []ids.Domain
+	Values            map[interface{}]interface{}
+	Priority          int
+	Instantiated      bool
+	State             ComponentState
+	Nested            *Description
+}
+
+type CacheDescription struct {
+	Capacity int
+	Keys     []string
+	Hits     int64
+	Misses   int64
+	NotFound int
+}
+
+// Description is a snapshot of a composite resolver's configuration, its
+// components and the state of its cache
+type Description struct {
+	ResolverType ids.TypeIdentifier
+	Strategy     strategytype.StrategyType
+	Components   []ComponentDescription
+	Cache        CacheDescription
+}
+
+// infoValues collects the values visible through info, values set on
+// derived infos hide those of the infos they were derived from
+func infoValues(info ResolverInfo) map[interface{}]interface{} {
+	values := make(map[interface{}]interface{})
+
+	for info, ok := info.(*resolverInfo); ok && info != nil; info, ok = info.parent.(*resolverInfo) {
+		for key, value := range info.values {
+			if _, ok := values[key]; !ok && value != nil {
+				values[key] = value
+			}
+		}
+	}
+
+	return values
+}
+
+func (this *CachingResolver) describeCache() CacheDescription {
+	keys := []string{}
+
+	for _, key := range this.cache.Keys() {
+		keys = append(keys, fmt.Sprint(key))
+	}
+
+	sort.Strings(keys)
+
+	description := CacheDescription{
+		Capacity: this.cacheSize,
+		Keys:     keys,
+		Hits:     atomic.LoadInt64(&this.hits),
+		Misses:   atomic.LoadInt64(&this.misses)}
+
+	if this.notFound != nil {
+		description.NotFound = this.notFound.Len()
+	}
+
+	return description
+}
+
+// Describe returns a snapshot of the resolver's components, in the order they
+// are consulted for each entity type, and of its cache
+func (this *CompositeResolver) Describe() Description {
+	return this.describe(map[*CompositeResolver]bool{})
+}
+
+func (this *CompositeResolver) describe(described map[*CompositeResolver]bool) Description {
+	described[this] = true
+
+	this.componentMapMutex.Lock()
+	entries := []*componentEntry{}
+	for _, typeEntries := range this.componentMap {
+		entries = append(entries, typeEntries...)
+	}
+	this.componentMapMutex.Unlock()
+
+	sortByPriority(entries)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].entityType.String() < entries[j].entityType.String()
+	})
+
+	components := make([]ComponentDescription, 0, len(entries))
+
+	for _, entry := range entries {
+		info := entry.ResolverInfo()
+		resolver := entry.current()
+
+		component := ComponentDescription{
+			EntityType:        entry.entityType,
+			ResolverType:      info.ResolverType(),
+			ResolvableTypes:   info.ResolvableTypes(),
+			ResolvableDomains: info.ResolvableDomains(),
+			Values:            infoValues(info),
+			Priority:          intInfoValue(info, infokeys.PRIORITY, 0),
+			Instantiated:      resolver != nil,
+			State:             entry.breaker.snapshot()}
+
+		component.State.ResolverType = component.ResolverType
+		component.State.EntityType = entry.entityType
+
+		if composite, ok := resolver.(*CompositeResolver); ok && !described[composite] {
+			nested := composite.describe(described)
+			component.Nested = &nested
+		}
+
+		components = append(components, component)
+	}
+
+	return Description{
+		ResolverType: this.ResolverInfo().ResolverType(),
+		Strategy:     strategyInfoValue(this.ResolverInfo(), infokeys.RESOLVE_STRATEGY, strategytype.RACE),
+		Components:   components,
+		Cache:        this.describeCache()}
+}
+
+func describeValues(values map[interface{}]interface{}) string {
+	pairs := make([]string, 0, len(values))
+
+	for key, value := range values {
+		if intKey, ok := key.(int); ok && infokeys.Name(intKey) != "" {
+			key = infokeys.Name(intKey)
+		}
+
+		pairs = append(pairs, fmt.Sprintf("%v=%v", key, value))
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ", ")
+}
+
+func (this Description) write(builder *strings.Builder, indent string) {
+	fmt.Fprintf(builder, "%sresolver %v strategy=%v\n", indent, this.ResolverType, this.Strategy)
+	fmt.Fprintf(builder, "%s  cache: %d/%d entries, %d hits, %d misses, %d not found\n", indent,
+		len(this.Cache.Keys), this.Cache.Capacity, this.Cache.Hits, this.Cache.Misses, this.Cache.NotFound)
+
+	for _, component := range this.Components {
+		fmt.Fprintf(builder, "%s  component %v for %v priority=%d instantiated=%t breaker=%v\n", indent,
+			component.ResolverType, component.EntityType, component.Priority, component.Instantiated, component.State.State)
+
+		if len(component.ResolvableDomains) > 0 {
+			fmt.Fprintf(builder, "%s    domains: %v\n", indent, component.ResolvableDomains)
+		}
+
+		if len(component.Values) > 0 {
+			fmt.Fprintf(builder, "%s    values: %s\n", indent, describeValues(component.Values))
+		}
+
+		if component.Nested != nil {
+			component.Nested.write(builder, indent+"    ")
+		}
+	}
+}
+
+// String formats the description as a multi line diagnostic dump
+func (this Description) String() string {
+	builder := &strings.Builder{}
+	this.write(builder, "")
+	return builder.String()
+}
+
+// Attempt records the consultation of a component during Explain. Started
+// is the attempt's offset from the start of the explanation
+type Attempt struct {
+	ResolverType ids.TypeIdentifier
+	EntityType   ids.TypeIdentifier
+	Outcome      outcometype.OutcomeType
+	Tries        int
+	Started      time.Duration
+	Duration     time.Duration
+	Result       interface{}
+	Err          error
+	explanation  *Explanation
+}
+
+// Explanation reports how a composite resolver resolved a selector. Attempts
+// are listed in the order they were started, attempts which were still
+// running when the resolve completed have a PENDING outcome
+type Explanation struct {
+	Selector       Selector
+	Strategy       strategytype.StrategyType
+	Cached         bool
+	CachedNotFound bool
+	Attempts       []Attempt
+	Result         interface{}
+	Err            error
+	resolver       *CompositeResolver
+	start          time.Time
+	mutex          sync.Mutex
+	attempts       []*Attempt
+}
+
+func (this *CompositeResolver) explanation(resolutionContext context.Context) *Explanation {
+	if explanation, ok := resolutionContext.Value(contextkeys.EXPLANATION).(*Explanation); ok && explanation.resolver == this {
+		return explanation
+	}
+
+	return nil
+}
+
+func (this *Explanation) begin(entry *componentEntry, now time.Time) *Attempt {
+	if this == nil {
+		return nil
+	}
+
+	attempt := &Attempt{
+		ResolverType: entry.ResolverInfo().ResolverType(),
+		EntityType:   entry.entityType,
+		Started:      now.Sub(this.start),
+		explanation:  this}
+
+	this.mutex.Lock()
+	this.attempts = append(this.attempts, attempt)
+	this.mutex.Unlock()
+
+	return attempt
+}
+
+func (this *Attempt) end(resolutionContext context.Context, result *componentResult, tries int, now time.Time) {
+	outcome := outcometype.FAILED
+
+	switch notFound := IsNotFound(result.err); {
+	case result.err == nil:
+		outcome = outcometype.FOUND
+	case tries == 0:
+		outcome = outcometype.REJECTED
+	case notFound:
+		outcome = outcometype.NOT_FOUND
+	case resolutionContext.Err() != nil:
+		outcome = outcometype.CANCELLED
+	}
+
+	this.explanation.mutex.Lock()
+	defer this.explanation.mutex.Unlock()
+
+	this.Outcome = outcome
+	this.Tries = tries
+	this.Duration = now.Sub(this.explanation.start) - this.Started
+	this.Result = result.result
+	this.Err = result.err
+}
+
+// Explain resolves selector as Resolve does and records the route taken,
+// whether the result came from the cache and, if not, which components were
+// tried, in what order and with what outcome. Explain doesn't share in
+// concurrent resolutions of the same key
+func (this *CompositeResolver) Explain(resolutionContext context.Context, selector Selector) *Explanation {
+	explanation := &Explanation{
+		Selector: selector,
+		Strategy: strategyInfoValue(this.ResolverInfo(), infokeys.RESOLVE_STRATEGY, strategytype.RACE),
+		resolver: this,
+		start:    this.clock.Now()}
+
+	if selector == nil {
+		explanation.Err = fmt.Errorf("Resolve Failed: selector cannot be nil")
+		return explanation
+	}
+
+	if shareable(resolutionContext) {
+		if result, err := this.CachingResolver.Get(resolutionContext, selector); err == nil {
+			explanation.Cached = true
+			explanation.Result = result
+			return explanation
+		}
+
+		if err := this.cachedNotFound(selector); err != nil {
+			explanation.CachedNotFound = true
+			explanation.Err = err
+			return explanation
+		}
+	}
+
+	resolverEntries, err := this.matchingEntries(selector)
+
+	if err == nil && len(resolverEntries) == 0 {
+		err = NewNoResolverForType(selector.Type())
+	}
+
+	if err != nil {
+		explanation.Err = err
+		return explanation
+	}
+
+	sortByPriority(resolverEntries)
+
+	explanation.Result, explanation.Err = this.resolveEntries(
+		context.WithValue(resolutionContext, contextkeys.EXPLANATION, explanation), resolverEntries, selector)
+
+	explanation.mutex.Lock()
+	for _, attempt := range explanation.attempts {
+		explanation.Attempts = append(explanation.Attempts, *attempt)
+	}
+	explanation.mutex.Unlock()
+
+	return explanation
+}
+
+// String formats the explanation as a multi line diagnostic dump
+func (this *Explanation) String() string {
+	builder := &strings.Builder{}
+
+	key := interface{}(nil)
+	if this.Selector != nil {
+		key = this.Selector.Key()
+	}
+
+	fmt.Fprintf(builder, "explain key=%v strategy=%v\n", key, this.Strategy)
+
+	switch {
+	case this.Cached:
+		fmt.Fprintf(builder, "  cache hit\n")
+	case this.CachedNotFound:
+		fmt.Fprintf(builder, "  cached not found\n")
+	}
+
+	for index, attempt := range this.Attempts {
+		fmt.Fprintf(builder, "  %d. %v for %v: %v after %d tries, started %v took %v",
+			index+1, attempt.ResolverType, attempt.EntityType, attempt.Outcome, attempt.Tries, attempt.Started, attempt.Duration)
+
+		if attempt.Err != nil {
+			fmt.Fprintf(builder, ": %v", attempt.Err)
+		}
+
+		builder.WriteString("\n")
+	}
+
+	if this.Err != nil {
+		fmt.Fprintf(builder, "  error: %v\n", this.Err)
+	} else {
+		fmt.Fprintf(builder, "  result: %v\n", this.Result)
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,85 @@
+package resolvers_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/infokeys"
+	"github.com/distributed-vision/go-resources/resolvers/outcometype"
+	"github.com/distributed-vision/go-resources/resolvers/strategytype"
+)
+
+func TestExplain(t *testing.T) {
+	missing := newStubResolver(2, 0, "", resolvers.NewEntityNotFound("missing", nil))
+	found := newStubResolver(1, 0, "found", nil)
+	unused := newStubResolver(0, 0, "unused", nil)
+
+	resolver := newStrategyResolver(t,
+		testInfo.WithValue(infokeys.RESOLVE_STRATEGY, strategytype.ORDERED),
+		unused, found, missing)
+
+	explanation := resolver.Explain(testContext, &typedSelector{key: "explained"})
+
+	if explanation.Err != nil || explanation.Result.(entity).value != "found" || explanation.Cached {
+		t.Fatal("TestExplain: unexpected result:", explanation)
+	}
+
+	if explanation.Strategy != strategytype.ORDERED || len(explanation.Attempts) != 2 {
+		t.Fatal("TestExplain: expected two ordered attempts, got:", explanation)
+	}
+
+	if attempt := explanation.Attempts[0]; attempt.Outcome != outcometype.NOT_FOUND || attempt.Tries != 1 || attempt.Err == nil {
+		t.Fatal("TestExplain: expected first attempt to be not found, got:", attempt)
+	}
+
+	if attempt := explanation.Attempts[1]; attempt.Outcome != outcometype.FOUND || attempt.Result.(entity).value != "found" {
+		t.Fatal("TestExplain: expected second attempt to be found, got:", attempt)
+	}
+
+	if !strings.Contains(explanation.String(), "not-found after 1 tries") {
+		t.Fatal("TestExplain: unexpected dump:", explanation)
+	}
+
+	if explanation = resolver.Explain(testContext, &typedSelector{key: "explained"}); !explanation.Cached || len(explanation.Attempts) != 0 {
+		t.Fatal("TestExplain: expected a cache hit, got:", explanation)
+	}
+
+	explanation = newStrategyResolver(t, testInfo, missing).Explain(testContext, &typedSelector{key: "explained"})
+
+	if _, ok := explanation.Err.(*resolvers.EntityNotFound); !ok || len(explanation.Attempts) != 1 {
+		t.Fatal("TestExplain: expected not found, got:", explanation)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	resolver := newStrategyResolver(t, testInfo.WithValue(infokeys.CACHE_SIZE, 10),
+		newStubResolver(1, 0, "low", nil),
+		newStubResolver(5, 0, "high", nil))
+
+	if _, err := resolver.Get(testContext, &typedSelector{key: "described"}); err != nil {
+		t.Fatal("TestDescribe: Get failed:", err)
+	}
+
+	resolver.Get(testContext, &typedSelector{key: "described"})
+
+	description := resolver.Describe()
+
+	if len(description.Components) != 2 {
+		t.Fatal("TestDescribe: expected two components, got:", description)
+	}
+
+	if high := description.Components[0]; high.Priority != 5 || !high.Instantiated || !high.EntityType.Equals(testEntityType) ||
+		high.Values[infokeys.PRIORITY] != 5 {
+		t.Fatal("TestDescribe: expected the high priority component first, got:", high)
+	}
+
+	if description.Cache.Capacity != 10 || len(description.Cache.Keys) != 1 || description.Cache.Keys[0] != "described" ||
+		description.Cache.Hits != 1 || description.Cache.Misses != 1 {
+		t.Fatal("TestDescribe: unexpected cache description:", description.Cache)
+	}
+
+	if dump := description.String(); !strings.Contains(dump, "1/10 entries, 1 hits, 1 misses") || !strings.Contains(dump, "priority=5") {
+		t.Fatal("TestDescribe: unexpected dump:", dump)
+	}
+}
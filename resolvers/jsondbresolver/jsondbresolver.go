@@ -0,0 +1,597 @@
+package jsondbresolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/distributed-vision/go-resources/encoding/encodertype"
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/ids/domain"
+	"github.com/distributed-vision/go-resources/ids/identifier"
+	"github.com/distributed-vision/go-resources/ids/mappings"
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/changetype"
+	"github.com/distributed-vision/go-resources/resolvers/operationtype"
+	"github.com/distributed-vision/go-resources/translators"
+	"github.com/distributed-vision/go-resources/types"
+	"github.com/distributed-vision/go-resources/types/gotypeid"
+	"github.com/distributed-vision/go-resources/types/publictypeid"
+	"github.com/distributed-vision/go-resources/util"
+	"github.com/distributed-vision/go-resources/util/jsondb"
+	"github.com/distributed-vision/go-resources/version"
+)
+
+var contentType ids.TypeIdentifier = gotypeid.IdOf(reflect.TypeOf(map[string]interface{}{}))
+
+var resolverType ids.TypeIdentifier = gotypeid.IdOf(reflect.TypeOf(JsonDbResolver{}))
+var publicTypeVersion = version.New(0, 0, 1)
+
+var PublicType = types.MustNewId(publictypeid.ResolverDomain, []byte("JsonDbResolver"), publicTypeVersion)
+
+var resolverMap map[string]*JsonDbResolver = make(map[string]*JsonDbResolver)
+var resolverMapMutex = &sync.Mutex{}
+
+func init() {
+	mappings.Map(context.Background(), resolverType, PublicType)
+	resolvers.ResisterNewFactoryFunction(PublicType, NewResolverFactory)
+}
+
+func ResolverType() ids.TypeIdentifier {
+	return resolverType
+}
+
+func NewResolverInfo(resolvableTypes []ids.TypeIdentifier, resolvableDomains []ids.Domain,
+	keyExtractor resolvers.KeyExtractor, values map[interface{}]interface{}) resolvers.ResolverInfo {
+	return resolvers.NewResolverInfo(PublicType,
+		resolvableTypes, resolvableDomains, keyExtractor, values)
+}
+
+type factory struct {
+	resolverInfo resolvers.ResolverInfo
+}
+
+func NewResolverFactory(resolverInfo resolvers.ResolverInfo) (resolvers.ResolverFactory, error) {
+	return &factory{resolverInfo}, nil
+}
+
+func (this *factory) New(resolutionContext context.Context) (resolvers.Resolver, error) {
+	location, ok := this.resolverInfo.Value("location").(string)
+
+	if !ok || location == "" {
+		return nil, fmt.Errorf("ResolverInfo 'location' value can't be empty")
+	}
+
+	return New(location, this.resolverInfo)
+}
+
+func (this *factory) ResolverType() ids.TypeIdentifier {
+	return resolverType
+}
+
+func (this *factory) ResolverInfo() resolvers.ResolverInfo {
+	return this.resolverInfo
+}
+
+// JsonDbResolver is a MutableResolver which persists entities in a jsondb
+// file. Entities are stored as json maps, using a translator from one of
+// the resolver's resolvable types, and are translated back to the
+// selector's type when they are resolved
+type JsonDbResolver struct {
+	*resolvers.ChangeFeed
+	path         string
+	resolverInfo resolvers.ResolverInfo
+	db           *jsondb.JsonDb
+	mutex        *sync.Mutex
+	entities     map[string]decodedEntity
+}
+
+// decodedEntity caches the translation of the json entity set by the write
+// with index
+type decodedEntity struct {
+	index  uint
+	entity interface{}
+}
+
+// New opens the database at locator, which is resolved against the
+// resolver info's "paths" value if it has one. Resolvers are shared by
+// location, as a database file can only be opened once, so opening a
+// location again with different resolvable types or key extractor fails
+func New(locator string, resolverInfo resolvers.ResolverInfo) (*JsonDbResolver, error) {
+	if resolverInfo == nil {
+		return nil, fmt.Errorf("base resolver info must be defined")
+	}
+
+	filePath := locate(locator, resolverInfo)
+
+	resolverMapMutex.Lock()
+	defer resolverMapMutex.Unlock()
+
+	if resolver, ok := resolverMap[filePath]; ok {
+		if !sameEntities(resolver.resolverInfo, resolverInfo) {
+			return nil, fmt.Errorf("Database %s is already open with different resolvable types or key extractor", filePath)
+		}
+
+		return resolver, nil
+	}
+
+	db := jsondb.NewJsonDb(util.NewFileStorage(filePath, 0644), true)
+
+	if err := util.AwaitError(db.Open()); err != nil {
+		return nil, err
+	}
+
+	resolver := &JsonDbResolver{
+		ChangeFeed:   &resolvers.ChangeFeed{},
+		path:         filePath,
+		resolverInfo: resolverInfo,
+		db:           db,
+		mutex:        &sync.Mutex{},
+		entities:     make(map[string]decodedEntity)}
+
+	resolverMap[filePath] = resolver
+
+	return resolver, nil
+}
+
+// sameEntities returns true if info and other resolve the same types with the
+// same key extractor, so that a database shared between them stores the same
+// entities
+func sameEntities(info resolvers.ResolverInfo, other resolvers.ResolverInfo) bool {
+	types, otherTypes := info.ResolvableTypes(), other.ResolvableTypes()
+
+	if len(types) != len(otherTypes) {
+		return false
+	}
+
+	for _, entityType := range types {
+		found := false
+
+		for _, otherType := range otherTypes {
+			if entityType.Equals(otherType) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return reflect.ValueOf(info.KeyExtractor()).Pointer() == reflect.ValueOf(other.KeyExtractor()).Pointer()
+}
+
+func locate(file string, resolverInfo resolvers.ResolverInfo) string {
+	paths, ok := resolverInfo.Value("paths").([]string)
+
+	if !ok || len(paths) == 0 || path.IsAbs(file) {
+		return file
+	}
+
+	for _, respath := range paths {
+		if _, err := os.Stat(path.Join(respath, file)); err == nil {
+			return path.Join(respath, file)
+		}
+	}
+
+	return path.Join(paths[0], file)
+}
+
+func (this *JsonDbResolver) ResolverInfo() resolvers.ResolverInfo {
+	return this.resolverInfo
+}
+
+// Close closes the database, the resolver can't be used once it is closed
+func (this *JsonDbResolver) Close() error {
+	resolverMapMutex.Lock()
+	delete(resolverMap, this.path)
+	resolverMapMutex.Unlock()
+
+	return util.AwaitError(this.db.Close())
+}
+
+// encode converts an entity to the json map it is stored as, entities
+// which no translator converts fail with NoTranslator
+func (this *JsonDbResolver) encode(encodeContext context.Context, entity interface{}) (interface{}, error) {
+	return translators.ToMap(encodeContext, entity, this.resolverInfo.ResolvableTypes()...)
+}
+
+var untypedLocalDomain []byte = domain.MustDecodeId(encodertype.BASE62, "3", "")
+
+// decode translates the json entity stored with key by the write with index
+// to targetType
+func (this *JsonDbResolver) decode(decodeContext context.Context, key string, index uint, jsonEntity interface{}, targetType ids.TypeIdentifier) (interface{}, error) {
+	if targetType == nil || targetType.Equals(contentType) || !translators.Exists(contentType, targetType) {
+		return jsonEntity, nil
+	}
+
+	this.mutex.Lock()
+	decoded, ok := this.entities[key]
+	this.mutex.Unlock()
+
+	if ok && decoded.index == index {
+		return decoded.entity, nil
+	}
+
+	entityId, err := identifier.New(untypedLocalDomain, []byte(key), nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	decodeContext = context.WithValue(decodeContext, "resolverInfo", this.resolverInfo)
+	entity, err := translators.TranslateFuture(decodeContext, contentType, entityId, jsonEntity, targetType).Await()
+
+	if err != nil {
+		return nil, err
+	}
+
+	this.mutex.Lock()
+	this.entities[key] = decodedEntity{index, entity}
+	this.mutex.Unlock()
+
+	return entity, nil
+}
+
+func (this *JsonDbResolver) Get(resolutionContext context.Context, selector resolvers.Selector) (interface{}, error) {
+	entity, _, err := this.find(resolutionContext, selector)
+	return entity, err
+}
+
+// GetRevision returns the entity selected by selector with its revision,
+// which is the index of the write which stored it
+func (this *JsonDbResolver) GetRevision(resolutionContext context.Context, selector resolvers.Selector) (interface{}, resolvers.Revision, error) {
+	entity, index, err := this.find(resolutionContext, selector)
+
+	if err != nil {
+		return nil, resolvers.NoRevision, err
+	}
+
+	return entity, toRevision(index), nil
+}
+
+// find returns the entity selected by selector with the index of the write
+// which stored it
+func (this *JsonDbResolver) find(resolutionContext context.Context, selector resolvers.Selector) (interface{}, uint, error) {
+	lookup := func(key string) (interface{}, uint, bool, error) {
+		jsonEntity, index, ok := this.db.GetIndexed(key)
+
+		if !ok {
+			return nil, 0, false, nil
+		}
+
+		entity, err := this.decode(resolutionContext, key, index, jsonEntity, selector.Type())
+
+		if err != nil {
+			return nil, 0, false, err
+		}
+
+		return entity, index, selector.Test(entity), nil
+	}
+
+	if key := resolvers.KeyString(selector.Key()); key != "" {
+		if entity, index, ok, err := lookup(key); err != nil || ok {
+			return entity, index, err
+		}
+	}
+
+	var found string
+	var err error
+
+	this.db.ForEachIndexed(func(key string, jsonEntity interface{}, index uint) {
+		if found != "" || err != nil {
+			return
+		}
+
+		var entity interface{}
+
+		if entity, err = this.decode(resolutionContext, key, index, jsonEntity, selector.Type()); err == nil && selector.Test(entity) {
+			found = key
+		}
+	})
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// the entity is looked up again, as it may have been written since it
+	// was found
+	if found != "" {
+		if entity, index, ok, err := lookup(found); err != nil || ok {
+			return entity, index, err
+		}
+	}
+
+	return nil, 0, resolvers.NewEntityNotFound(fmt.Sprintf("Can't resolve entity for %v", selector), nil)
+}
+
+func (this *JsonDbResolver) Resolve(resolutionContext context.Context, selector resolvers.Selector) (chan interface{}, chan error) {
+	cres, cerr := make(chan interface{}, 1), make(chan error, 1)
+
+	go func() {
+		entity, err := this.Get(resolutionContext, selector)
+
+		if err != nil {
+			cerr <- err
+		} else {
+			cres <- entity
+		}
+
+		close(cres)
+		close(cerr)
+	}()
+
+	return cres, cerr
+}
+
+func (this *JsonDbResolver) Query(queryContext context.Context, selector resolvers.Selector, opts resolvers.QueryOpts) (<-chan interface{}, <-chan error) {
+	stream := resolvers.NewQueryStream(queryContext, opts, this.resolverInfo.KeyExtractor())
+
+	go func() {
+		var err error
+		done := false
+
+		this.db.ForEachIndexed(func(key string, jsonEntity interface{}, index uint) {
+			if done || err != nil {
+				return
+			}
+
+			var entity interface{}
+
+			if entity, err = this.decode(queryContext, key, index, jsonEntity, selector.Type()); err == nil && selector.Test(entity) {
+				done = !stream.AddKeyed(key, entity)
+			}
+		})
+
+		stream.Close(err)
+	}()
+
+	return stream.Results()
+}
+
+func (this *JsonDbResolver) write(resolutionContext context.Context, entity interface{}, mustExist bool) (interface{}, error) {
+	key, ok := this.resolverInfo.KeyExtractor()(entity)
+
+	if !ok {
+		return nil, fmt.Errorf("Cannot extract key from: %v", entity)
+	}
+
+	jsonEntity, err := this.encode(resolutionContext, entity)
+
+	if err != nil {
+		return nil, err
+	}
+
+	keyString := resolvers.KeyString(key)
+	_, index, exists := this.db.GetIndexed(keyString)
+
+	// the entity is set if it hasn't been written since it was checked, so
+	// that a concurrent delete can't be overwritten by a Post
+	for {
+		if mustExist && !exists {
+			return nil, resolvers.NewEntityNotFound(fmt.Sprintf("Can't resolve entity for %v", key), nil)
+		}
+
+		current, cerr := this.db.SetIfIndex(keyString, jsonEntity, index)
+		err := util.AwaitError(cerr)
+
+		if err == nil {
+			break
+		}
+
+		if !errors.Is(err, jsondb.ErrIndexMismatch) {
+			return nil, err
+		}
+
+		index, exists = current, current != 0
+	}
+
+	this.mutex.Lock()
+	delete(this.entities, keyString)
+	this.mutex.Unlock()
+
+	if exists {
+		this.Publish(changetype.UPDATE, key, entity)
+	} else {
+		this.Publish(changetype.PUT, key, entity)
+	}
+
+	return entity, nil
+}
+
+func (this *JsonDbResolver) Put(resolutionContext context.Context, entity interface{}) (interface{}, error) {
+	return this.write(resolutionContext, entity, false)
+}
+
+func (this *JsonDbResolver) Post(resolutionContext context.Context, entity interface{}) (interface{}, error) {
+	return this.write(resolutionContext, entity, true)
+}
+
+func (this *JsonDbResolver) Delete(resolutionContext context.Context, selector resolvers.Selector) error {
+	key := resolvers.KeyString(selector.Key())
+
+	if err := util.AwaitError(this.db.Delete(key)); err != nil {
+		return err
+	}
+
+	this.mutex.Lock()
+	entity := this.entities[key].entity
+	delete(this.entities, key)
+	this.mutex.Unlock()
+
+	this.Publish(changetype.DELETE, selector.Key(), entity)
+
+	return nil
+}
+
+func toRevision(index uint) resolvers.Revision {
+	if index == 0 {
+		return resolvers.NoRevision
+	}
+
+	return resolvers.Revision(strconv.FormatUint(uint64(index), 10))
+}
+
+// PostIfMatch writes entity if its stored revision is revision
+func (this *JsonDbResolver) PostIfMatch(resolutionContext context.Context, entity interface{}, revision resolvers.Revision) (resolvers.Revision, error) {
+	key, ok := this.resolverInfo.KeyExtractor()(entity)
+
+	if !ok {
+		return resolvers.NoRevision, fmt.Errorf("Cannot extract key from: %v", entity)
+	}
+
+	var expected uint64
+
+	if revision != resolvers.NoRevision {
+		var err error
+
+		if expected, err = strconv.ParseUint(string(revision), 10, 0); err != nil || expected == 0 {
+			return resolvers.NoRevision, resolvers.NewConflict(fmt.Sprintf("Post Failed: invalid revision %s for entity %v", revision, key), err)
+		}
+	}
+
+	jsonEntity, err := this.encode(resolutionContext, entity)
+
+	if err != nil {
+		return resolvers.NoRevision, err
+	}
+
+	keyString := resolvers.KeyString(key)
+	index, cerr := this.db.SetIfIndex(keyString, jsonEntity, uint(expected))
+
+	if err := util.AwaitError(cerr); err != nil {
+		if errors.Is(err, jsondb.ErrIndexMismatch) {
+			return resolvers.NoRevision, resolvers.NewRevisionConflict(key, revision, toRevision(index))
+		}
+
+		return resolvers.NoRevision, err
+	}
+
+	this.mutex.Lock()
+	delete(this.entities, keyString)
+	this.mutex.Unlock()
+
+	if expected != 0 {
+		this.Publish(changetype.UPDATE, key, entity)
+	} else {
+		this.Publish(changetype.PUT, key, entity)
+	}
+
+	return toRevision(index), nil
+}
+
+// Begin starts a transaction whose writes are set in the database together
+// when it is committed. They are stored as a single record, so if any write
+// can't be encoded, or the record can't be written, none are applied
+func (this *JsonDbResolver) Begin(transactionContext context.Context) (resolvers.Transaction, error) {
+	return resolvers.NewBufferedTransaction(this.apply), nil
+}
+
+func (this *JsonDbResolver) apply(transactionContext context.Context, ops []resolvers.Op) error {
+	keyExtractor := this.resolverInfo.KeyExtractor()
+	writes := make([]jsondb.Write, 0, len(ops))
+	events := make([]resolvers.ChangeEvent, 0, len(ops))
+	staged := make(map[string]bool)
+
+	exists := func(key string) bool {
+		if exists, ok := staged[key]; ok {
+			return exists
+		}
+
+		return this.db.Has(key)
+	}
+
+	for _, op := range ops {
+		switch op.Operation {
+		case operationtype.PUT, operationtype.POST:
+			var key interface{}
+			ok := false
+
+			if keyExtractor != nil {
+				key, ok = keyExtractor(op.Entity)
+			}
+
+			if !ok {
+				return fmt.Errorf("Commit Failed: Cannot extract key from: %v", op.Entity)
+			}
+
+			keyString := resolvers.KeyString(key)
+			existed := exists(keyString)
+
+			if !existed && op.Operation == operationtype.POST {
+				return fmt.Errorf("Commit Failed: %w",
+					resolvers.NewEntityNotFound(fmt.Sprintf("Can't resolve entity for %v", key), nil))
+			}
+
+			jsonEntity, err := this.encode(transactionContext, op.Entity)
+
+			if err != nil {
+				return fmt.Errorf("Commit Failed: %w", err)
+			}
+
+			writes = append(writes, jsondb.Write{Key: keyString, Value: jsonEntity})
+			staged[keyString] = true
+
+			if existed {
+				events = append(events, resolvers.ChangeEvent{Type: changetype.UPDATE, Key: key, Entity: op.Entity})
+			} else {
+				events = append(events, resolvers.ChangeEvent{Type: changetype.PUT, Key: key, Entity: op.Entity})
+			}
+		case operationtype.DELETE:
+			if op.Selector == nil {
+				return fmt.Errorf("Commit Failed: selector cannot be nil")
+			}
+
+			keyString := resolvers.KeyString(op.Selector.Key())
+			writes = append(writes, jsondb.Write{Key: keyString, Value: nil})
+			staged[keyString] = false
+			events = append(events, resolvers.ChangeEvent{Type: changetype.DELETE, Key: op.Selector.Key()})
+		default:
+			return fmt.Errorf("Commit Failed: Invalid write operation: %v", op.Operation)
+		}
+	}
+
+	if err := util.AwaitError(this.db.SetAll(writes)); err != nil {
+		return fmt.Errorf("Commit Failed: %w", err)
+	}
+
+	this.mutex.Lock()
+	for index, write := range writes {
+		if events[index].Type == changetype.DELETE {
+			events[index].Entity = this.entities[write.Key].entity
+		}
+		delete(this.entities, write.Key)
+	}
+	this.mutex.Unlock()
+
+	for _, event := range events {
+		this.Publish(event.Type, event.Key, event.Entity)
+	}
+
+	return nil
+}
+
+// ForEach calls callback with each stored entity, translated to the
+// resolver's resolvable type if it has a single one. Entities which can't be
+// translated are passed as they are stored
+func (this *JsonDbResolver) ForEach(callback func(key interface{}, entity interface{})) {
+	var entityType ids.TypeIdentifier
+
+	if resolvableTypes := this.resolverInfo.ResolvableTypes(); len(resolvableTypes) == 1 {
+		entityType = resolvableTypes[0]
+	}
+
+	this.db.ForEachIndexed(func(key string, jsonEntity interface{}, index uint) {
+		if entity, err := this.decode(context.Background(), key, index, jsonEntity, entityType); err == nil {
+			callback(key, entity)
+		} else {
+			callback(key, jsonEntity)
+		}
+	})
+}
@@ -0,0 +1,420 @@
+package jsondbresolver_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/distributed-vision/go-resources/encoding/encodertype"
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/ids/domain"
+	"github.com/distributed-vision/go-resources/ids/identifier"
+	_ "github.com/distributed-vision/go-resources/ids/identitydomain"
+	"github.com/distributed-vision/go-resources/ids/mappings"
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/jsondbresolver"
+	"github.com/distributed-vision/go-resources/translators"
+	"github.com/distributed-vision/go-resources/types/gotypeid"
+	"github.com/distributed-vision/go-resources/util"
+	"github.com/distributed-vision/go-resources/util/random"
+	"github.com/distributed-vision/go-resources/version/versiontype"
+)
+
+var contentType = gotypeid.IdOf(reflect.TypeOf(map[string]interface{}{}))
+var testContext = context.Background()
+
+func idExtractor(e ...interface{}) (interface{}, bool) {
+	if entity, ok := e[0].(map[string]interface{}); ok {
+		id, ok := entity["id"].(string)
+		return id, ok
+	}
+
+	return nil, false
+}
+
+type selector struct {
+	key string
+}
+
+func (this *selector) Type() ids.TypeIdentifier {
+	return contentType
+}
+
+func (this *selector) Key() interface{} {
+	return this.key
+}
+
+func (this *selector) Test(candidate interface{}) bool {
+	entity, ok := candidate.(map[string]interface{})
+	return ok && (this.key == "" || entity["id"] == this.key)
+}
+
+func TestJsonDbResolver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsondbresolver")
+
+	if err != nil {
+		t.Fatal("TestJsonDbResolver: TempDir failed:", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	info := jsondbresolver.NewResolverInfo([]ids.TypeIdentifier{contentType}, nil, idExtractor,
+		map[interface{}]interface{}{"location": "entities.db", "paths": []string{dir}})
+
+	factory, err := resolvers.NewResolverFactory(info)
+
+	if err != nil {
+		t.Fatal("TestJsonDbResolver: NewResolverFactory failed:", err)
+	}
+
+	component, err := factory.New(testContext)
+
+	if err != nil {
+		t.Fatal("TestJsonDbResolver: factory.New failed:", err)
+	}
+
+	resolver := component.(*jsondbresolver.JsonDbResolver)
+
+	if _, err := resolver.Post(testContext, map[string]interface{}{"id": "a", "value": "1"}); err == nil {
+		t.Fatal("TestJsonDbResolver: Post of a missing entity should fail")
+	}
+
+	for _, id := range []string{"a", "b", "c"} {
+		if _, err := resolver.Put(testContext, map[string]interface{}{"id": id, "value": "1"}); err != nil {
+			t.Fatal("TestJsonDbResolver: Put failed:", err)
+		}
+	}
+
+	if _, err := resolver.Post(testContext, map[string]interface{}{"id": "a", "value": "2"}); err != nil {
+		t.Fatal("TestJsonDbResolver: Post failed:", err)
+	}
+
+	if err := resolver.Delete(testContext, &selector{"c"}); err != nil {
+		t.Fatal("TestJsonDbResolver: Delete failed:", err)
+	}
+
+	if err := resolver.Close(); err != nil {
+		t.Fatal("TestJsonDbResolver: Close failed:", err)
+	}
+
+	reopened, err := jsondbresolver.New(filepath.Join(dir, "entities.db"), info)
+
+	if err != nil {
+		t.Fatal("TestJsonDbResolver: reopen failed:", err)
+	}
+
+	defer reopened.Close()
+
+	if entity, err := reopened.Get(testContext, &selector{"a"}); err != nil || entity.(map[string]interface{})["value"] != "2" {
+		t.Fatal("TestJsonDbResolver: expected persisted entity got:", entity, err)
+	}
+
+	if _, err := reopened.Get(testContext, &selector{"c"}); err == nil {
+		t.Fatal("TestJsonDbResolver: expected deleted entity to be missing")
+	} else if _, ok := err.(*resolvers.EntityNotFound); !ok {
+		t.Fatal("TestJsonDbResolver: expected EntityNotFound got:", err)
+	}
+
+	entities, err := util.AwaitAll(reopened.Query(testContext, &selector{}, resolvers.QueryOpts{Order: resolvers.KEY_ASCENDING}))
+
+	if err != nil || len(entities) != 2 || entities[0].(map[string]interface{})["id"] != "a" {
+		t.Fatal("TestJsonDbResolver: unexpected query result:", entities, err)
+	}
+}
+
+func TestJsonDbTransaction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsondbresolver")
+
+	if err != nil {
+		t.Fatal("TestJsonDbTransaction: TempDir failed:", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	info := jsondbresolver.NewResolverInfo([]ids.TypeIdentifier{contentType}, nil, idExtractor, nil)
+	resolver, err := jsondbresolver.New(filepath.Join(dir, "transactions.db"), info)
+
+	if err != nil {
+		t.Fatal("TestJsonDbTransaction: New failed:", err)
+	}
+
+	resolver.Put(testContext, map[string]interface{}{"id": "a", "value": "1"})
+
+	if _, err := resolvers.Batch(testContext, resolver, []resolvers.Op{
+		resolvers.PutOp(map[string]interface{}{"id": "b", "value": "1"}),
+		resolvers.PostOp(map[string]interface{}{"id": "missing", "value": "1"})}); !resolvers.IsNotFound(err) {
+		t.Fatal("TestJsonDbTransaction: Expected not found, got:", err)
+	}
+
+	if _, err := resolver.Get(testContext, &selector{"b"}); !resolvers.IsNotFound(err) {
+		t.Fatal("TestJsonDbTransaction: Expected failed transaction not to write, got:", err)
+	}
+
+	if _, err := resolvers.Batch(testContext, resolver, []resolvers.Op{
+		resolvers.PutOp(map[string]interface{}{"id": "b", "value": "1"}),
+		resolvers.PostOp(map[string]interface{}{"id": "b", "value": "2"}),
+		resolvers.DeleteOp(&selector{"a"})}); err != nil {
+		t.Fatal("TestJsonDbTransaction: Batch failed:", err)
+	}
+
+	if err := resolver.Close(); err != nil {
+		t.Fatal("TestJsonDbTransaction: Close failed:", err)
+	}
+
+	reopened, err := jsondbresolver.New(filepath.Join(dir, "transactions.db"), info)
+
+	if err != nil {
+		t.Fatal("TestJsonDbTransaction: reopen failed:", err)
+	}
+
+	defer reopened.Close()
+
+	if entity, err := reopened.Get(testContext, &selector{"b"}); err != nil || entity.(map[string]interface{})["value"] != "2" {
+		t.Fatal("TestJsonDbTransaction: expected committed entity got:", entity, err)
+	}
+
+	if _, err := reopened.Get(testContext, &selector{"a"}); !resolvers.IsNotFound(err) {
+		t.Fatal("TestJsonDbTransaction: expected deleted entity to be missing, got:", err)
+	}
+}
+
+func TestJsonDbRevisions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsondbresolver")
+
+	if err != nil {
+		t.Fatal("TestJsonDbRevisions: TempDir failed:", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	info := jsondbresolver.NewResolverInfo([]ids.TypeIdentifier{contentType}, nil, idExtractor, nil)
+	resolver, err := jsondbresolver.New(filepath.Join(dir, "revisions.db"), info)
+
+	if err != nil {
+		t.Fatal("TestJsonDbRevisions: New failed:", err)
+	}
+
+	created, err := resolver.PostIfMatch(testContext, map[string]interface{}{"id": "a", "value": "1"}, resolvers.NoRevision)
+
+	if err != nil {
+		t.Fatal("TestJsonDbRevisions: create failed:", err)
+	}
+
+	if _, err := resolver.PostIfMatch(testContext, map[string]interface{}{"id": "a", "value": "2"}, resolvers.NoRevision); !errors.Is(err, resolvers.ErrConflict) {
+		t.Fatal("TestJsonDbRevisions: expected create of existing entity to conflict, got:", err)
+	}
+
+	updated, err := resolver.PostIfMatch(testContext, map[string]interface{}{"id": "a", "value": "2"}, created)
+
+	if err != nil || updated == created {
+		t.Fatal("TestJsonDbRevisions: PostIfMatch failed:", updated, err)
+	}
+
+	if _, err := resolver.PostIfMatch(testContext, map[string]interface{}{"id": "a", "value": "3"}, created); !errors.Is(err, resolvers.ErrConflict) {
+		t.Fatal("TestJsonDbRevisions: expected stale revision to conflict, got:", err)
+	}
+
+	if err := resolver.Close(); err != nil {
+		t.Fatal("TestJsonDbRevisions: Close failed:", err)
+	}
+
+	reopened, err := jsondbresolver.New(filepath.Join(dir, "revisions.db"), info)
+
+	if err != nil {
+		t.Fatal("TestJsonDbRevisions: reopen failed:", err)
+	}
+
+	defer reopened.Close()
+
+	entity, revision, err := reopened.GetRevision(testContext, &selector{"a"})
+
+	if err != nil || revision != updated || entity.(map[string]interface{})["value"] != "2" {
+		t.Fatal("TestJsonDbRevisions: expected revision to persist got:", entity, revision, err)
+	}
+
+	if _, err := reopened.Post(testContext, map[string]interface{}{"id": "a", "value": "3"}); err != nil {
+		t.Fatal("TestJsonDbRevisions: Post failed:", err)
+	}
+
+	if _, err := reopened.PostIfMatch(testContext, map[string]interface{}{"id": "a", "value": "4"}, revision); !errors.Is(err, resolvers.ErrConflict) {
+		t.Fatal("TestJsonDbRevisions: expected unconditional post to change revision, got:", err)
+	}
+}
+
+type typedSelector struct {
+	entityType ids.TypeIdentifier
+	key        string
+}
+
+func (this *typedSelector) Type() ids.TypeIdentifier {
+	return this.entityType
+}
+
+func (this *typedSelector) Key() interface{} {
+	return this.key
+}
+
+func (this *typedSelector) Test(candidate interface{}) bool {
+	return true
+}
+
+// roundTrip puts entity into a new database, reopens it and returns the
+// entity read back with key
+func roundTrip(t *testing.T, dir string, info resolvers.ResolverInfo, entity interface{}, key string) interface{} {
+	location := filepath.Join(dir, random.RandomString(8)+".db")
+	resolver, err := jsondbresolver.New(location, info)
+
+	if err != nil {
+		t.Fatal("New failed:", err)
+	}
+
+	if _, err := resolver.Put(testContext, entity); err != nil {
+		t.Fatal("Put failed:", err)
+	}
+
+	if err := resolver.Close(); err != nil {
+		t.Fatal("Close failed:", err)
+	}
+
+	reopened, err := jsondbresolver.New(location, info)
+
+	if err != nil {
+		t.Fatal("reopen failed:", err)
+	}
+
+	defer reopened.Close()
+
+	resolved, err := reopened.Get(testContext, &typedSelector{info.ResolvableTypes()[0], key})
+
+	if err != nil {
+		t.Fatal("Get after reopen failed:", err)
+	}
+
+	return resolved
+}
+
+func TestJsonDbTypedEntities(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsondbresolver")
+
+	if err != nil {
+		t.Fatal("TestJsonDbTypedEntities: TempDir failed:", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	fromDomain := domain.MustDecodeId(encodertype.BASE62, "3", "")
+	toDomain := domain.MustDecodeId(encodertype.BASE62, "3", "1")
+	from, _ := identifier.New(fromDomain, []byte("from"))
+	to, _ := identifier.New(toDomain, []byte("to"))
+
+	translated, err := translators.TranslateFuture(testContext, contentType, nil, map[string]interface{}{
+		"from":      from.String(),
+		"to":        domain.Wrap(toDomain).String(),
+		"mappedIds": []interface{}{map[string]interface{}{"to": to.String(), "validTo": "2020-01-01T00:00:00Z"}}},
+		mappings.EntityType()).Await()
+
+	if err != nil {
+		t.Fatal("TestJsonDbTypedEntities: Translate failed:", err)
+	}
+
+	mappingsInfo := jsondbresolver.NewResolverInfo([]ids.TypeIdentifier{mappings.EntityType()}, nil, mappings.KeyExtractor, nil)
+	key, _ := mappings.KeyExtractor(translated)
+	resolved := roundTrip(t, dir, mappingsInfo, translated, key.(string))
+
+	var expected, actual bytes.Buffer
+	mappings.Export(testContext, &expected, mappings.NDJSON, translated.(mappings.Mappings))
+
+	if resolvedMappings, ok := resolved.(mappings.Mappings); !ok || !strings.Contains(expected.String(), to.String()) ||
+		mappings.Export(testContext, &actual, mappings.NDJSON, resolvedMappings) != nil || actual.String() != expected.String() {
+		t.Fatalf("TestJsonDbTypedEntities: expected mappings %q got: %q", expected.String(), actual.String())
+	}
+
+	domainType := gotypeid.IdOf(reflect.TypeOf((*ids.Domain)(nil)).Elem())
+	stored, err := domain.New(domain.SchemeId(fromDomain), []byte("root"), nil, 0, versiontype.UNVERSIONED, true, false,
+		map[interface{}]interface{}{"name": "stored"})
+
+	if err != nil {
+		t.Fatal("TestJsonDbTypedEntities: domain.New failed:", err)
+	}
+
+	domainInfo := jsondbresolver.NewResolverInfo([]ids.TypeIdentifier{domainType}, nil, domain.KeyExtractor, nil)
+	key, _ = domain.KeyExtractor(stored)
+	resolved = roundTrip(t, dir, domainInfo, stored, key.(string))
+
+	if resolvedDomain, ok := resolved.(ids.Domain); !ok || !resolvedDomain.Equals(stored) ||
+		resolvedDomain.Name() != "stored" || !resolvedDomain.HasPaths() {
+		t.Fatal("TestJsonDbTypedEntities: expected domain to round trip got:", resolved)
+	}
+
+	local, _ := jsondbresolver.New(filepath.Join(dir, "untranslated.db"), jsondbresolver.NewResolverInfo(
+		[]ids.TypeIdentifier{gotypeid.IdOf(reflect.TypeOf(typedSelector{}))}, nil, func(...interface{}) (interface{}, bool) { return "key", true }, nil))
+	defer local.Close()
+
+	if _, err := local.Put(testContext, typedSelector{}); !errors.Is(err, translators.ErrNoTranslator) {
+		t.Fatal("TestJsonDbTypedEntities: expected entity without a translator to fail, got:", err)
+	}
+}
+
+func TestJsonDbExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsondbresolver")
+
+	if err != nil {
+		t.Fatal("TestJsonDbExport: TempDir failed:", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	fromDomain := domain.MustDecodeId(encodertype.BASE62, "3", "")
+	toDomain := domain.MustDecodeId(encodertype.BASE62, "3", "1")
+	from, _ := identifier.New(fromDomain, []byte(random.RandomString(8)))
+	to, _ := identifier.New(toDomain, []byte(random.RandomString(8)))
+
+	translated, err := translators.TranslateFuture(testContext, contentType, nil, map[string]interface{}{
+		"from":      from.String(),
+		"to":        domain.Wrap(toDomain).String(),
+		"mappedIds": []interface{}{map[string]interface{}{"to": to.String(), "validTo": "2020-01-01T00:00:00Z"}}},
+		mappings.EntityType()).Await()
+
+	if err != nil {
+		t.Fatal("TestJsonDbExport: Translate failed:", err)
+	}
+
+	location := filepath.Join(dir, "mappings.db")
+	mappingsInfo := jsondbresolver.NewResolverInfo([]ids.TypeIdentifier{mappings.EntityType()}, nil, mappings.KeyExtractor, nil)
+	resolver, err := jsondbresolver.New(location, mappingsInfo)
+
+	if err != nil {
+		t.Fatal("TestJsonDbExport: New failed:", err)
+	}
+
+	defer resolver.Close()
+
+	if _, err := jsondbresolver.New(location, jsondbresolver.NewResolverInfo([]ids.TypeIdentifier{contentType}, nil, idExtractor, nil)); err == nil {
+		t.Fatal("TestJsonDbExport: expected reopening with different resolver info to fail")
+	}
+
+	if _, err := resolver.Put(testContext, translated); err != nil {
+		t.Fatal("TestJsonDbExport: Put failed:", err)
+	}
+
+	if err := mappings.RegisterResolver(resolver); err != nil {
+		t.Fatal("TestJsonDbExport: RegisterResolver failed:", err)
+	}
+
+	var expected, exported bytes.Buffer
+	mappings.Export(testContext, &expected, mappings.NDJSON, translated.(mappings.Mappings))
+
+	if err := mappings.ExportAll(testContext, &exported, mappings.NDJSON); err != nil {
+		t.Fatal("TestJsonDbExport: ExportAll failed:", err)
+	}
+
+	if !strings.Contains(expected.String(), to.String()) || exported.String() != expected.String() {
+		t.Fatalf("TestJsonDbExport: expected export %q got: %q", expected.String(), exported.String())
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strconv"
 	"sync"
 
 	"github.com/distributed-vision/go-resources/ids"
 	"github.com/distributed-vision/go-resources/ids/mappings"
 	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/changetype"
+	"github.com/distributed-vision/go-resources/resolvers/operationtype"
 	"github.com/distributed-vision/go-resources/types"
 	"github.com/distributed-vision/go-resources/types/gotypeid"
 	"github.com/distributed-vision/go-resources/types/publictypeid"
@@ -52,8 +55,11 @@ func (this *factory) ResolverInfo() resolvers.ResolverInfo {
 }
 
 type LocalResolver struct {
+	*resolvers.ChangeFeed
 	resolverInfo resolvers.ResolverInfo
 	entityMap    map[interface{}]interface{}
+	revisions    map[interface{}]uint64
+	revision     uint64
 	mutex        *sync.Mutex
 }
 
@@ -63,8 +69,11 @@ func New(baseInfo resolvers.ResolverInfo) (*LocalResolver, error) {
 	}
 
 	return &LocalResolver{
+		&resolvers.ChangeFeed{},
 		baseInfo.DerivedCopy(),
 		make(map[interface{}]interface{}),
+		make(map[interface{}]uint64),
+		0,
 		&sync.Mutex{}}, nil
 }
 
@@ -76,6 +85,25 @@ func (this *LocalResolver) Get(resolutionContext context.Context, selector resol
 	this.mutex.Lock()
 	defer this.mutex.Unlock()
 
+	return this.get(selector)
+}
+
+// GetMany resolves each of selectors under a single lock
+func (this *LocalResolver) GetMany(resolutionContext context.Context, selectors []resolvers.Selector) []resolvers.Result {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	results := make([]resolvers.Result, len(selectors))
+
+	for index, selector := range selectors {
+		entity, err := this.get(selector)
+		results[index] = resolvers.Result{Selector: selector, Entity: entity, Err: err}
+	}
+
+	return results
+}
+
+func (this *LocalResolver) get(selector resolvers.Selector) (interface{}, error) {
 	var key string
 
 	switch selector.Key().(type) {
@@ -102,7 +130,7 @@ func (this *LocalResolver) Get(resolutionContext context.Context, selector resol
 		}
 	}
 
-	return nil, fmt.Errorf("Can't resolve entity for %v", selector)
+	return nil, resolvers.NewEntityNotFound(fmt.Sprintf("Can't resolve entity for %v", selector), nil)
 }
 
 func (this *LocalResolver) Resolve(resolutionContext context.Context, selector resolvers.Selector) (chan interface{}, chan error) {
@@ -124,32 +152,49 @@ func (this *LocalResolver) Resolve(resolutionContext context.Context, selector r
 	return cres, cerr
 }
 
-func (this *LocalResolver) Put(resolutionContext context.Context, entity interface{}) error {
+func (this *LocalResolver) Put(resolutionContext context.Context, entity interface{}) (interface{}, error) {
 	keyExtractor := this.resolverInfo.KeyExtractor()
 
 	if key, ok := keyExtractor(entity); ok {
+		this.mutex.Lock()
+		_, exists := this.entityMap[key]
 		this.entityMap[key] = entity
+		this.revise(key)
+		this.mutex.Unlock()
+
+		if exists {
+			this.Publish(changetype.UPDATE, key, entity)
+		} else {
+			this.Publish(changetype.PUT, key, entity)
+		}
 	} else {
-		return fmt.Errorf("Cannot extract key from: %v", entity)
+		return nil, fmt.Errorf("Cannot extract key from: %v", entity)
 	}
 
-	return nil
+	return entity, nil
 }
 
-func (this *LocalResolver) Post(resolutionContext context.Context, entity interface{}) error {
+func (this *LocalResolver) Post(resolutionContext context.Context, entity interface{}) (interface{}, error) {
 	keyExtractor := this.resolverInfo.KeyExtractor()
 
 	if key, ok := keyExtractor(entity); ok {
+		this.mutex.Lock()
+
 		if _, ok := this.entityMap[key]; ok {
 			this.entityMap[key] = entity
+			this.revise(key)
+			this.mutex.Unlock()
 		} else {
-			return fmt.Errorf("Can't resolve entity for %v", key)
+			this.mutex.Unlock()
+			return nil, resolvers.NewEntityNotFound(fmt.Sprintf("Can't resolve entity for %v", key), nil)
 		}
+
+		this.Publish(changetype.UPDATE, key, entity)
 	} else {
-		return fmt.Errorf("Cannot extract key from: %v", entity)
+		return nil, fmt.Errorf("Cannot extract key from: %v", entity)
 	}
 
-	return nil
+	return entity, nil
 }
 
 func (this *LocalResolver) Delete(resolutionContext context.Context, selector resolvers.Selector) error {
@@ -164,7 +209,208 @@ func (this *LocalResolver) Delete(resolutionContext context.Context, selector re
 		key = fmt.Sprintf("%v", selector.Key())
 	}
 
+	this.mutex.Lock()
+	entity := this.entityMap[key]
 	delete(this.entityMap, key)
+	delete(this.revisions, key)
+	this.mutex.Unlock()
+	this.Publish(changetype.DELETE, key, entity)
+
+	return nil
+}
+
+// Begin starts a transaction whose writes are applied under a single lock
+// when it is committed, if any write fails none are applied
+func (this *LocalResolver) Begin(transactionContext context.Context) (resolvers.Transaction, error) {
+	return resolvers.NewBufferedTransaction(this.apply), nil
+}
+
+type priorEntity struct {
+	entity   interface{}
+	revision uint64
+	exists   bool
+}
+
+func (this *LocalResolver) apply(transactionContext context.Context, ops []resolvers.Op) error {
+	keyExtractor := this.resolverInfo.KeyExtractor()
+	priors := make(map[interface{}]priorEntity)
+	events := make([]resolvers.ChangeEvent, 0, len(ops))
+
+	this.mutex.Lock()
+
+	save := func(key interface{}) (interface{}, bool) {
+		entity, exists := this.entityMap[key]
+
+		if _, ok := priors[key]; !ok {
+			priors[key] = priorEntity{entity, this.revisions[key], exists}
+		}
+
+		return entity, exists
+	}
+
+	fail := func(err error) error {
+		for key, prior := range priors {
+			if prior.exists {
+				this.entityMap[key] = prior.entity
+				this.revisions[key] = prior.revision
+			} else {
+				delete(this.entityMap, key)
+				delete(this.revisions, key)
+			}
+		}
+
+		this.mutex.Unlock()
+
+		return fmt.Errorf("Commit Failed: %w", err)
+	}
+
+	for _, op := range ops {
+		switch op.Operation {
+		case operationtype.PUT, operationtype.POST:
+			var key interface{}
+			ok := false
+
+			if keyExtractor != nil {
+				key, ok = keyExtractor(op.Entity)
+			}
+
+			if !ok {
+				return fail(fmt.Errorf("Cannot extract key from: %v", op.Entity))
+			}
+
+			_, exists := save(key)
+
+			if !exists && op.Operation == operationtype.POST {
+				return fail(resolvers.NewEntityNotFound(fmt.Sprintf("Can't resolve entity for %v", key), nil))
+			}
+
+			this.entityMap[key] = op.Entity
+			this.revise(key)
+
+			if exists {
+				events = append(events, resolvers.ChangeEvent{Type: changetype.UPDATE, Key: key, Entity: op.Entity})
+			} else {
+				events = append(events, resolvers.ChangeEvent{Type: changetype.PUT, Key: key, Entity: op.Entity})
+			}
+		case operationtype.DELETE:
+			if op.Selector == nil {
+				return fail(fmt.Errorf("selector cannot be nil"))
+			}
+
+			key := resolvers.KeyString(op.Selector.Key())
+			entity, _ := save(key)
+			delete(this.entityMap, key)
+			delete(this.revisions, key)
+			events = append(events, resolvers.ChangeEvent{Type: changetype.DELETE, Key: key, Entity: entity})
+		default:
+			return fail(fmt.Errorf("Invalid write operation: %v", op.Operation))
+		}
+	}
+
+	this.mutex.Unlock()
+
+	for _, event := range events {
+		this.Publish(event.Type, event.Key, event.Entity)
+	}
 
 	return nil
 }
+
+// revise gives the entity stored with key a new revision, it must be called
+// with the mutex held
+func (this *LocalResolver) revise(key interface{}) resolvers.Revision {
+	this.revision++
+	this.revisions[key] = this.revision
+	return resolvers.Revision(strconv.FormatUint(this.revision, 10))
+}
+
+func (this *LocalResolver) revisionOf(key interface{}) resolvers.Revision {
+	if revision, ok := this.revisions[key]; ok {
+		return resolvers.Revision(strconv.FormatUint(revision, 10))
+	}
+
+	return resolvers.NoRevision
+}
+
+// GetRevision returns the entity selected by selector with its revision
+func (this *LocalResolver) GetRevision(resolutionContext context.Context, selector resolvers.Selector) (interface{}, resolvers.Revision, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	entity, err := this.get(selector)
+
+	if err != nil {
+		return nil, resolvers.NoRevision, err
+	}
+
+	key, _ := this.resolverInfo.KeyExtractor()(entity)
+
+	return entity, this.revisionOf(key), nil
+}
+
+// PostIfMatch writes entity if its stored revision is revision
+func (this *LocalResolver) PostIfMatch(resolutionContext context.Context, entity interface{}, revision resolvers.Revision) (resolvers.Revision, error) {
+	key, ok := this.resolverInfo.KeyExtractor()(entity)
+
+	if !ok {
+		return resolvers.NoRevision, fmt.Errorf("Cannot extract key from: %v", entity)
+	}
+
+	this.mutex.Lock()
+
+	_, exists := this.entityMap[key]
+
+	if current := this.revisionOf(key); current != revision {
+		this.mutex.Unlock()
+		return resolvers.NoRevision, resolvers.NewRevisionConflict(key, revision, current)
+	}
+
+	this.entityMap[key] = entity
+	revised := this.revise(key)
+	this.mutex.Unlock()
+
+	if exists {
+		this.Publish(changetype.UPDATE, key, entity)
+	} else {
+		this.Publish(changetype.PUT, key, entity)
+	}
+
+	return revised, nil
+}
+
+func (this *LocalResolver) ForEach(callback func(key interface{}, entity interface{})) {
+	type entry struct {
+		key    interface{}
+		entity interface{}
+	}
+
+	this.mutex.Lock()
+
+	entries := make([]entry, 0, len(this.entityMap))
+
+	for key, entity := range this.entityMap {
+		entries = append(entries, entry{key, entity})
+	}
+
+	this.mutex.Unlock()
+
+	for _, entry := range entries {
+		callback(entry.key, entry.entity)
+	}
+}
+
+func (this *LocalResolver) Query(queryContext context.Context, selector resolvers.Selector, opts resolvers.QueryOpts) (<-chan interface{}, <-chan error) {
+	stream := resolvers.NewQueryStream(queryContext, opts, this.resolverInfo.KeyExtractor())
+
+	go func() {
+		this.ForEach(func(key interface{}, entity interface{}) {
+			if selector.Test(entity) {
+				stream.AddKeyed(resolvers.KeyString(key), entity)
+			}
+		})
+
+		stream.Close(nil)
+	}()
+
+	return stream.Results()
+}
@@ -2,6 +2,8 @@ package localresolver_test
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"testing"
 
 	"github.com/distributed-vision/go-resources/ids"
@@ -89,6 +91,18 @@ func TestLocalResolverGet(t *testing.T) {
 		}
 	}
 
+	selectors := make([]resolvers.Selector, 256)
+
+	for i := 0; i < 256; i++ {
+		selectors[i] = &selector{key: keys[i]}
+	}
+
+	for i, result := range resolver.GetMany(testContext, selectors) {
+		if result.Err != nil || values[i] != result.Entity.(entity).value {
+			t.Fatalf("TestLocalResolverGet: Key %v GetMany unexpectedly failed: %v", i, result.Err)
+		}
+	}
+
 	for i := 0; i < 256; i++ {
 		resolved, err := util.Await(resolver.Resolve(testContext, &selector{key: keys[i]}))
 
@@ -153,7 +167,7 @@ func TestLocalResolverGet(t *testing.T) {
 	}
 
 	for i := 0; i < 128; i++ {
-		err := resolver.Post(testContext, entity{keys[i], values[i+128]})
+		_, err := resolver.Post(testContext, entity{keys[i], values[i+128]})
 
 		if err != nil {
 			t.Fatal("TestLocalResolverGet: LocalResolver.Post failed:", err)
@@ -161,7 +175,7 @@ func TestLocalResolverGet(t *testing.T) {
 	}
 
 	for i := 128; i < 256; i++ {
-		err := resolver.Post(testContext, entity{keys[i], values[i-128]})
+		_, err := resolver.Post(testContext, entity{keys[i], values[i-128]})
 
 		if err == nil {
 			t.Fatal("TestLocalResolverGet: LocalResolver.Post unexpectedly succeded at:", i)
@@ -181,3 +195,68 @@ func TestLocalResolverGet(t *testing.T) {
 		}
 	}
 }
+
+func TestLocalResolverRevisions(t *testing.T) {
+	resolver, _ := localresolver.New(testInfo)
+
+	created, err := resolver.PostIfMatch(testContext, entity{"a", "1"}, resolvers.NoRevision)
+
+	if err != nil || created == resolvers.NoRevision {
+		t.Fatal("TestLocalResolverRevisions: create failed:", created, err)
+	}
+
+	if _, err := resolver.PostIfMatch(testContext, entity{"a", "2"}, resolvers.NoRevision); !errors.Is(err, resolvers.ErrConflict) {
+		t.Fatal("TestLocalResolverRevisions: expected create of existing entity to conflict, got:", err)
+	}
+
+	resolved, revision, err := resolver.GetRevision(testContext, &selector{key: "a"})
+
+	if err != nil || revision != created || resolved.(entity).value != "1" {
+		t.Fatal("TestLocalResolverRevisions: GetRevision failed:", resolved, revision, err)
+	}
+
+	updated, err := resolver.PostIfMatch(testContext, entity{"a", "2"}, revision)
+
+	if err != nil || updated == revision {
+		t.Fatal("TestLocalResolverRevisions: PostIfMatch failed:", updated, err)
+	}
+
+	var conflict *resolvers.Conflict
+
+	if _, err := resolver.PostIfMatch(testContext, entity{"a", "3"}, revision); !errors.As(err, &conflict) {
+		t.Fatal("TestLocalResolverRevisions: expected stale revision to conflict, got:", err)
+	}
+
+	if resolver.Put(testContext, entity{"a", "4"}); resolver.Delete(testContext, &selector{key: "a"}) != nil {
+		t.Fatal("TestLocalResolverRevisions: Delete failed")
+	}
+
+	if _, err := resolver.PostIfMatch(testContext, entity{"a", "5"}, updated); !errors.Is(err, resolvers.ErrConflict) {
+		t.Fatal("TestLocalResolverRevisions: expected update of deleted entity to conflict, got:", err)
+	}
+}
+
+func TestLocalResolverUpdate(t *testing.T) {
+	resolver, _ := localresolver.New(testInfo)
+	resolver.Put(testContext, entity{"count", ""})
+
+	var wait sync.WaitGroup
+
+	// each updater can only conflict with the writes of the others, so all
+	// succeed within DefaultUpdateAttempts
+	for i := 0; i < 8; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			resolvers.Update(testContext, resolver, &selector{key: "count"}, func(current interface{}) (interface{}, error) {
+				return entity{"count", current.(entity).value + "x"}, nil
+			})
+		}()
+	}
+
+	wait.Wait()
+
+	if resolved, err := resolver.Get(testContext, &selector{key: "count"}); err != nil || len(resolved.(entity).value) != 8 {
+		t.Fatal("TestLocalResolverUpdate: expected 8 updates got:", resolved, err)
+	}
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"expvar"
+	"sync"
+)
+
+var published = struct {
+	sync.Mutex
+	recorders map[string]*Recorder
+}{recorders: make(map[string]*Recorder)}
+
+// Publish exports the recorder's snapshot as the expvar variable name. As
+// expvar variables can't be removed, publishing a name again replaces the
+// recorder it exports
+func Publish(name string, recorder *Recorder) {
+	published.Lock()
+	defer published.Unlock()
+
+	if _, ok := published.recorders[name]; ok {
+		published.recorders[name] = recorder
+		return
+	}
+
+	published.recorders[name] = recorder
+
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		published.Lock()
+		recorder := published.recorders[name]
+		published.Unlock()
+
+		return recorder.Snapshot()
+	}))
+}
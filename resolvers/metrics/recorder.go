@@ -0,0 +1,287 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/util/clock"
+)
+
+// LatencyBuckets are the upper bounds of the latency histogram buckets, a
+// final bucket counts latencies above the last bound
+var LatencyBuckets = []time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+type Histogram struct {
+	Bounds []time.Duration
+	Counts []int64
+	Count  int64
+	Sum    time.Duration
+}
+
+func newHistogram() *Histogram {
+	return &Histogram{Bounds: LatencyBuckets, Counts: make([]int64, len(LatencyBuckets)+1)}
+}
+
+func (this *Histogram) observe(latency time.Duration) {
+	bucket := len(this.Bounds)
+
+	for i, bound := range this.Bounds {
+		if latency <= bound {
+			bucket = i
+			break
+		}
+	}
+
+	this.Counts[bucket]++
+	this.Count++
+	this.Sum += latency
+}
+
+func (this *Histogram) Mean() time.Duration {
+	if this.Count == 0 {
+		return 0
+	}
+
+	return this.Sum / time.Duration(this.Count)
+}
+
+func (this *Histogram) copy() *Histogram {
+	copied := *this
+	copied.Counts = append([]int64{}, this.Counts...)
+	return &copied
+}
+
+// CallStats counts the outcomes of an operation on a subject, calls
+// returning EntityNotFound are misses
+type CallStats struct {
+	Hits    int64
+	Misses  int64
+	Errors  int64
+	Latency *Histogram
+}
+
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+}
+
+func (this CacheStats) HitRatio() float64 {
+	if this.Hits+this.Misses == 0 {
+		return 0
+	}
+
+	return float64(this.Hits) / float64(this.Hits+this.Misses)
+}
+
+type SpanRecord struct {
+	Operation string
+	Subject   string
+	Start     time.Time
+	Duration  time.Duration
+	Err       error
+}
+
+var DefaultSpanHistory = 1000
+
+// Recorder is an in memory Instrumentation which keeps call statistics per
+// operation and subject, cache statistics per resolver type and the most
+// recent spans
+type Recorder struct {
+	mutex       *sync.Mutex
+	clock       clock.Clock
+	calls       map[string]map[string]*CallStats
+	caches      map[string]*CacheStats
+	spans       []SpanRecord
+	spanHistory int
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{
+		mutex:       &sync.Mutex{},
+		clock:       clock.System,
+		calls:       make(map[string]map[string]*CallStats),
+		caches:      make(map[string]*CacheStats),
+		spanHistory: DefaultSpanHistory}
+}
+
+func (this *Recorder) SetClock(clock clock.Clock) {
+	this.clock = clock
+}
+
+func subjectName(subject ids.TypeIdentifier) string {
+	if subject == nil {
+		return ""
+	}
+
+	return subject.String()
+}
+
+type span struct {
+	recorder *Recorder
+	record   SpanRecord
+}
+
+func (this *span) End(err error) {
+	this.record.Duration = this.recorder.clock.Now().Sub(this.record.Start)
+	this.record.Err = err
+	this.recorder.end(this.record)
+}
+
+func (this *Recorder) StartSpan(spanContext context.Context, operation string, subject ids.TypeIdentifier) (context.Context, resolvers.Span) {
+	return spanContext, &span{this, SpanRecord{Operation: operation, Subject: subjectName(subject), Start: this.clock.Now()}}
+}
+
+func (this *Recorder) end(record SpanRecord) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	subjects, ok := this.calls[record.Operation]
+
+	if !ok {
+		subjects = make(map[string]*CallStats)
+		this.calls[record.Operation] = subjects
+	}
+
+	stats, ok := subjects[record.Subject]
+
+	if !ok {
+		stats = &CallStats{Latency: newHistogram()}
+		subjects[record.Subject] = stats
+	}
+
+	if record.Err == nil {
+		stats.Hits++
+	} else if resolvers.IsNotFound(record.Err) {
+		stats.Misses++
+	} else {
+		stats.Errors++
+	}
+
+	stats.Latency.observe(record.Duration)
+
+	this.spans = append(this.spans, record)
+
+	if len(this.spans) > this.spanHistory {
+		this.spans = this.spans[len(this.spans)-this.spanHistory:]
+	}
+}
+
+func (this *Recorder) CacheLookup(resolverType ids.TypeIdentifier, hit bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	name := subjectName(resolverType)
+	stats, ok := this.caches[name]
+
+	if !ok {
+		stats = &CacheStats{}
+		this.caches[name] = stats
+	}
+
+	if hit {
+		stats.Hits++
+	} else {
+		stats.Misses++
+	}
+}
+
+// Calls returns the statistics for operation on subject
+func (this *Recorder) Calls(operation string, subject ids.TypeIdentifier) CallStats {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if stats, ok := this.calls[operation][subjectName(subject)]; ok {
+		copied := *stats
+		copied.Latency = stats.Latency.copy()
+		return copied
+	}
+
+	return CallStats{Latency: newHistogram()}
+}
+
+func (this *Recorder) Cache(resolverType ids.TypeIdentifier) CacheStats {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if stats, ok := this.caches[subjectName(resolverType)]; ok {
+		return *stats
+	}
+
+	return CacheStats{}
+}
+
+func (this *Recorder) Spans() []SpanRecord {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return append([]SpanRecord{}, this.spans...)
+}
+
+func (this *Recorder) Reset() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.calls = make(map[string]map[string]*CallStats)
+	this.caches = make(map[string]*CacheStats)
+	this.spans = nil
+}
+
+// Snapshot returns the recorder's statistics in a form suitable for json
+// encoding, latencies are reported in milliseconds
+func (this *Recorder) Snapshot() map[string]interface{} {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	calls := make(map[string]interface{})
+
+	for operation, subjects := range this.calls {
+		operationStats := make(map[string]interface{})
+
+		for subject, stats := range subjects {
+			buckets := make(map[string]int64)
+
+			for i, count := range stats.Latency.Counts {
+				if i < len(stats.Latency.Bounds) {
+					buckets[stats.Latency.Bounds[i].String()] = count
+				} else {
+					buckets["+Inf"] = count
+				}
+			}
+
+			operationStats[subject] = map[string]interface{}{
+				"hits":          stats.Hits,
+				"misses":        stats.Misses,
+				"errors":        stats.Errors,
+				"meanLatencyMs": float64(stats.Latency.Mean()) / float64(time.Millisecond),
+				"latency":       buckets}
+		}
+
+		calls[operation] = operationStats
+	}
+
+	caches := make(map[string]interface{})
+
+	for resolverType, stats := range this.caches {
+		caches[resolverType] = map[string]interface{}{
+			"hits":     stats.Hits,
+			"misses":   stats.Misses,
+			"hitRatio": stats.HitRatio()}
+	}
+
+	return map[string]interface{}{"calls": calls, "caches": caches}
+}
@@ -0,0 +1,133 @@
+package metrics_test
+
+import (
+	"context"
+	"expvar"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/localresolver"
+	"github.com/distributed-vision/go-resources/resolvers/metrics"
+	"github.com/distributed-vision/go-resources/translators"
+	"github.com/distributed-vision/go-resources/types/gotypeid"
+	"github.com/distributed-vision/go-resources/util"
+	"github.com/distributed-vision/go-resources/util/clock"
+	"github.com/distributed-vision/go-resources/util/random"
+)
+
+type entity struct {
+	key   string
+	value string
+}
+
+type selector struct {
+	key string
+}
+
+func (this *selector) Type() ids.TypeIdentifier {
+	return entityType
+}
+
+func (this *selector) Key() interface{} {
+	return this.key
+}
+
+func (this *selector) Test(candidate interface{}) bool {
+	e, ok := candidate.(entity)
+	return ok && e.key == this.key
+}
+
+var entityType = gotypeid.IdOf(reflect.TypeOf(entity{}))
+var testContext = context.Background()
+
+func extractor(e ...interface{}) (interface{}, bool) {
+	return e[0].(entity).key, true
+}
+
+func TestRecorder(t *testing.T) {
+	recorder := metrics.NewRecorder()
+	testClock := clock.NewManual(time.Now())
+	recorder.SetClock(testClock)
+
+	resolvers.SetInstrumentation(recorder)
+	defer resolvers.SetInstrumentation(nil)
+
+	info := localresolver.NewResolverInfo([]ids.TypeIdentifier{entityType}, nil, extractor, nil)
+	factory, err := resolvers.NewResolverFactory(info)
+
+	if err != nil {
+		t.Fatal("TestRecorder: NewResolverFactory failed:", err)
+	}
+
+	resolver, err := resolvers.NewCompositeResolver(resolvers.RootInfo.WithExtractor(extractor))
+
+	if err != nil {
+		t.Fatal("TestRecorder: NewCompositeResolver failed:", err)
+	}
+
+	resolver.RegisterComponentFactory(factory, false)
+
+	key := random.RandomString(20)
+
+	if _, err := resolver.Get(testContext, &selector{key}); err == nil {
+		t.Fatal("TestRecorder: expected missing entity")
+	}
+
+	for _, component := range resolver.GetMutableComponents(testContext, &selector{key}) {
+		component.(resolvers.MutableResolver).Put(testContext, entity{key, "value"})
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := resolver.Get(testContext, &selector{key}); err != nil {
+			t.Fatal("TestRecorder: Get failed:", err)
+		}
+	}
+
+	if stats := recorder.Calls(resolvers.OP_INSTANTIATE, localresolver.PublicType); stats.Hits != 1 {
+		t.Fatalf("TestRecorder: expected 1 instantiation got: %+v", stats)
+	}
+
+	stats := recorder.Calls(resolvers.OP_RESOLVE, localresolver.PublicType)
+
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Errors != 0 || stats.Latency.Count != 2 {
+		t.Fatalf("TestRecorder: unexpected component stats: %+v", stats)
+	}
+
+	cache := recorder.Cache(resolver.ResolverInfo().ResolverType())
+
+	if cache.Hits != 2 || cache.Misses != 2 || cache.HitRatio() != 0.5 {
+		t.Fatalf("TestRecorder: unexpected cache stats: %+v", cache)
+	}
+
+	fromType := gotypeid.IdOf(reflect.TypeOf(""))
+
+	translators.Register(testContext, fromType, entityType,
+		func(translationContext context.Context, fromId ids.Identifier, fromValue interface{}) (chan interface{}, chan error) {
+			cres, cerr := make(chan interface{}, 1), make(chan error, 1)
+			testClock.Advance(3 * time.Millisecond)
+			cres <- entity{fromValue.(string), fromValue.(string)}
+			close(cres)
+			close(cerr)
+			return cres, cerr
+		})
+
+	if translated, err := util.Await(translators.Translate(testContext, fromType, nil, "translated", entityType)); err != nil || translated.(entity).key != "translated" {
+		t.Fatal("TestRecorder: Translate failed:", translated, err)
+	}
+
+	translations := recorder.Calls(resolvers.OP_TRANSLATE, entityType)
+
+	if translations.Hits != 1 || translations.Latency.Mean() != 3*time.Millisecond {
+		t.Fatalf("TestRecorder: unexpected translation stats: %+v", translations)
+	}
+
+	metrics.Publish("resolvers", recorder)
+
+	if value := expvar.Get("resolvers").String(); !strings.Contains(value, `"hitRatio":0.5`) {
+		t.Fatal("TestRecorder: unexpected expvar value:", value)
+	}
+}
@@ -0,0 +1,55 @@
+package operationtype
+
+import (
+	"errors"
+	"strings"
+)
+
+type OperationType int
+
+const (
+	GET OperationType = iota
+	QUERY
+	PUT
+	POST
+	DELETE
+)
+
+func (this OperationType) String() string {
+	switch this {
+	case GET:
+		return "get"
+	case QUERY:
+		return "query"
+	case PUT:
+		return "put"
+	case POST:
+		return "post"
+	case DELETE:
+		return "delete"
+	default:
+		return "invalid"
+	}
+}
+
+// IsWrite returns true for the operations which change entities
+func (this OperationType) IsWrite() bool {
+	return this == PUT || this == POST || this == DELETE
+}
+
+func Parse(value string) (OperationType, error) {
+	switch strings.ToUpper(value) {
+	case "GET":
+		return GET, nil
+	case "QUERY":
+		return QUERY, nil
+	case "PUT":
+		return PUT, nil
+	case "POST":
+		return POST, nil
+	case "DELETE":
+		return DELETE, nil
+	default:
+		return -1, errors.New("Unknown operation type: " + value)
+	}
+}
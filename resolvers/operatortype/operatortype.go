@@ -0,0 +1,68 @@
+package operatortype
+
+import (
+	"errors"
+	"strings"
+)
+
+// OperatorType is the comparison a field selector makes between an entity's
+// field and the selector's value
+type OperatorType int
+
+const (
+	EQ OperatorType = iota
+	NE
+	LT
+	LE
+	GT
+	GE
+	PREFIX
+	CONTAINS
+)
+
+func (this OperatorType) String() string {
+	switch this {
+	case EQ:
+		return "eq"
+	case NE:
+		return "ne"
+	case LT:
+		return "lt"
+	case LE:
+		return "le"
+	case GT:
+		return "gt"
+	case GE:
+		return "ge"
+	case PREFIX:
+		return "prefix"
+	case CONTAINS:
+		return "contains"
+	default:
+		return "invalid"
+	}
+}
+
+// Parse reads an operator from its name or, for comparisons, its symbol
+func Parse(value string) (OperatorType, error) {
+	switch strings.ToUpper(value) {
+	case "EQ", "=", "==":
+		return EQ, nil
+	case "NE", "!=", "<>":
+		return NE, nil
+	case "LT", "<":
+		return LT, nil
+	case "LE", "<=":
+		return LE, nil
+	case "GT", ">":
+		return GT, nil
+	case "GE", ">=":
+		return GE, nil
+	case "PREFIX":
+		return PREFIX, nil
+	case "CONTAINS":
+		return CONTAINS, nil
+	default:
+		return -1, errors.New("Unknown operator type: " + value)
+	}
+}
@@ -0,0 +1,56 @@
+package outcometype
+
+import (
+	"errors"
+	"strings"
+)
+
+// OutcomeType is the result of consulting a component during a resolve
+type OutcomeType int
+
+const (
+	PENDING OutcomeType = iota
+	FOUND
+	NOT_FOUND
+	FAILED
+	REJECTED
+	CANCELLED
+)
+
+func (this OutcomeType) String() string {
+	switch this {
+	case PENDING:
+		return "pending"
+	case FOUND:
+		return "found"
+	case NOT_FOUND:
+		return "not-found"
+	case FAILED:
+		return "failed"
+	case REJECTED:
+		return "rejected"
+	case CANCELLED:
+		return "cancelled"
+	default:
+		return "invalid"
+	}
+}
+
+func Parse(value string) (OutcomeType, error) {
+	switch strings.ToUpper(value) {
+	case "PENDING":
+		return PENDING, nil
+	case "FOUND":
+		return FOUND, nil
+	case "NOT-FOUND", "NOT_FOUND":
+		return NOT_FOUND, nil
+	case "FAILED":
+		return FAILED, nil
+	case "REJECTED":
+		return REJECTED, nil
+	case "CANCELLED":
+		return CANCELLED, nil
+	default:
+		return -1, errors.New("Unknown outcome type: " + value)
+	}
+}
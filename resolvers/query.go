@@ -0,0 +1,241 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+type QueryOrder int
+
+const (
+	UNORDERED QueryOrder = iota
+	KEY_ASCENDING
+	KEY_DESCENDING
+)
+
+// QueryOpts controls the entities returned by a query. Results are streamed
+// as they are found unless an order, offset or cursor is requested, in which
+// case they are collected and sorted before being returned. Cursor is the
+// key of the last entity of the previous page; queries with a cursor and no
+// order are ordered by key
+type QueryOpts struct {
+	Limit  int
+	Offset int
+	Cursor string
+	Order  QueryOrder
+	Less   func(entity, other interface{}) bool
+}
+
+func (this *QueryOpts) isOrdered() bool {
+	return this.Order != UNORDERED || this.Less != nil || this.Cursor != "" || this.Offset > 0
+}
+
+type QueryResolver interface {
+	Resolver
+	Query(queryContext context.Context, selector Selector, opts QueryOpts) (<-chan interface{}, <-chan error)
+}
+
+func Query(queryContext context.Context, selector Selector, opts QueryOpts) (<-chan interface{}, <-chan error) {
+	return rootResolver.Query(queryContext, selector, opts)
+}
+
+// KeyString converts an entity key to the string form used to index
+// entities in caches and maps
+func KeyString(key interface{}) string {
+	switch key.(type) {
+	case nil:
+		return ""
+	case string:
+		return key.(string)
+	case []byte:
+		return string(key.([]byte))
+	default:
+		return fmt.Sprintf("%v", key)
+	}
+}
+
+type queryEntry struct {
+	key    string
+	entity interface{}
+}
+
+// QueryStream applies QueryOpts to the entities found by a query and
+// delivers them on its result channel, entities with a key which has already
+// been seen are dropped. It is safe for use by concurrent producers
+type QueryStream struct {
+	queryContext context.Context
+	opts         QueryOpts
+	keyExtractor KeyExtractor
+	cres         chan interface{}
+	cerr         chan error
+	mutex        *sync.Mutex
+	seen         map[string]bool
+	entries      []queryEntry
+	sent         int
+	done         bool
+}
+
+func NewQueryStream(queryContext context.Context, opts QueryOpts, keyExtractor KeyExtractor) *QueryStream {
+	return &QueryStream{
+		queryContext: queryContext,
+		opts:         opts,
+		keyExtractor: keyExtractor,
+		cres:         make(chan interface{}),
+		cerr:         make(chan error, 1),
+		mutex:        &sync.Mutex{},
+		seen:         make(map[string]bool)}
+}
+
+func (this *QueryStream) Results() (<-chan interface{}, <-chan error) {
+	return this.cres, this.cerr
+}
+
+// Add offers an entity to the stream, it returns false once the stream
+// needs no further entities
+func (this *QueryStream) Add(entity interface{}) bool {
+	var key string
+
+	if this.keyExtractor != nil {
+		if entityKey, ok := this.keyExtractor(entity); ok {
+			key = KeyString(entityKey)
+		}
+	}
+
+	return this.AddKeyed(key, entity)
+}
+
+func (this *QueryStream) AddKeyed(key string, entity interface{}) bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if this.done {
+		return false
+	}
+
+	if key != "" {
+		if this.seen[key] {
+			return true
+		}
+		this.seen[key] = true
+	}
+
+	if this.opts.isOrdered() {
+		this.entries = append(this.entries, queryEntry{key, entity})
+		return true
+	}
+
+	return this.send(entity)
+}
+
+func (this *QueryStream) send(entity interface{}) bool {
+	if this.opts.Limit > 0 && this.sent >= this.opts.Limit {
+		this.done = true
+		return false
+	}
+
+	select {
+	case this.cres <- entity:
+		this.sent++
+	case <-this.queryContext.Done():
+		this.done = true
+		return false
+	}
+
+	if this.opts.Limit > 0 && this.sent >= this.opts.Limit {
+		this.done = true
+	}
+
+	return !this.done
+}
+
+// Close completes the stream, ordered entities are sorted and paged before
+// being sent. A non nil err is delivered after any results
+func (this *QueryStream) Close(err error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if this.opts.isOrdered() && !this.done {
+		entries, pageErr := this.page()
+
+		if pageErr != nil && err == nil {
+			err = pageErr
+		}
+
+		for _, entry := range entries {
+			if !this.send(entry.entity) {
+				break
+			}
+		}
+	}
+
+	if err == nil && this.queryContext.Err() != nil {
+		err = this.queryContext.Err()
+	}
+
+	if err != nil {
+		this.cerr <- err
+	}
+
+	this.done = true
+	close(this.cres)
+	close(this.cerr)
+}
+
+func (this *QueryStream) page() ([]queryEntry, error) {
+	entries := this.entries
+	order := this.opts.Order
+
+	if order == UNORDERED && this.opts.Less == nil && this.opts.Cursor != "" {
+		order = KEY_ASCENDING
+	}
+
+	switch {
+	case this.opts.Less != nil:
+		sort.SliceStable(entries, func(i, j int) bool {
+			return this.opts.Less(entries[i].entity, entries[j].entity)
+		})
+	case order == KEY_ASCENDING:
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	case order == KEY_DESCENDING:
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].key > entries[j].key })
+	}
+
+	if this.opts.Cursor != "" {
+		if this.opts.Less != nil {
+			index := -1
+
+			for i, entry := range entries {
+				if entry.key == this.opts.Cursor {
+					index = i
+					break
+				}
+			}
+
+			if index < 0 {
+				return nil, fmt.Errorf("Query Failed: cursor entity not found: %s", this.opts.Cursor)
+			}
+
+			entries = entries[index+1:]
+		} else {
+			index := sort.Search(len(entries), func(i int) bool {
+				if order == KEY_DESCENDING {
+					return entries[i].key < this.opts.Cursor
+				}
+				return entries[i].key > this.opts.Cursor
+			})
+
+			entries = entries[index:]
+		}
+	}
+
+	if this.opts.Offset > 0 {
+		if this.opts.Offset >= len(entries) {
+			return nil, nil
+		}
+		entries = entries[this.opts.Offset:]
+	}
+
+	return entries, nil
+}
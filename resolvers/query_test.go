@@ -0,0 +1,111 @@
+package resolvers_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/localresolver"
+	"github.com/distributed-vision/go-resources/util"
+	"github.com/distributed-vision/go-resources/util/random"
+)
+
+func queryKeys(t *testing.T, resolver resolvers.QueryResolver, selector resolvers.Selector, opts resolvers.QueryOpts) []string {
+	results, err := util.AwaitAll(resolver.Query(testContext, selector, opts))
+
+	if err != nil {
+		t.Fatal("Query failed:", err)
+	}
+
+	keys := []string{}
+
+	for _, result := range results {
+		keys = append(keys, result.(entity).key)
+	}
+
+	return keys
+}
+
+func TestCompositeQuery(t *testing.T) {
+	resolver, err := resolvers.NewCompositeResolver(testInfo)
+
+	if err != nil {
+		t.Fatal("TestCompositeQuery: NewCompositeResolver failed:", err)
+	}
+
+	value := random.RandomString(20)
+	keys := []string{}
+
+	for i := 0; i < 3; i++ {
+		component, err := localresolver.New(resolvers.NewResolverInfo(testResolverType, testResolvableTypes, nil, testExtractor, nil))
+
+		if err != nil {
+			t.Fatal("TestCompositeQuery: localresolver.New failed:", err)
+		}
+
+		// each component shares half of its entities with the previous one
+		for j := i * 5; j < i*5+10; j++ {
+			key := random.RandomString(20)
+
+			if j < len(keys) {
+				key = keys[j]
+			} else {
+				keys = append(keys, key)
+			}
+
+			component.Put(testContext, entity{key, value})
+		}
+
+		component.Put(testContext, entity{random.RandomString(20), random.RandomString(20)})
+
+		if err := resolver.RegisterComponent(component); err != nil {
+			t.Fatal("TestCompositeQuery: RegisterComponent failed:", err)
+		}
+	}
+
+	sort.Strings(keys)
+	selector := &typedSelector{value: value}
+
+	if found := queryKeys(t, resolver, selector, resolvers.QueryOpts{}); len(found) != len(keys) {
+		t.Fatalf("TestCompositeQuery: expected %d unique entities got: %d", len(keys), len(found))
+	}
+
+	if found := queryKeys(t, resolver, selector, resolvers.QueryOpts{Limit: 7}); len(found) != 7 {
+		t.Fatal("TestCompositeQuery: expected 7 entities got:", len(found))
+	}
+
+	found := queryKeys(t, resolver, selector, resolvers.QueryOpts{Order: resolvers.KEY_ASCENDING, Offset: 2, Limit: 5})
+
+	if len(found) != 5 || found[0] != keys[2] || found[4] != keys[6] {
+		t.Fatalf("TestCompositeQuery: unexpected page: %v", found)
+	}
+
+	found = queryKeys(t, resolver, selector, resolvers.QueryOpts{Order: resolvers.KEY_DESCENDING, Limit: 3})
+
+	if len(found) != 3 || found[0] != keys[len(keys)-1] || found[2] != keys[len(keys)-3] {
+		t.Fatalf("TestCompositeQuery: unexpected descending page: %v", found)
+	}
+
+	paged := []string{}
+
+	for cursor := ""; ; {
+		page := queryKeys(t, resolver, selector, resolvers.QueryOpts{Order: resolvers.KEY_ASCENDING, Cursor: cursor, Limit: 4})
+
+		if len(page) == 0 {
+			break
+		}
+
+		paged = append(paged, page...)
+		cursor = page[len(page)-1]
+	}
+
+	if len(paged) != len(keys) {
+		t.Fatalf("TestCompositeQuery: expected %d paged entities got: %d", len(keys), len(paged))
+	}
+
+	for i := range keys {
+		if paged[i] != keys[i] {
+			t.Fatalf("TestCompositeQuery: unexpected key at %d: %s", i, paged[i])
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/resolvers/breakerstate"
+	"github.com/distributed-vision/go-resources/resolvers/infokeys"
+)
+
+var DefaultRetryBackoff = 100 * time.Millisecond
+var DefaultBreakerCooldown = 30 * time.Second
+
+// ComponentState reports the health of a composite resolver's component
+type ComponentState struct {
+	ResolverType        ids.TypeIdentifier
+	EntityType          ids.TypeIdentifier
+	State               breakerstate.BreakerState
+	ConsecutiveFailures int
+	OpenedAt            time.Time
+	Successes           int64
+	Failures            int64
+	Timeouts            int64
+	Retries             int64
+	Rejections          int64
+}
+
+// breaker is a component's circuit breaker. It opens after threshold
+// consecutive failures and rejects calls until cooldown has passed, when
+// a single probe call is let through. A successful probe closes the breaker,
+// a failed one re-opens it
+type breaker struct {
+	mutex   *sync.Mutex
+	state   ComponentState
+	probing bool
+}
+
+func newBreaker() *breaker {
+	return &breaker{mutex: &sync.Mutex{}}
+}
+
+func (this *breaker) allow(now time.Time, cooldown time.Duration) bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	switch this.state.State {
+	case breakerstate.OPEN:
+		if now.Sub(this.state.OpenedAt) < cooldown {
+			this.state.Rejections++
+			return false
+		}
+		this.state.State = breakerstate.HALF_OPEN
+		fallthrough
+	case breakerstate.HALF_OPEN:
+		if this.probing {
+			this.state.Rejections++
+			return false
+		}
+		this.probing = true
+	}
+
+	return true
+}
+
+func (this *breaker) success() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.state.Successes++
+	this.state.ConsecutiveFailures = 0
+	this.state.State = breakerstate.CLOSED
+	this.probing = false
+}
+
+func (this *breaker) failure(now time.Time, threshold int, timeout bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.state.Failures++
+	this.state.ConsecutiveFailures++
+
+	if timeout {
+		this.state.Timeouts++
+	}
+
+	if threshold > 0 && (this.state.State == breakerstate.HALF_OPEN || this.state.ConsecutiveFailures >= threshold) {
+		this.state.State = breakerstate.OPEN
+		this.state.OpenedAt = now
+	}
+
+	this.probing = false
+}
+
+// abandon releases a probe whose call was cancelled by the caller
+func (this *breaker) abandon() {
+	this.mutex.Lock()
+	this.probing = false
+	this.mutex.Unlock()
+}
+
+func (this *breaker) retry() {
+	this.mutex.Lock()
+	this.state.Retries++
+	this.mutex.Unlock()
+}
+
+func (this *breaker) snapshot() ComponentState {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.state
+}
+
+// ComponentStates returns the breaker state and call statistics of each of
+// the resolver's components
+func (this *CompositeResolver) ComponentStates() []ComponentState {
+	this.componentMapMutex.Lock()
+	defer this.componentMapMutex.Unlock()
+
+	states := []ComponentState{}
+
+	for _, entries := range this.componentMap {
+		for _, entry := range entries {
+			state := entry.breaker.snapshot()
+			state.ResolverType = entry.ResolverInfo().ResolverType()
+			state.EntityType = entry.entityType
+			states = append(states, state)
+		}
+	}
+
+	return states
+}
+
+// resolveComponent resolves selector with a single component, applying the
+// TIMEOUT, RETRIES and BREAKER_THRESHOLD policies declared in the component's
+// resolver info
+func (this *CompositeResolver) resolveComponent(resolutionContext context.Context, entry *componentEntry, selector Selector) (result *componentResult) {
+	info := entry.ResolverInfo()
+	timeout := durationInfoValue(info, infokeys.TIMEOUT, 0)
+	retries := intInfoValue(info, infokeys.RETRIES, 0)
+	backoff := durationInfoValue(info, infokeys.RETRY_BACKOFF, DefaultRetryBackoff)
+	threshold := intInfoValue(info, infokeys.BREAKER_THRESHOLD, 0)
+	cooldown := durationInfoValue(info, infokeys.BREAKER_COOLDOWN, DefaultBreakerCooldown)
+
+	tries := 0
+
+	if attempt := this.explanation(resolutionContext).begin(entry, this.clock.Now()); attempt != nil {
+		defer func() {
+			attempt.end(resolutionContext, result, tries, this.clock.Now())
+		}()
+	}
+
+	if !entry.breaker.allow(this.clock.Now(), cooldown) {
+		return &componentResult{entry, nil,
+			NewTemporaryError(fmt.Sprintf("Resolve Failed: circuit open for %v", info.ResolverType()), nil)}
+	}
+
+	for attempt := 0; ; attempt++ {
+		tries++
+		var timedOut bool
+		result, timedOut = this.resolveAttempt(resolutionContext, entry, selector, timeout)
+
+		if result.err == nil {
+			entry.breaker.success()
+			return result
+		}
+
+		if resolutionContext.Err() != nil {
+			entry.breaker.abandon()
+			return result
+		}
+
+		if IsNotFound(result.err) {
+			entry.breaker.success()
+			return result
+		}
+
+		if attempt >= retries || !IsRetryable(result.err) {
+			entry.breaker.failure(this.clock.Now(), threshold, timedOut)
+			return result
+		}
+
+		entry.breaker.retry()
+
+		select {
+		case <-this.clock.After(backoff << uint(attempt)):
+		case <-resolutionContext.Done():
+			entry.breaker.abandon()
+			return &componentResult{entry, nil, resolutionContext.Err()}
+		}
+	}
+}
+
+func (this *CompositeResolver) resolveAttempt(resolutionContext context.Context, entry *componentEntry, selector Selector, timeout time.Duration) (*componentResult, bool) {
+	resolver, err := entry.instance(resolutionContext)
+
+	if err != nil {
+		return &componentResult{entry, nil, err}, false
+	}
+
+	var expired <-chan time.Time
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		resolutionContext, cancel = context.WithCancel(resolutionContext)
+		defer cancel()
+		expired = this.clock.After(timeout)
+	}
+
+	resolutionContext, span := GetInstrumentation().StartSpan(resolutionContext, OP_RESOLVE, resolver.ResolverInfo().ResolverType())
+	result, timedOut := this.awaitComponent(resolutionContext, entry, resolver, selector, timeout, expired)
+	span.End(result.err)
+
+	return result, timedOut
+}
+
+func (this *CompositeResolver) awaitComponent(resolutionContext context.Context, entry *componentEntry, resolver Resolver, selector Selector, timeout time.Duration, expired <-chan time.Time) (*componentResult, bool) {
+	cres, cerr := resolver.Resolve(resolutionContext, selector)
+
+	if cres == nil || cerr == nil {
+		return &componentResult{entry, nil, fmt.Errorf("Resolve Failed: Resolve channels are undefined")}, false
+	}
+
+	for cres != nil || cerr != nil {
+		select {
+		case res, ok := <-cres:
+			if ok {
+				return &componentResult{entry, res, nil}, false
+			}
+			cres = nil
+		case err, ok := <-cerr:
+			if ok {
+				return &componentResult{entry, nil, err}, false
+			}
+			cerr = nil
+		case <-expired:
+			return &componentResult{entry, nil,
+				NewTimeout(fmt.Sprintf("Resolve Failed: %v timed out after %v", resolver.ResolverInfo().ResolverType(), timeout), nil)}, true
+		case <-resolutionContext.Done():
+			return &componentResult{entry, nil, resolutionContext.Err()}, false
+		}
+	}
+
+	return &componentResult{entry, nil, fmt.Errorf("Resolve Failed: no result for %v", selector)}, false
+}
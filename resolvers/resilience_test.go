@@ -0,0 +1,176 @@
+package resolvers_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/breakerstate"
+	"github.com/distributed-vision/go-resources/resolvers/infokeys"
+	"github.com/distributed-vision/go-resources/util/clock"
+	"github.com/distributed-vision/go-resources/util/random"
+)
+
+// flakyResolver fails with a temporary error until failures calls have
+// been made
+type flakyResolver struct {
+	*stubResolver
+	failures int32
+}
+
+func (this *flakyResolver) Resolve(resolutionContext context.Context, selector resolvers.Selector) (chan interface{}, chan error) {
+	if atomic.AddInt32(&this.failures, -1) >= 0 {
+		atomic.AddInt32(&this.calls, 1)
+		cres, cerr := make(chan interface{}), make(chan error, 1)
+		cerr <- resolvers.NewTemporaryError("unavailable", nil)
+		close(cres)
+		close(cerr)
+		return cres, cerr
+	}
+
+	return this.stubResolver.Resolve(resolutionContext, selector)
+}
+
+func newResilientResolver(t *testing.T, component resolvers.Resolver) (*resolvers.CompositeResolver, *clock.Manual) {
+	resolver, err := resolvers.NewCompositeResolver(testInfo)
+
+	if err != nil {
+		t.Fatal("NewCompositeResolver failed:", err)
+	}
+
+	testClock := clock.NewManual(time.Now())
+	resolver.SetClock(testClock)
+	resolver.RegisterComponent(component)
+
+	return resolver, testClock
+}
+
+// getAdvancing resolves a random key, advancing the clock by step whenever
+// the resolution is waiting on it
+func getAdvancing(resolver resolvers.Resolver, testClock *clock.Manual, step time.Duration) (interface{}, error) {
+	type result struct {
+		entity interface{}
+		err    error
+	}
+
+	done := make(chan result, 1)
+
+	go func() {
+		entity, err := resolver.Get(testContext, &typedSelector{key: random.RandomString(20)})
+		done <- result{entity, err}
+	}()
+
+	for {
+		select {
+		case result := <-done:
+			return result.entity, result.err
+		case <-time.After(time.Millisecond):
+			if testClock.Waiters() > 0 {
+				testClock.Advance(step)
+			}
+		}
+	}
+}
+
+func TestComponentTimeout(t *testing.T) {
+	slow := newStubResolver(0, time.Hour, "slow", nil)
+	slow.resolverInfo = slow.resolverInfo.WithValue(infokeys.TIMEOUT, "5s")
+	resolver, testClock := newResilientResolver(t, slow)
+
+	start := time.Now()
+	_, err := getAdvancing(resolver, testClock, 5*time.Second)
+
+	if !resolvers.IsRetryable(err) {
+		t.Fatal("TestComponentTimeout: expected a retryable timeout error got:", err)
+	}
+
+	if time.Since(start) > 10*time.Second {
+		t.Fatal("TestComponentTimeout: resolve should not wait for the slow component")
+	}
+
+	if states := resolver.ComponentStates(); len(states) != 1 || states[0].Timeouts != 1 {
+		t.Fatalf("TestComponentTimeout: expected a recorded timeout got: %+v", states)
+	}
+}
+
+func TestComponentRetries(t *testing.T) {
+	flaky := &flakyResolver{newStubResolver(0, 0, "value", nil), 2}
+	flaky.resolverInfo = flaky.resolverInfo.WithValues(map[interface{}]interface{}{
+		infokeys.RETRIES:       2,
+		infokeys.RETRY_BACKOFF: time.Second})
+	resolver, testClock := newResilientResolver(t, flaky)
+
+	resolved, err := getAdvancing(resolver, testClock, time.Second)
+
+	if err != nil || resolved.(entity).value != "value" {
+		t.Fatal("TestComponentRetries: expected retries to succeed got:", resolved, err)
+	}
+
+	if flaky.calls != 3 {
+		t.Fatal("TestComponentRetries: expected 3 calls got:", flaky.calls)
+	}
+
+	if states := resolver.ComponentStates(); states[0].Retries != 2 || states[0].Successes != 1 {
+		t.Fatalf("TestComponentRetries: unexpected state: %+v", states[0])
+	}
+
+	flaky.failures = 3
+
+	if _, err := getAdvancing(resolver, testClock, time.Second); err == nil {
+		t.Fatal("TestComponentRetries: expected failure once retries are exhausted")
+	}
+
+	if _, err := resolver.Get(testContext, &typedSelector{key: random.RandomString(20)}); err != nil {
+		t.Fatal("TestComponentRetries: expected recovered component to succeed got:", err)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	failing := newStubResolver(0, 0, "value", fmt.Errorf("broken"))
+	failing.resolverInfo = failing.resolverInfo.WithValues(map[interface{}]interface{}{
+		"breakerThreshold": 2,
+		"breakerCooldown":  "1m"})
+	resolver, testClock := newResilientResolver(t, failing)
+
+	state := func() resolvers.ComponentState {
+		return resolver.ComponentStates()[0]
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := resolveValue(t, resolver); err == nil {
+			t.Fatal("TestCircuitBreaker: expected failure")
+		}
+	}
+
+	if state().State != breakerstate.OPEN {
+		t.Fatal("TestCircuitBreaker: expected open breaker got:", state().State)
+	}
+
+	if _, err := resolveValue(t, resolver); err == nil || failing.calls != 2 {
+		t.Fatal("TestCircuitBreaker: open breaker should reject calls, calls:", failing.calls)
+	}
+
+	testClock.Advance(time.Minute)
+
+	if _, err := resolveValue(t, resolver); err == nil || failing.calls != 3 {
+		t.Fatal("TestCircuitBreaker: expected a failed probe, calls:", failing.calls)
+	}
+
+	if state().State != breakerstate.OPEN || state().Rejections != 1 {
+		t.Fatalf("TestCircuitBreaker: failed probe should re-open the breaker: %+v", state())
+	}
+
+	testClock.Advance(time.Minute)
+	failing.err = nil
+
+	if _, err := resolveValue(t, resolver); err != nil {
+		t.Fatal("TestCircuitBreaker: expected a successful probe got:", err)
+	}
+
+	if state().State != breakerstate.CLOSED || state().ConsecutiveFailures != 0 {
+		t.Fatalf("TestCircuitBreaker: successful probe should close the breaker: %+v", state())
+	}
+}
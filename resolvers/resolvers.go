@@ -6,6 +6,8 @@ import (
 	"sync"
 
 	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/resolvers/changetype"
+	"github.com/distributed-vision/go-resources/util"
 )
 
 type Selector interface {
@@ -27,6 +29,35 @@ type MutableResolver interface {
 	Delete(resolutionContext context.Context, selector Selector) error
 }
 
+// ChangeEvent reports a change to the entity stored under Key. PUT events
+// are reported for new entities, or by resolvers which can't tell whether
+// the entity existed, and UPDATE events for replaced entities. Entity is
+// the entity written, or for DELETE events the deleted entity if the
+// resolver knows it
+type ChangeEvent struct {
+	Type   changetype.ChangeType
+	Key    interface{}
+	Entity interface{}
+}
+
+// ChangeListener is called after a change to an entity, before the write
+// which caused it returns
+type ChangeListener func(event ChangeEvent)
+
+// ChangeNotifier is implemented by resolvers which report the writes made
+// to them, composite resolvers use it to invalidate their caches
+type ChangeNotifier interface {
+	OnChange(listener ChangeListener)
+}
+
+// Watchable is implemented by resolvers which report changes to their
+// entities. Watch returns the changes to entities matching selector, or to
+// all entities if selector is nil, until watchContext is done. The events
+// channel must be drained, as writes wait for their events to be delivered
+type Watchable interface {
+	Watch(watchContext context.Context, selector Selector) <-chan ChangeEvent
+}
+
 type ResolverInfo interface {
 	ResolverType() ids.TypeIdentifier
 	IsMutable() bool
@@ -60,6 +91,27 @@ func Resolve(resolutionContext context.Context, selector Selector) (chan interfa
 	return rootResolver.Resolve(resolutionContext, selector)
 }
 
+// GetMany resolves each of selectors with the root resolver
+func GetMany(resolutionContext context.Context, selectors []Selector) []Result {
+	return rootResolver.GetMany(resolutionContext, selectors)
+}
+
+// ResolveFuture resolves selector with the root resolver, cancelling the
+// returned future cancels the resolution
+func ResolveFuture(resolutionContext context.Context, selector Selector) *util.Future[interface{}] {
+	return rootResolver.ResolveFuture(resolutionContext, selector)
+}
+
+// Describe returns a snapshot of the root resolver's components and cache
+func Describe() Description {
+	return rootResolver.Describe()
+}
+
+// Explain reports how the root resolver resolves selector
+func Explain(resolutionContext context.Context, selector Selector) *Explanation {
+	return rootResolver.Explain(resolutionContext, selector)
+}
+
 func RegisterResolver(resolver Resolver) {
 	rootResolver.RegisterComponent(resolver)
 }
@@ -71,6 +123,7 @@ func RegisterFactory(resolverFactory ResolverFactory) {
 type NewFactoryFunction func(resolverInfo ResolverInfo) (ResolverFactory, error)
 
 var newFactoryFunctionRegistry map[string]NewFactoryFunction = make(map[string]NewFactoryFunction)
+var newFactoryFunctionTypes map[string]ids.TypeIdentifier = make(map[string]ids.TypeIdentifier)
 var newFactoryFunctionRegistryMutex = &sync.Mutex{}
 
 func NewResolverFactory(resolverInfo ResolverInfo) (ResolverFactory, error) {
@@ -88,9 +141,25 @@ func ResisterNewFactoryFunction(resolverType ids.TypeIdentifier, newFactoryFunct
 	newFactoryFunctionRegistryMutex.Lock()
 	//fmt.Printf("Regestering func for: %v\n", resolverType)
 	newFactoryFunctionRegistry[string(resolverType.Value())] = newFactoryFunction
+	newFactoryFunctionTypes[string(resolverType.Value())] = resolverType
 	newFactoryFunctionRegistryMutex.Unlock()
 }
 
+// FactoryTypes returns the resolver types which have a registered factory
+// function
+func FactoryTypes() []ids.TypeIdentifier {
+	newFactoryFunctionRegistryMutex.Lock()
+	defer newFactoryFunctionRegistryMutex.Unlock()
+
+	factoryTypes := make([]ids.TypeIdentifier, 0, len(newFactoryFunctionTypes))
+
+	for _, factoryType := range newFactoryFunctionTypes {
+		factoryTypes = append(factoryTypes, factoryType)
+	}
+
+	return factoryTypes
+}
+
 type resolverInfo struct {
 	resolverType      ids.TypeIdentifier
 	resolvableTypes   []ids.TypeIdentifier
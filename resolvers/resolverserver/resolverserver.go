@@ -0,0 +1,580 @@
+package resolverserver
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/distributed-vision/go-resources/encoding/encodertype"
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/ids/domain"
+	"github.com/distributed-vision/go-resources/ids/identifier"
+	"github.com/distributed-vision/go-resources/ids/schemevisibility"
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/operationtype"
+	"github.com/distributed-vision/go-resources/translators"
+	"github.com/distributed-vision/go-resources/types/gotypeid"
+	"github.com/distributed-vision/go-resources/util"
+)
+
+var contentType ids.TypeIdentifier = gotypeid.IdOf(reflect.TypeOf(map[string]interface{}{}))
+
+// SelectorFactory creates the selector for a request, key is empty for
+// queries
+type SelectorFactory func(request *http.Request, key string) (resolvers.Selector, error)
+
+type Options struct {
+	// EntityType is the type of entity served, entities are translated to
+	// and from json maps if translators are registered for it
+	EntityType ids.TypeIdentifier
+	Selector   SelectorFactory
+	// Authorizer authorizes each request's operation on behalf of its
+	// principal, the resolver is wrapped with an AuthorizingResolver so that
+	// the entities read and written are authorized too
+	Authorizer resolvers.Authorizer
+	// Principal identifies the caller of a request, it is passed to the
+	// resolver in the resolution context
+	Principal func(request *http.Request) resolvers.Principal
+	// Visible decides whether entities with a scheme visibility are served,
+	// by default only PUBLIC and UNTYPED entities are
+	Visible     func(request *http.Request, visibility ids.SchemeVisibility) bool
+	MaxPageSize int
+}
+
+var DefaultMaxPageSize = 1000
+
+// Server exposes a resolver as a REST api. GET /key returns an entity,
+// GET / lists entities using the limit, offset, cursor and order query
+// parameters, and if the resolver is a MutableResolver PUT, POST and DELETE
+// of /key write entities
+type Server struct {
+	resolver resolvers.Resolver
+	base     resolvers.Resolver
+	opts     Options
+}
+
+func New(resolver resolvers.Resolver, opts Options) *Server {
+	base := resolver
+
+	if opts.Authorizer != nil {
+		resolver, _ = resolvers.NewAuthorizingResolver(resolver, opts.Authorizer)
+	}
+
+	if opts.Selector == nil {
+		opts.Selector = defaultSelector(opts.EntityType, resolver.ResolverInfo().KeyExtractor())
+	}
+
+	if opts.Visible == nil {
+		opts.Visible = func(request *http.Request, visibility ids.SchemeVisibility) bool {
+			return visibility == schemevisibility.PUBLIC || visibility == schemevisibility.UNTYPED
+		}
+	}
+
+	if opts.MaxPageSize <= 0 {
+		opts.MaxPageSize = DefaultMaxPageSize
+	}
+
+	return &Server{resolver, base, opts}
+}
+
+type keySelector struct {
+	entityType   ids.TypeIdentifier
+	key          string
+	keyExtractor resolvers.KeyExtractor
+}
+
+func (this *keySelector) Type() ids.TypeIdentifier {
+	return this.entityType
+}
+
+func (this *keySelector) Key() interface{} {
+	if this.key == "" {
+		return nil
+	}
+
+	return this.key
+}
+
+func (this *keySelector) Test(candidate interface{}) bool {
+	if this.key == "" {
+		return true
+	}
+
+	if this.keyExtractor == nil {
+		return false
+	}
+
+	key, ok := this.keyExtractor(candidate)
+
+	return ok && resolvers.KeyString(key) == this.key
+}
+
+func defaultSelector(entityType ids.TypeIdentifier, keyExtractor resolvers.KeyExtractor) SelectorFactory {
+	return func(request *http.Request, key string) (resolvers.Selector, error) {
+		return &keySelector{entityType, key, keyExtractor}, nil
+	}
+}
+
+type httpError struct {
+	status int
+	err    error
+}
+
+func (this *httpError) Error() string {
+	return this.err.Error()
+}
+
+func statusOf(err error) int {
+	var httpErr *httpError
+
+	switch {
+	case errors.As(err, &httpErr):
+		return httpErr.status
+	case errors.Is(err, resolvers.ErrNotFound), errors.Is(err, resolvers.ErrNoResolverForType):
+		return http.StatusNotFound
+	case errors.Is(err, resolvers.ErrAccessDenied):
+		return http.StatusForbidden
+	case errors.Is(err, resolvers.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, resolvers.ErrAmbiguous):
+		return http.StatusMultipleChoices
+	case errors.Is(err, resolvers.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, resolvers.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(response http.ResponseWriter, err error) {
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(statusOf(err))
+	json.NewEncoder(response).Encode(map[string]string{"error": err.Error()})
+}
+
+func (this *Server) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	key, err := url.PathUnescape(strings.TrimPrefix(request.URL.EscapedPath(), "/"))
+
+	if err != nil {
+		writeError(response, &httpError{http.StatusBadRequest, err})
+		return
+	}
+
+	var operation operationtype.OperationType
+
+	switch request.Method {
+	case http.MethodGet, http.MethodHead:
+		operation = operationtype.GET
+		if key == "" {
+			operation = operationtype.QUERY
+		}
+	case http.MethodPut:
+		operation = operationtype.PUT
+	case http.MethodPost:
+		operation = operationtype.POST
+	case http.MethodDelete:
+		operation = operationtype.DELETE
+	default:
+		writeError(response, &httpError{http.StatusMethodNotAllowed, fmt.Errorf("Method not allowed: %s", request.Method)})
+		return
+	}
+
+	if key == "" && operation != operationtype.QUERY {
+		writeError(response, &httpError{http.StatusMethodNotAllowed, fmt.Errorf("%s requires an entity key", request.Method)})
+		return
+	}
+
+	if this.opts.Principal != nil {
+		if principal := this.opts.Principal(request); principal != nil {
+			request = request.WithContext(resolvers.WithPrincipal(request.Context(), principal))
+		}
+	}
+
+	if this.opts.Authorizer != nil {
+		var accessKey interface{}
+
+		if key != "" {
+			accessKey = key
+		}
+
+		access := resolvers.NewAccess(request.Context(), operation, this.opts.EntityType, accessKey)
+
+		if err := this.opts.Authorizer.Authorize(request.Context(), access); err != nil {
+			writeError(response, err)
+			return
+		}
+	}
+
+	switch operation {
+	case operationtype.GET:
+		err = this.get(response, request, key)
+	case operationtype.QUERY:
+		err = this.query(response, request)
+	default:
+		err = this.write(response, request, operation, key)
+	}
+
+	if err != nil {
+		writeError(response, err)
+	}
+}
+
+// visibility returns the scheme visibility of an entity, entities which are
+// not schemes take the visibility of their scheme
+func visibility(entity interface{}) (ids.SchemeVisibility, bool) {
+	switch entity.(type) {
+	case ids.Scheme:
+		return entity.(ids.Scheme).Visibility(), true
+	case ids.Domain:
+		if scheme := entity.(ids.Domain).Scheme(); scheme != nil {
+			return scheme.Visibility(), true
+		}
+	case map[string]interface{}:
+		if value, ok := entity.(map[string]interface{})["visibility"].(string); ok {
+			if visibility, err := schemevisibility.Parse(value); err == nil {
+				return visibility, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+func (this *Server) visible(request *http.Request, entity interface{}) bool {
+	if visibility, ok := visibility(entity); ok {
+		return this.opts.Visible(request, visibility)
+	}
+
+	return true
+}
+
+// encode converts an entity to a json map using a translator for its type or
+// the served entity type, failing if there is none
+func (this *Server) encode(encodeContext context.Context, entity interface{}) (interface{}, error) {
+	if this.opts.EntityType == nil {
+		return translators.ToMap(encodeContext, entity)
+	}
+
+	return translators.ToMap(encodeContext, entity, this.opts.EntityType)
+}
+
+var untypedLocalDomain []byte = domain.MustDecodeId(encodertype.BASE62, "3", "")
+
+func (this *Server) decode(decodeContext context.Context, key string, request *http.Request) (interface{}, error) {
+	var jsonEntity map[string]interface{}
+
+	if err := json.NewDecoder(request.Body).Decode(&jsonEntity); err != nil {
+		return nil, &httpError{http.StatusBadRequest, err}
+	}
+
+	if this.opts.EntityType == nil || !translators.Exists(contentType, this.opts.EntityType) {
+		if _, ok := jsonEntity["id"]; !ok {
+			jsonEntity["id"] = key
+		}
+		return jsonEntity, nil
+	}
+
+	entityId, err := identifier.New(untypedLocalDomain, []byte(key), nil)
+
+	if err != nil {
+		return nil, &httpError{http.StatusBadRequest, err}
+	}
+
+	entity, err := translators.TranslateFuture(decodeContext, contentType, entityId, jsonEntity, this.opts.EntityType).Await()
+
+	if err != nil {
+		return nil, &httpError{http.StatusBadRequest, err}
+	}
+
+	return entity, nil
+}
+
+// writeJSON writes value with etag, or if etag is empty with a hash of the
+// encoded value
+func writeJSON(response http.ResponseWriter, request *http.Request, value interface{}, etag string) error {
+	body, err := json.Marshal(value)
+
+	if err != nil {
+		return err
+	}
+
+	if etag == "" {
+		hash := sha1.Sum(body)
+		etag = `"` + hex.EncodeToString(hash[:]) + `"`
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	response.Header().Set("ETag", etag)
+
+	if request.Header.Get("If-None-Match") == etag {
+		response.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	if request.Method != http.MethodHead {
+		response.Write(body)
+	}
+
+	return nil
+}
+
+func (this *Server) get(response http.ResponseWriter, request *http.Request, key string) error {
+	selector, err := this.opts.Selector(request, key)
+
+	if err != nil {
+		return &httpError{http.StatusBadRequest, err}
+	}
+
+	var entity interface{}
+	var etag string
+
+	if revisionedResolver, ok := this.resolver.(resolvers.RevisionedResolver); ok {
+		var revision resolvers.Revision
+
+		if entity, revision, err = revisionedResolver.GetRevision(request.Context(), selector); err == nil {
+			etag = toETag(revision)
+		}
+	} else {
+		entity, err = this.resolver.Get(request.Context(), selector)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !this.visible(request, entity) {
+		return resolvers.NewEntityNotFound(fmt.Sprintf("Can't resolve entity for %s", key), nil)
+	}
+
+	encoded, err := this.encode(request.Context(), entity)
+
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(response, request, encoded, etag)
+}
+
+func queryOpts(request *http.Request, maxPageSize int) (resolvers.QueryOpts, error) {
+	values := request.URL.Query()
+	opts := resolvers.QueryOpts{Limit: maxPageSize, Cursor: values.Get("cursor")}
+
+	for name, field := range map[string]*int{"limit": &opts.Limit, "offset": &opts.Offset} {
+		if value := values.Get(name); value != "" {
+			intValue, err := strconv.Atoi(value)
+
+			if err != nil || intValue < 0 {
+				return opts, &httpError{http.StatusBadRequest, fmt.Errorf("Invalid %s: %s", name, value)}
+			}
+
+			*field = intValue
+		}
+	}
+
+	if opts.Limit == 0 || opts.Limit > maxPageSize {
+		opts.Limit = maxPageSize
+	}
+
+	switch strings.ToLower(values.Get("order")) {
+	case "", "asc":
+		opts.Order = resolvers.KEY_ASCENDING
+	case "desc":
+		opts.Order = resolvers.KEY_DESCENDING
+	case "none":
+		opts.Order = resolvers.UNORDERED
+	default:
+		return opts, &httpError{http.StatusBadRequest, fmt.Errorf("Invalid order: %s", values.Get("order"))}
+	}
+
+	return opts, nil
+}
+
+func (this *Server) query(response http.ResponseWriter, request *http.Request) error {
+	queryResolver, ok := this.resolver.(resolvers.QueryResolver)
+
+	if !ok {
+		return &httpError{http.StatusMethodNotAllowed, fmt.Errorf("Resolver does not support queries")}
+	}
+
+	opts, err := queryOpts(request, this.opts.MaxPageSize)
+
+	if err != nil {
+		return err
+	}
+
+	selector, err := this.opts.Selector(request, "")
+
+	if err != nil {
+		return &httpError{http.StatusBadRequest, err}
+	}
+
+	results, err := util.AwaitAll(queryResolver.Query(request.Context(), selector, opts))
+
+	if err != nil {
+		return err
+	}
+
+	entities := []interface{}{}
+	keyExtractor := this.resolver.ResolverInfo().KeyExtractor()
+	var cursor string
+
+	for _, entity := range results {
+		if keyExtractor != nil {
+			if key, ok := keyExtractor(entity); ok {
+				cursor = resolvers.KeyString(key)
+			}
+		}
+
+		if !this.visible(request, entity) {
+			continue
+		}
+
+		encoded, err := this.encode(request.Context(), entity)
+
+		if err != nil {
+			return err
+		}
+
+		entities = append(entities, encoded)
+	}
+
+	page := map[string]interface{}{"entities": entities}
+
+	if len(results) == opts.Limit && cursor != "" {
+		page["cursor"] = cursor
+	}
+
+	return writeJSON(response, request, page, "")
+}
+
+func (this *Server) write(response http.ResponseWriter, request *http.Request, operation operationtype.OperationType, key string) error {
+	mutableResolver, ok := this.resolver.(resolvers.MutableResolver)
+
+	if !ok {
+		return &httpError{http.StatusMethodNotAllowed, fmt.Errorf("Resolver is read only")}
+	}
+
+	selector, err := this.opts.Selector(request, key)
+
+	if err != nil {
+		return &httpError{http.StatusBadRequest, err}
+	}
+
+	if err := this.checkTarget(request, selector, key); err != nil {
+		return err
+	}
+
+	switch operation {
+	case operationtype.DELETE:
+		err = mutableResolver.Delete(request.Context(), selector)
+	default:
+		var entity interface{}
+		entity, err = this.decode(request.Context(), key, request)
+
+		if err != nil {
+			return err
+		}
+
+		if keyExtractor := this.resolver.ResolverInfo().KeyExtractor(); keyExtractor != nil {
+			if entityKey, ok := keyExtractor(entity); !ok || resolvers.KeyString(entityKey) != key {
+				return &httpError{http.StatusBadRequest, fmt.Errorf("Entity key does not match: %s", key)}
+			}
+		}
+
+		if revision, conditional := precondition(request); conditional {
+			return this.writeIfMatch(response, request, entity, revision)
+		}
+
+		if operation == operationtype.PUT {
+			_, err = mutableResolver.Put(request.Context(), entity)
+		} else {
+			_, err = mutableResolver.Post(request.Context(), entity)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// checkTarget fails as not found if the entity a write would replace or
+// delete isn't visible to the request, the write itself authorizes the
+// entity so it is read from the unauthorized resolver
+func (this *Server) checkTarget(request *http.Request, selector resolvers.Selector, key string) error {
+	entity, err := this.base.Get(request.Context(), selector)
+
+	if errors.Is(err, resolvers.ErrNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !this.visible(request, entity) {
+		return resolvers.NewEntityNotFound(fmt.Sprintf("Can't resolve entity for %s", key), nil)
+	}
+
+	return nil
+}
+
+func toETag(revision resolvers.Revision) string {
+	if revision == resolvers.NoRevision {
+		return ""
+	}
+
+	return `"` + string(revision) + `"`
+}
+
+// precondition returns the revision required by a conditional write, an
+// If-None-Match of * requires that the entity doesn't exist
+func precondition(request *http.Request) (resolvers.Revision, bool) {
+	if etag := request.Header.Get("If-Match"); etag != "" {
+		return resolvers.Revision(strings.Trim(etag, `"`)), true
+	}
+
+	if request.Header.Get("If-None-Match") == "*" {
+		return resolvers.NoRevision, true
+	}
+
+	return resolvers.NoRevision, false
+}
+
+// writeIfMatch writes entity if its revision is revision, failing with
+// Precondition Failed if it isn't
+func (this *Server) writeIfMatch(response http.ResponseWriter, request *http.Request, entity interface{}, revision resolvers.Revision) error {
+	revisionedResolver, ok := this.resolver.(resolvers.RevisionedResolver)
+
+	if !ok {
+		return &httpError{http.StatusBadRequest, fmt.Errorf("Resolver does not support conditional writes")}
+	}
+
+	written, err := revisionedResolver.PostIfMatch(request.Context(), entity, revision)
+
+	if errors.Is(err, resolvers.ErrConflict) {
+		return &httpError{http.StatusPreconditionFailed, err}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if etag := toETag(written); etag != "" {
+		response.Header().Set("ETag", etag)
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+	return nil
+}
@@ -0,0 +1,300 @@
+package resolverserver_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/fileresolver"
+	"github.com/distributed-vision/go-resources/resolvers/httpresolver"
+	"github.com/distributed-vision/go-resources/resolvers/localresolver"
+	"github.com/distributed-vision/go-resources/resolvers/operationtype"
+	"github.com/distributed-vision/go-resources/resolvers/resolverserver"
+	"github.com/distributed-vision/go-resources/types/gotypeid"
+)
+
+var contentType = gotypeid.IdOf(reflect.TypeOf(map[string]interface{}{}))
+var testContext = context.Background()
+
+func idExtractor(e ...interface{}) (interface{}, bool) {
+	if entity, ok := e[0].(map[string]interface{}); ok {
+		id, ok := entity["id"].(string)
+		return id, ok
+	}
+
+	return nil, false
+}
+
+type selector struct {
+	key string
+}
+
+func (this *selector) Type() ids.TypeIdentifier {
+	return contentType
+}
+
+func (this *selector) Key() interface{} {
+	return this.key
+}
+
+func (this *selector) Test(candidate interface{}) bool {
+	entity, ok := candidate.(map[string]interface{})
+	return ok && entity["id"] == this.key
+}
+
+type page struct {
+	Entities []map[string]interface{}
+	Cursor   string
+}
+
+func getPage(t *testing.T, url string) page {
+	response, err := http.Get(url)
+
+	if err != nil {
+		t.Fatal("GET failed:", err)
+	}
+
+	defer response.Body.Close()
+
+	var result page
+
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Fatal("Invalid page:", err)
+	}
+
+	return result
+}
+
+func TestServerRoundTrip(t *testing.T) {
+	local, _ := localresolver.New(localresolver.NewResolverInfo(
+		[]ids.TypeIdentifier{contentType}, nil, idExtractor, nil))
+
+	server := httptest.NewServer(resolverserver.New(local, resolverserver.Options{
+		EntityType: contentType,
+		Authorizer: resolvers.AuthorizerFunc(func(authorizationContext context.Context, access *resolvers.Access) error {
+			if access.Operation == operationtype.DELETE && (access.Principal == nil || access.Principal.Name() != "admin") {
+				return resolvers.NewAccessDenied(access, "delete requires admin")
+			}
+			return nil
+		}),
+		Principal: func(request *http.Request) resolvers.Principal {
+			if name := request.Header.Get("Authorization"); name != "" {
+				return resolvers.NewPrincipal(name)
+			}
+			return nil
+		}}))
+	defer server.Close()
+
+	client, err := httpresolver.New(server.URL, httpresolver.NewResolverInfo(
+		[]ids.TypeIdentifier{contentType}, nil, idExtractor, nil))
+
+	if err != nil {
+		t.Fatal("TestServerRoundTrip: httpresolver.New failed:", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		entity := map[string]interface{}{"id": fmt.Sprintf("id%d", i), "visibility": "PUBLIC"}
+
+		if _, err := client.Put(testContext, entity); err != nil {
+			t.Fatal("TestServerRoundTrip: Put failed:", err)
+		}
+	}
+
+	local.Put(testContext, map[string]interface{}{"id": "zhidden", "visibility": "PRIVATE"})
+
+	if entity, err := client.Get(testContext, &selector{"id3"}); err != nil || entity.(map[string]interface{})["id"] != "id3" {
+		t.Fatal("TestServerRoundTrip: Get failed:", entity, err)
+	}
+
+	if _, err := client.Get(testContext, &selector{"zhidden"}); err == nil {
+		t.Fatal("TestServerRoundTrip: PRIVATE entities should not be served")
+	}
+
+	if _, err := client.Put(testContext, map[string]interface{}{"id": "zhidden", "visibility": "PUBLIC"}); !errors.Is(err, resolvers.ErrNotFound) {
+		t.Fatal("TestServerRoundTrip: Expected Put over a PRIVATE entity to be not found, got:", err)
+	}
+
+	if _, err := client.PostIfMatch(testContext, map[string]interface{}{"id": "zhidden", "visibility": "PUBLIC"}, resolvers.NoRevision); !errors.Is(err, resolvers.ErrNotFound) {
+		t.Fatal("TestServerRoundTrip: Expected conditional write over a PRIVATE entity to be not found, got:", err)
+	}
+
+	request, _ := http.NewRequest(http.MethodDelete, server.URL+"/zhidden", nil)
+	request.Header.Set("Authorization", "admin")
+
+	if response, err := http.DefaultClient.Do(request); err != nil || response.StatusCode != http.StatusNotFound {
+		t.Fatal("TestServerRoundTrip: Expected Delete of a PRIVATE entity to be not found, got:", response, err)
+	}
+
+	if entity, err := local.Get(testContext, &selector{"zhidden"}); err != nil || entity.(map[string]interface{})["visibility"] != "PRIVATE" {
+		t.Fatal("TestServerRoundTrip: PRIVATE entity should be unchanged:", entity, err)
+	}
+
+	if _, err := client.Post(testContext, map[string]interface{}{"id": "missing"}); err == nil {
+		t.Fatal("TestServerRoundTrip: Post of a missing entity should fail")
+	}
+
+	first := getPage(t, server.URL+"/?limit=2")
+	second := getPage(t, server.URL+"/?limit=2&cursor="+first.Cursor)
+
+	if len(first.Entities) != 2 || first.Entities[0]["id"] != "id0" || second.Entities[0]["id"] != "id2" {
+		t.Fatalf("TestServerRoundTrip: unexpected pages: %+v, %+v", first, second)
+	}
+
+	if all := getPage(t, server.URL+"/"); len(all.Entities) != 5 || all.Cursor != "" {
+		t.Fatalf("TestServerRoundTrip: unexpected listing: %+v", all)
+	}
+
+	if err := client.Delete(testContext, &selector{"id0"}); !errors.Is(err, resolvers.ErrAccessDenied) {
+		t.Fatal("TestServerRoundTrip: unauthorized Delete should fail")
+	}
+
+	request, _ = http.NewRequest(http.MethodDelete, server.URL+"/id0", nil)
+	request.Header.Set("Authorization", "admin")
+
+	if response, err := http.DefaultClient.Do(request); err != nil || response.StatusCode != http.StatusNoContent {
+		t.Fatal("TestServerRoundTrip: authorized Delete failed:", response, err)
+	}
+
+	if _, err := client.Get(testContext, &selector{"id0"}); err == nil {
+		t.Fatal("TestServerRoundTrip: deleted entity should be missing")
+	}
+}
+
+func TestServerPrincipal(t *testing.T) {
+	local, _ := localresolver.New(localresolver.NewResolverInfo(
+		[]ids.TypeIdentifier{contentType}, nil, idExtractor, nil))
+
+	readOnly := resolvers.AuthorizerFunc(func(authorizationContext context.Context, access *resolvers.Access) error {
+		if access.Operation.IsWrite() && (access.Principal == nil || access.Principal.Name() != "writer") {
+			return resolvers.NewAccessDenied(access, "")
+		}
+		return nil
+	})
+
+	resolver, _ := resolvers.NewAuthorizingResolver(local, readOnly)
+
+	server := httptest.NewServer(resolverserver.New(resolver, resolverserver.Options{
+		EntityType: contentType,
+		Principal: func(request *http.Request) resolvers.Principal {
+			if name := request.Header.Get("Authorization"); name != "" {
+				return resolvers.NewPrincipal(name)
+			}
+			return nil
+		}}))
+	defer server.Close()
+
+	client, _ := httpresolver.New(server.URL, httpresolver.NewResolverInfo(
+		[]ids.TypeIdentifier{contentType}, nil, idExtractor, nil))
+
+	if _, err := client.Put(testContext, map[string]interface{}{"id": "id0"}); !errors.Is(err, resolvers.ErrAccessDenied) {
+		t.Fatal("TestServerPrincipal: Expected anonymous Put to be denied, got:", err)
+	}
+
+	request, _ := http.NewRequest(http.MethodPut, server.URL+"/id0", strings.NewReader(`{"id": "id0"}`))
+	request.Header.Set("Authorization", "writer")
+
+	if response, err := http.DefaultClient.Do(request); err != nil || response.StatusCode >= 300 {
+		t.Fatal("TestServerPrincipal: writer Put failed:", response, err)
+	}
+
+	if _, err := client.Get(testContext, &selector{"id0"}); err != nil {
+		t.Fatal("TestServerPrincipal: Get failed:", err)
+	}
+}
+
+func TestServerConditionalWrite(t *testing.T) {
+	local, _ := localresolver.New(localresolver.NewResolverInfo(
+		[]ids.TypeIdentifier{contentType}, nil, idExtractor, nil))
+
+	server := httptest.NewServer(resolverserver.New(local, resolverserver.Options{EntityType: contentType}))
+	defer server.Close()
+
+	client, _ := httpresolver.New(server.URL, httpresolver.NewResolverInfo(
+		[]ids.TypeIdentifier{contentType}, nil, idExtractor, nil))
+
+	created, err := client.PostIfMatch(testContext, map[string]interface{}{"id": "id0", "value": "1"}, resolvers.NoRevision)
+
+	if err != nil || created == resolvers.NoRevision {
+		t.Fatal("TestServerConditionalWrite: create failed:", created, err)
+	}
+
+	if _, err := client.PostIfMatch(testContext, map[string]interface{}{"id": "id0", "value": "2"}, resolvers.NoRevision); !errors.Is(err, resolvers.ErrConflict) {
+		t.Fatal("TestServerConditionalWrite: Expected create of existing entity to conflict, got:", err)
+	}
+
+	entity, revision, err := client.GetRevision(testContext, &selector{"id0"})
+
+	if err != nil || revision != created || entity.(map[string]interface{})["value"] != "1" {
+		t.Fatal("TestServerConditionalWrite: GetRevision failed:", entity, revision, err)
+	}
+
+	if _, err := resolvers.Update(testContext, client, &selector{"id0"}, func(current interface{}) (interface{}, error) {
+		return map[string]interface{}{"id": "id0", "value": "2"}, nil
+	}); err != nil {
+		t.Fatal("TestServerConditionalWrite: Update failed:", err)
+	}
+
+	request, _ := http.NewRequest(http.MethodPost, server.URL+"/id0", strings.NewReader(`{"id": "id0", "value": "3"}`))
+	request.Header.Set("If-Match", `"`+string(revision)+`"`)
+
+	if response, err := http.DefaultClient.Do(request); err != nil || response.StatusCode != http.StatusPreconditionFailed {
+		t.Fatal("TestServerConditionalWrite: Expected stale If-Match to fail, got:", response, err)
+	}
+
+	if entity, err := local.Get(testContext, &selector{"id0"}); err != nil || entity.(map[string]interface{})["value"] != "2" {
+		t.Fatal("TestServerConditionalWrite: Unexpected entity:", entity, err)
+	}
+}
+
+func TestServeFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "resolverserver")
+
+	if err != nil {
+		t.Fatal("TestServeFile: TempDir failed:", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "schemeinfo.json")
+
+	ioutil.WriteFile(file, []byte(`{
+		"1": {"name": "public", "visibility": "PUBLIC"},
+		"2": {"name": "private", "visibility": "PRIVATE"}}`), 0644)
+
+	resolver, err := fileresolver.New(file, fileresolver.NewResolverInfo(
+		[]ids.TypeIdentifier{contentType}, nil, idExtractor, nil))
+
+	if err != nil {
+		t.Fatal("TestServeFile: fileresolver.New failed:", err)
+	}
+
+	server := httptest.NewServer(resolverserver.New(resolver, resolverserver.Options{EntityType: contentType}))
+	defer server.Close()
+
+	if response, err := http.Get(server.URL + "/1"); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatal("TestServeFile: GET failed:", response, err)
+	}
+
+	if response, err := http.Get(server.URL + "/2"); err != nil || response.StatusCode != http.StatusNotFound {
+		t.Fatal("TestServeFile: PRIVATE scheme should not be found:", response, err)
+	}
+
+	if response, err := http.Post(server.URL+"/1", "application/json", nil); err != nil || response.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatal("TestServeFile: writes to a read only resolver should not be allowed:", response, err)
+	}
+
+	if listing := getPage(t, server.URL+"/"); len(listing.Entities) != 1 || listing.Entities[0]["name"] != "public" {
+		t.Fatalf("TestServeFile: unexpected listing: %+v", listing)
+	}
+}
@@ -0,0 +1,79 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Revision identifies a version of a stored entity. Revisions are opaque,
+// they are only compared for equality, and change whenever the entity is
+// written. NoRevision is the revision of an entity which doesn't exist
+type Revision string
+
+const NoRevision Revision = ""
+
+// RevisionedResolver is implemented by mutable resolvers which track the
+// revisions of the entities they store. GetRevision returns an entity with
+// its current revision. PostIfMatch writes entity only if its stored
+// revision is revision, returning the entity's new revision, or a Conflict
+// if the entity has been written since revision was read. A PostIfMatch
+// with NoRevision only succeeds if the entity doesn't exist, so it creates
+// the entity
+type RevisionedResolver interface {
+	MutableResolver
+	GetRevision(resolutionContext context.Context, selector Selector) (interface{}, Revision, error)
+	PostIfMatch(resolutionContext context.Context, entity interface{}, revision Revision) (Revision, error)
+}
+
+// NewRevisionConflict returns the Conflict reported by PostIfMatch when the
+// stored revision of the entity with key isn't expected
+func NewRevisionConflict(key interface{}, expected Revision, actual Revision) *Conflict {
+	switch {
+	case expected == NoRevision:
+		return NewConflict(fmt.Sprintf("Post Failed: entity %v exists at revision %s", key, actual), nil)
+	case actual == NoRevision:
+		return NewConflict(fmt.Sprintf("Post Failed: entity %v doesn't exist, expected revision %s", key, expected), nil)
+	default:
+		return NewConflict(fmt.Sprintf("Post Failed: entity %v is at revision %s, expected revision %s", key, actual, expected), nil)
+	}
+}
+
+// DefaultUpdateAttempts is the number of times Update reads and writes an
+// entity before it gives up
+var DefaultUpdateAttempts = 10
+
+// Update applies update to the entity selected by selector and writes the
+// result with PostIfMatch, reading and updating the entity again if it is
+// written concurrently. Update is passed nil if the entity doesn't exist
+func Update(resolutionContext context.Context, resolver RevisionedResolver, selector Selector, update func(current interface{}) (interface{}, error)) (interface{}, error) {
+	var err error
+
+	for attempt := 0; attempt < DefaultUpdateAttempts; attempt++ {
+		current, revision, getErr := resolver.GetRevision(resolutionContext, selector)
+
+		if getErr != nil {
+			if !IsNotFound(getErr) {
+				return nil, getErr
+			}
+
+			current, revision = nil, NoRevision
+		}
+
+		updated, updateErr := update(current)
+
+		if updateErr != nil {
+			return nil, updateErr
+		}
+
+		if _, err = resolver.PostIfMatch(resolutionContext, updated, revision); err == nil {
+			return updated, nil
+		}
+
+		if !errors.Is(err, ErrConflict) || resolutionContext.Err() != nil {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("Update Failed: gave up after %d attempts: %w", DefaultUpdateAttempts, err)
+}
@@ -0,0 +1,456 @@
+package resolvers
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/resolvers/operatortype"
+)
+
+// SelectorOpts control how selectors compare string values
+type SelectorOpts struct {
+	IgnoreCase       bool
+	IgnoreWhitespace bool
+}
+
+// Normalize returns value in the form it is compared in
+func (this SelectorOpts) Normalize(value string) string {
+	if this.IgnoreWhitespace {
+		value = strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, value)
+	}
+
+	if this.IgnoreCase {
+		value = strings.ToUpper(value)
+	}
+
+	return value
+}
+
+func (this SelectorOpts) Equal(value string, other string) bool {
+	return this.Normalize(value) == this.Normalize(other)
+}
+
+// AndSelector selects the entities selected by all of its selectors
+type AndSelector struct {
+	Selectors []Selector
+}
+
+func And(selectors ...Selector) *AndSelector {
+	return &AndSelector{selectors}
+}
+
+// Type returns the first type declared by the selector's selectors
+func (this *AndSelector) Type() ids.TypeIdentifier {
+	for _, selector := range this.Selectors {
+		if selectorType := selector.Type(); selectorType != nil {
+			return selectorType
+		}
+	}
+
+	return nil
+}
+
+// Key returns the first key declared by the selector's selectors
+func (this *AndSelector) Key() interface{} {
+	for _, selector := range this.Selectors {
+		if key := selector.Key(); key != nil {
+			return key
+		}
+	}
+
+	return nil
+}
+
+func (this *AndSelector) Test(entity interface{}) bool {
+	for _, selector := range this.Selectors {
+		if !selector.Test(entity) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// OrSelector selects the entities selected by any of its selectors
+type OrSelector struct {
+	Selectors []Selector
+}
+
+func Or(selectors ...Selector) *OrSelector {
+	return &OrSelector{selectors}
+}
+
+// Type returns the type declared by all of the selector's selectors, or
+// nil if they differ
+func (this *OrSelector) Type() ids.TypeIdentifier {
+	var selectorType ids.TypeIdentifier
+
+	for index, selector := range this.Selectors {
+		if index == 0 {
+			selectorType = selector.Type()
+		} else if other := selector.Type(); selectorType == nil || other == nil || !selectorType.Equals(other) {
+			return nil
+		}
+	}
+
+	return selectorType
+}
+
+// Key returns the key declared by all of the selector's selectors, or nil
+// if they differ
+func (this *OrSelector) Key() interface{} {
+	var key string
+
+	for index, selector := range this.Selectors {
+		if index == 0 {
+			key = KeyString(selector.Key())
+		} else if other := KeyString(selector.Key()); key == "" || other != key {
+			return nil
+		}
+	}
+
+	if key == "" {
+		return nil
+	}
+
+	return this.Selectors[0].Key()
+}
+
+func (this *OrSelector) Test(entity interface{}) bool {
+	for _, selector := range this.Selectors {
+		if selector.Test(entity) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NotSelector selects the entities of its selector's type which its
+// selector doesn't select
+type NotSelector struct {
+	Selector Selector
+}
+
+func Not(selector Selector) *NotSelector {
+	return &NotSelector{selector}
+}
+
+func (this *NotSelector) Type() ids.TypeIdentifier {
+	return this.Selector.Type()
+}
+
+func (this *NotSelector) Key() interface{} {
+	return nil
+}
+
+func (this *NotSelector) Test(entity interface{}) bool {
+	return !this.Selector.Test(entity)
+}
+
+// TypedSelector declares the type and key of the entities selected by
+// Selector, a nil Selector selects every entity
+type TypedSelector struct {
+	EntityType ids.TypeIdentifier
+	EntityKey  interface{}
+	Selector   Selector
+}
+
+func Typed(entityType ids.TypeIdentifier, key interface{}, selector Selector) *TypedSelector {
+	return &TypedSelector{entityType, key, selector}
+}
+
+func (this *TypedSelector) Type() ids.TypeIdentifier {
+	return this.EntityType
+}
+
+func (this *TypedSelector) Key() interface{} {
+	if this.EntityKey == nil && this.Selector != nil {
+		return this.Selector.Key()
+	}
+
+	return this.EntityKey
+}
+
+func (this *TypedSelector) Test(entity interface{}) bool {
+	return this.Selector == nil || this.Selector.Test(entity)
+}
+
+// FieldAccessor returns the value of a field of entity, and false if the
+// entity doesn't have the field
+type FieldAccessor func(entity interface{}) (interface{}, bool)
+
+// FieldSelector selects entities by comparing the value of one of their
+// fields with Value. Fields are read with Accessor if it is set, otherwise
+// with FieldValue
+type FieldSelector struct {
+	Field    string
+	Operator operatortype.OperatorType
+	Value    interface{}
+	Opts     SelectorOpts
+	Accessor FieldAccessor
+}
+
+func Where(field string, operator operatortype.OperatorType, value interface{}) *FieldSelector {
+	return &FieldSelector{Field: field, Operator: operator, Value: value}
+}
+
+func Eq(field string, value interface{}) *FieldSelector {
+	return Where(field, operatortype.EQ, value)
+}
+
+func (this *FieldSelector) WithOpts(opts SelectorOpts) *FieldSelector {
+	selector := *this
+	selector.Opts = opts
+	return &selector
+}
+
+func (this *FieldSelector) WithAccessor(accessor FieldAccessor) *FieldSelector {
+	selector := *this
+	selector.Accessor = accessor
+	return &selector
+}
+
+func (this *FieldSelector) Type() ids.TypeIdentifier {
+	return nil
+}
+
+func (this *FieldSelector) Key() interface{} {
+	return nil
+}
+
+func (this *FieldSelector) Test(entity interface{}) bool {
+	var value interface{}
+	var ok bool
+
+	if this.Accessor != nil {
+		value, ok = this.Accessor(entity)
+	} else {
+		value, ok = FieldValue(entity, this.Field)
+	}
+
+	if !ok {
+		return this.Operator == operatortype.NE
+	}
+
+	return compareValues(value, this.Operator, this.Value, this.Opts)
+}
+
+func (this *FieldSelector) String() string {
+	return fmt.Sprintf("%s %v %v", this.Field, this.Operator, this.Value)
+}
+
+// FieldValue reads a field of entity by name. Dotted names read nested
+// fields. Maps are indexed by the name, other values are read from a
+// method with the name, or the name prefixed by Get, and then from a
+// struct field with the name. Method and struct field names are matched
+// with their first letter capitalised
+func FieldValue(entity interface{}, name string) (interface{}, bool) {
+	for _, part := range strings.Split(name, ".") {
+		var ok bool
+
+		if entity, ok = fieldValue(entity, part); !ok {
+			return nil, false
+		}
+	}
+
+	return entity, true
+}
+
+func fieldValue(entity interface{}, name string) (interface{}, bool) {
+	if entity == nil || name == "" {
+		return nil, false
+	}
+
+	if jsonEntity, ok := entity.(map[string]interface{}); ok {
+		value, ok := jsonEntity[name]
+		return value, ok
+	}
+
+	exported := strings.ToUpper(name[:1]) + name[1:]
+	value := reflect.ValueOf(entity)
+
+	for _, methodName := range []string{exported, "Get" + exported} {
+		if method := value.MethodByName(methodName); method.IsValid() && method.Type().NumIn() == 0 && method.Type().NumOut() == 1 {
+			return method.Call(nil)[0].Interface(), true
+		}
+	}
+
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil, false
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		if field := value.FieldByName(exported); field.IsValid() && field.CanInterface() {
+			return field.Interface(), true
+		}
+	case reflect.Map:
+		if value.Type().Key().Kind() == reflect.String {
+			if field := value.MapIndex(reflect.ValueOf(name).Convert(value.Type().Key())); field.IsValid() {
+				return field.Interface(), true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch value := reflect.ValueOf(value); value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	}
+
+	return 0, false
+}
+
+func toString(value interface{}) (string, bool) {
+	switch value := value.(type) {
+	case string:
+		return value, true
+	case fmt.Stringer:
+		return value.String(), true
+	}
+
+	if value := reflect.ValueOf(value); value.Kind() == reflect.String {
+		return value.String(), true
+	}
+
+	return "", false
+}
+
+// compare orders value and other, ok is false if they can't be ordered
+func compare(value interface{}, other interface{}, opts SelectorOpts) (order int, ok bool) {
+	if number, ok := toFloat(value); ok {
+		if otherNumber, ok := toFloat(other); ok {
+			switch {
+			case number < otherNumber:
+				return -1, true
+			case number > otherNumber:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+
+	if data, ok := value.([]byte); ok {
+		if otherData, ok := other.([]byte); ok {
+			return bytes.Compare(data, otherData), true
+		}
+	}
+
+	if moment, ok := value.(time.Time); ok {
+		if otherMoment, ok := other.(time.Time); ok {
+			switch {
+			case moment.Before(otherMoment):
+				return -1, true
+			case moment.After(otherMoment):
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+
+	if str, ok := toString(value); ok {
+		if otherStr, ok := toString(other); ok {
+			return strings.Compare(opts.Normalize(str), opts.Normalize(otherStr)), true
+		}
+	}
+
+	return 0, false
+}
+
+func compareValues(value interface{}, operator operatortype.OperatorType, other interface{}, opts SelectorOpts) bool {
+	switch operator {
+	case operatortype.PREFIX, operatortype.CONTAINS:
+		str, ok := toString(value)
+		otherStr, otherOk := toString(other)
+
+		if !ok || !otherOk {
+			return false
+		}
+
+		if operator == operatortype.PREFIX {
+			return strings.HasPrefix(opts.Normalize(str), opts.Normalize(otherStr))
+		}
+
+		return strings.Contains(opts.Normalize(str), opts.Normalize(otherStr))
+	}
+
+	order, ok := compare(value, other, opts)
+
+	if !ok {
+		switch operator {
+		case operatortype.EQ:
+			return reflect.DeepEqual(value, other)
+		case operatortype.NE:
+			return !reflect.DeepEqual(value, other)
+		}
+
+		return false
+	}
+
+	switch operator {
+	case operatortype.EQ:
+		return order == 0
+	case operatortype.NE:
+		return order != 0
+	case operatortype.LT:
+		return order < 0
+	case operatortype.LE:
+		return order <= 0
+	case operatortype.GT:
+		return order > 0
+	case operatortype.GE:
+		return order >= 0
+	}
+
+	return false
+}
+
+// Conjuncts returns the field selectors which every entity selected by
+// selector must pass, so that resolvers can apply them as filters in their
+// store. Exact is false if the selector has conditions other than the
+// field selectors, entities must still be passed to the selector's Test
+func Conjuncts(selector Selector) (fields []*FieldSelector, exact bool) {
+	switch selector := selector.(type) {
+	case *FieldSelector:
+		return []*FieldSelector{selector}, true
+	case *AndSelector:
+		exact = true
+
+		for _, selector := range selector.Selectors {
+			selectorFields, selectorExact := Conjuncts(selector)
+			fields = append(fields, selectorFields...)
+			exact = exact && selectorExact
+		}
+
+		return fields, exact
+	case *TypedSelector:
+		if selector.Selector == nil {
+			return nil, true
+		}
+
+		return Conjuncts(selector.Selector)
+	}
+
+	return nil, false
+}
@@ -0,0 +1,113 @@
+package resolvers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/operatortype"
+)
+
+type named struct {
+	Name    string
+	created time.Time
+}
+
+func (this *named) Created() time.Time {
+	return this.created
+}
+
+func TestSelectorOpts(t *testing.T) {
+	opts := resolvers.SelectorOpts{IgnoreCase: true, IgnoreWhitespace: true}
+
+	if !opts.Equal("Domain Scope", "domainscope") || !opts.Equal(" a\tb ", "AB") {
+		t.Fatal("TestSelectorOpts: expected normalized values to be equal")
+	}
+
+	if (resolvers.SelectorOpts{IgnoreCase: true}).Equal("Domain Scope", "domainscope") {
+		t.Fatal("TestSelectorOpts: whitespace should be significant")
+	}
+}
+
+func TestFieldSelector(t *testing.T) {
+	jsonEntity := map[string]interface{}{"name": "Alpha Scheme", "size": 3.0,
+		"domainInfo": map[string]interface{}{"format": "FIXED"}}
+	structEntity := &named{"Beta", time.Unix(1000, 0)}
+
+	tests := []struct {
+		selector resolvers.Selector
+		entity   interface{}
+		expected bool
+	}{
+		{resolvers.Eq("name", "Alpha Scheme"), jsonEntity, true},
+		{resolvers.Eq("name", "alpha scheme"), jsonEntity, false},
+		{resolvers.Eq("name", "alphascheme").WithOpts(resolvers.SelectorOpts{IgnoreCase: true, IgnoreWhitespace: true}), jsonEntity, true},
+		{resolvers.Where("size", operatortype.GT, 2), jsonEntity, true},
+		{resolvers.Where("size", operatortype.LE, 2), jsonEntity, false},
+		{resolvers.Where("name", operatortype.PREFIX, "Alpha"), jsonEntity, true},
+		{resolvers.Where("name", operatortype.CONTAINS, "Sch"), jsonEntity, true},
+		{resolvers.Eq("domainInfo.format", "FIXED"), jsonEntity, true},
+		{resolvers.Eq("missing", "x"), jsonEntity, false},
+		{resolvers.Where("missing", operatortype.NE, "x"), jsonEntity, true},
+		{resolvers.Eq("name", "Beta"), structEntity, true},
+		{resolvers.Where("created", operatortype.LT, time.Unix(2000, 0)), structEntity, true},
+		{resolvers.Eq("upper", "BETA").WithAccessor(func(entity interface{}) (interface{}, bool) {
+			return "BETA", true
+		}), structEntity, true},
+		{resolvers.And(resolvers.Eq("name", "Alpha Scheme"), resolvers.Where("size", operatortype.GE, 3)), jsonEntity, true},
+		{resolvers.And(resolvers.Eq("name", "Alpha Scheme"), resolvers.Where("size", operatortype.LT, 3)), jsonEntity, false},
+		{resolvers.Or(resolvers.Eq("name", "Beta"), resolvers.Eq("name", "Alpha Scheme")), jsonEntity, true},
+		{resolvers.Not(resolvers.Eq("name", "Alpha Scheme")), jsonEntity, false},
+	}
+
+	for index, test := range tests {
+		if test.selector.Test(test.entity) != test.expected {
+			t.Fatalf("TestFieldSelector: test %d %v expected %t", index, test.selector, test.expected)
+		}
+	}
+}
+
+func entityValue(candidate interface{}) (interface{}, bool) {
+	if e, ok := candidate.(entity); ok {
+		return e.value, true
+	}
+
+	return nil, false
+}
+
+func TestComposedSelectors(t *testing.T) {
+	keyed := &typedSelector{key: "1"}
+	selector := resolvers.Typed(testEntityType, nil,
+		resolvers.And(resolvers.Eq("value", "one").WithAccessor(entityValue), keyed,
+			resolvers.Where("value", operatortype.NE, "two").WithAccessor(entityValue)))
+
+	if !selector.Type().Equals(testEntityType) || selector.Key() != "1" {
+		t.Fatal("TestComposedSelectors: unexpected type or key:", selector.Type(), selector.Key())
+	}
+
+	if fields, exact := resolvers.Conjuncts(selector); len(fields) != 2 || exact {
+		t.Fatal("TestComposedSelectors: unexpected conjuncts:", fields, exact)
+	}
+
+	if fields, exact := resolvers.Conjuncts(resolvers.And(resolvers.Eq("a", 1), resolvers.Eq("b", 2))); len(fields) != 2 || !exact {
+		t.Fatal("TestComposedSelectors: expected exact conjuncts:", fields, exact)
+	}
+
+	if fields, _ := resolvers.Conjuncts(resolvers.Or(resolvers.Eq("a", 1), resolvers.Eq("b", 2))); len(fields) != 0 {
+		t.Fatal("TestComposedSelectors: Or has no conjuncts:", fields)
+	}
+
+	if or := resolvers.Or(keyed, &typedSelector{key: "2"}); or.Key() != nil || !or.Type().Equals(testEntityType) {
+		t.Fatal("TestComposedSelectors: unexpected Or key or type:", or.Key(), or.Type())
+	}
+
+	resolver := newStrategyResolver(t, testInfo, newStubResolver(0, 0, "one", nil))
+
+	if _, err := resolver.Get(testContext, selector); err != nil {
+		t.Fatal("TestComposedSelectors: Get failed:", err)
+	}
+
+	if !selector.Test(entity{"1", "one"}) || selector.Test(entity{"1", "two"}) || selector.Test(entity{"2", "one"}) {
+		t.Fatal("TestComposedSelectors: unexpected Test result")
+	}
+}
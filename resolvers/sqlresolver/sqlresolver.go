@@ -0,0 +1,659 @@
+package sqlresolver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/distributed-vision/go-resources/encoding/base62"
+	"github.com/distributed-vision/go-resources/encoding/encodertype"
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/ids/domain"
+	"github.com/distributed-vision/go-resources/ids/identifier"
+	"github.com/distributed-vision/go-resources/ids/mappings"
+	"github.com/distributed-vision/go-resources/ids/scheme"
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/changetype"
+	"github.com/distributed-vision/go-resources/resolvers/operatortype"
+	"github.com/distributed-vision/go-resources/translators"
+	"github.com/distributed-vision/go-resources/types"
+	"github.com/distributed-vision/go-resources/types/gotypeid"
+	"github.com/distributed-vision/go-resources/types/publictypeid"
+	"github.com/distributed-vision/go-resources/version"
+)
+
+var contentType ids.TypeIdentifier = gotypeid.IdOf(reflect.TypeOf(map[string]interface{}{}))
+
+var resolverType ids.TypeIdentifier = gotypeid.IdOf(reflect.TypeOf(SqlResolver{}))
+var publicTypeVersion = version.New(0, 0, 1)
+
+var PublicType = types.MustNewId(publictypeid.ResolverDomain, []byte("SqlResolver"), publicTypeVersion)
+
+const (
+	DefaultKeyColumn     = "id"
+	DefaultPayloadColumn = "payload"
+)
+
+func init() {
+	mappings.Map(context.Background(), resolverType, PublicType)
+	resolvers.ResisterNewFactoryFunction(PublicType, NewResolverFactory)
+}
+
+func ResolverType() ids.TypeIdentifier {
+	return resolverType
+}
+
+func NewResolverInfo(resolvableTypes []ids.TypeIdentifier, resolvableDomains []ids.Domain,
+	keyExtractor resolvers.KeyExtractor, values map[interface{}]interface{}) resolvers.ResolverInfo {
+	return resolvers.NewResolverInfo(PublicType,
+		resolvableTypes, resolvableDomains, keyExtractor, values)
+}
+
+type factory struct {
+	resolverInfo resolvers.ResolverInfo
+}
+
+func NewResolverFactory(resolverInfo resolvers.ResolverInfo) (resolvers.ResolverFactory, error) {
+	return &factory{resolverInfo}, nil
+}
+
+// New opens a connection pool using the "driver" and "dsn" resolver info
+// values, the pool is owned by the resolver and is closed by Close
+func (this *factory) New(resolutionContext context.Context) (resolvers.Resolver, error) {
+	driver, _ := this.resolverInfo.Value("driver").(string)
+	dsn, _ := this.resolverInfo.Value("dsn").(string)
+
+	if driver == "" || dsn == "" {
+		return nil, fmt.Errorf("ResolverInfo 'driver' and 'dsn' values can't be empty")
+	}
+
+	db, err := sql.Open(driver, dsn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resolver, err := New(db, this.resolverInfo)
+
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	resolver.owned = true
+
+	return resolver, nil
+}
+
+func (this *factory) ResolverType() ids.TypeIdentifier {
+	return resolverType
+}
+
+func (this *factory) ResolverInfo() resolvers.ResolverInfo {
+	return this.resolverInfo
+}
+
+// SqlResolver is a MutableResolver which stores entities as json payloads
+// in a single table, keyed by the resolver info's key extractor. The table
+// is configured by the "table", "keyColumn" and "payloadColumn" resolver
+// info values. The optional "columns" value maps entity fields to
+// additional columns, which are written with each entity and used to
+// narrow queries for selectors with structured fields
+type SqlResolver struct {
+	*resolvers.ChangeFeed
+	resolverInfo  resolvers.ResolverInfo
+	db            *sql.DB
+	owned         bool
+	table         string
+	keyColumn     string
+	payloadColumn string
+	columns       map[string]string
+	numbered      bool
+}
+
+// validIdentifier matches the table and column names which can be used
+// unquoted in generated statements
+var validIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func New(db *sql.DB, resolverInfo resolvers.ResolverInfo) (*SqlResolver, error) {
+	if resolverInfo == nil {
+		return nil, fmt.Errorf("base resolver info must be defined")
+	}
+
+	table, _ := resolverInfo.Value("table").(string)
+
+	if table == "" {
+		return nil, fmt.Errorf("ResolverInfo 'table' value can't be empty")
+	}
+
+	resolver := &SqlResolver{
+		ChangeFeed:    &resolvers.ChangeFeed{},
+		resolverInfo:  resolverInfo,
+		db:            db,
+		table:         table,
+		keyColumn:     stringValue(resolverInfo, "keyColumn", DefaultKeyColumn),
+		payloadColumn: stringValue(resolverInfo, "payloadColumn", DefaultPayloadColumn),
+		columns:       make(map[string]string)}
+
+	switch columns := resolverInfo.Value("columns").(type) {
+	case map[string]string:
+		for field, column := range columns {
+			resolver.columns[field] = column
+		}
+	case map[string]interface{}:
+		for field, column := range columns {
+			resolver.columns[field] = fmt.Sprint(column)
+		}
+	}
+
+	for name, identifier := range map[string]string{
+		"table": resolver.table, "keyColumn": resolver.keyColumn, "payloadColumn": resolver.payloadColumn} {
+		if !validIdentifier.MatchString(identifier) {
+			return nil, fmt.Errorf("ResolverInfo '%s' value is not a valid identifier: %q", name, identifier)
+		}
+	}
+
+	for field, column := range resolver.columns {
+		if !validIdentifier.MatchString(column) {
+			return nil, fmt.Errorf("ResolverInfo 'columns' value for %s is not a valid identifier: %q", field, column)
+		}
+	}
+
+	switch driver, _ := resolverInfo.Value("driver").(string); driver {
+	case "postgres", "pgx":
+		resolver.numbered = true
+	}
+
+	return resolver, nil
+}
+
+func stringValue(resolverInfo resolvers.ResolverInfo, key string, defaultValue string) string {
+	if value, ok := resolverInfo.Value(key).(string); ok && value != "" {
+		return value
+	}
+
+	return defaultValue
+}
+
+func (this *SqlResolver) ResolverInfo() resolvers.ResolverInfo {
+	return this.resolverInfo
+}
+
+// Close closes the resolver's connection pool if it was opened by the
+// resolver's factory
+func (this *SqlResolver) Close() error {
+	if this.owned {
+		return this.db.Close()
+	}
+
+	return nil
+}
+
+// condition is a single column comparison in a WHERE clause
+type condition struct {
+	column     string
+	operator   operatortype.OperatorType
+	value      interface{}
+	ignoreCase bool
+}
+
+// conditions translates the structured fields of selector to column
+// comparisons. Generic selectors are narrowed by their field conjuncts, and
+// by a comparison of their key which is returned separately as the key may
+// not be the stored key. As columns hold the text of field values, only
+// string comparisons which text comparison can't wrongly exclude are used,
+// other fields are left to the selector's Test
+func (this *SqlResolver) conditions(selector resolvers.Selector) ([]condition, *condition) {
+	var conditions []condition
+
+	field := func(name string, operator operatortype.OperatorType, value interface{}, opts resolvers.SelectorOpts) {
+		if _, ok := value.(string); !ok || opts.IgnoreWhitespace {
+			return
+		}
+
+		switch operator {
+		case operatortype.EQ, operatortype.PREFIX, operatortype.CONTAINS:
+			if column, ok := this.columns[name]; ok {
+				conditions = append(conditions, condition{column, operator, value, opts.IgnoreCase})
+			}
+		}
+	}
+
+	switch selector := selector.(type) {
+	case *domain.Selector:
+		if selector.Id != nil {
+			conditions = append(conditions, condition{this.keyColumn, operatortype.EQ, base62.Encode(selector.Id), false})
+		}
+		if selector.SchemeId != nil {
+			field("schemeId", operatortype.EQ, base62.Encode(selector.SchemeId), resolvers.SelectorOpts{})
+		}
+		if selector.IdRoot != nil {
+			field("idRoot", operatortype.EQ, base62.Encode(selector.IdRoot), resolvers.SelectorOpts{})
+		}
+		if selector.Name != "" {
+			field("name", operatortype.EQ, selector.Name, selector.Opts)
+		}
+	case *scheme.Selector:
+		if selector.Id != nil {
+			conditions = append(conditions, condition{this.keyColumn, operatortype.EQ, base62.Encode(selector.Id), false})
+		}
+		if selector.Name != "" {
+			field("name", operatortype.EQ, selector.Name, selector.Opts)
+		}
+	default:
+		fields, _ := resolvers.Conjuncts(selector)
+
+		for _, fieldSelector := range fields {
+			if fieldSelector.Accessor == nil {
+				field(fieldSelector.Field, fieldSelector.Operator, fieldSelector.Value, fieldSelector.Opts)
+			}
+		}
+
+		if key := resolvers.KeyString(selector.Key()); key != "" {
+			return conditions, &condition{this.keyColumn, operatortype.EQ, key, false}
+		}
+	}
+
+	return conditions, nil
+}
+
+func (this *SqlResolver) placeholder(index int) string {
+	if this.numbered {
+		return fmt.Sprintf("$%d", index)
+	}
+
+	return "?"
+}
+
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+func (this *SqlResolver) where(conditions []condition) (string, []interface{}) {
+	if len(conditions) == 0 {
+		return "", nil
+	}
+
+	clauses := make([]string, len(conditions))
+	args := make([]interface{}, len(conditions))
+
+	for index, condition := range conditions {
+		column, placeholder := condition.column, this.placeholder(index+1)
+		args[index] = condition.value
+
+		if condition.ignoreCase {
+			column, placeholder = "UPPER("+column+")", "UPPER("+placeholder+")"
+		}
+
+		switch condition.operator {
+		case operatortype.PREFIX, operatortype.CONTAINS:
+			pattern := likeEscaper.Replace(fmt.Sprint(condition.value)) + "%"
+
+			if condition.operator == operatortype.CONTAINS {
+				pattern = "%" + pattern
+			}
+
+			clauses[index] = fmt.Sprintf("%s LIKE %s ESCAPE '!'", column, placeholder)
+			args[index] = pattern
+		default:
+			clauses[index] = fmt.Sprintf("%s = %s", column, placeholder)
+		}
+	}
+
+	return " WHERE " + strings.Join(clauses, " AND "), args
+}
+
+// selectWhere calls callback with each decoded entity which matches
+// conditions and passes selector's Test, until callback returns false
+func (this *SqlResolver) selectWhere(resolutionContext context.Context, selector resolvers.Selector, conditions []condition, callback func(key string, entity interface{}) bool) error {
+	where, args := this.where(conditions)
+
+	rows, err := this.db.QueryContext(resolutionContext,
+		fmt.Sprintf("SELECT %s, %s FROM %s%s", this.keyColumn, this.payloadColumn, this.table, where), args...)
+
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		var payload []byte
+
+		if err := rows.Scan(&key, &payload); err != nil {
+			return err
+		}
+
+		entity, err := this.decode(resolutionContext, key, payload, selector.Type())
+
+		if err != nil {
+			return err
+		}
+
+		if selector.Test(entity) && !callback(key, entity) {
+			break
+		}
+	}
+
+	return rows.Err()
+}
+
+func (this *SqlResolver) Get(resolutionContext context.Context, selector resolvers.Selector) (interface{}, error) {
+	var found interface{}
+
+	conditions, keyCondition := this.conditions(selector)
+
+	keyed := conditions
+
+	if keyCondition != nil {
+		keyed = append(keyed[:len(keyed):len(keyed)], *keyCondition)
+	}
+
+	// keyed selectors are looked up by their key, only keyless selectors
+	// scan the table
+	err := this.selectWhere(resolutionContext, selector, keyed, func(key string, entity interface{}) bool {
+		found = entity
+		return false
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if found == nil {
+		return nil, resolvers.NewEntityNotFound(fmt.Sprintf("Can't resolve entity for %v", selector), nil)
+	}
+
+	return found, nil
+}
+
+// MaxBatchKeys limits the number of keys in the IN clause of a GetMany query
+var MaxBatchKeys = 500
+
+// storedKey returns the stored key compared by selector's key condition
+func (this *SqlResolver) storedKey(selector resolvers.Selector) (string, bool) {
+	conditions, keyCondition := this.conditions(selector)
+
+	if keyCondition != nil {
+		conditions = append(conditions, *keyCondition)
+	}
+
+	for _, condition := range conditions {
+		if condition.column == this.keyColumn && condition.operator == operatortype.EQ {
+			key, ok := condition.value.(string)
+			return key, ok
+		}
+	}
+
+	return "", false
+}
+
+// GetMany resolves the selectors which compare a stored key with a single
+// IN query per MaxBatchKeys keys, selectors whose key is not found are not
+// found and other selectors are resolved with Get
+func (this *SqlResolver) GetMany(resolutionContext context.Context, selectors []resolvers.Selector) []resolvers.Result {
+	results := make([]resolvers.Result, len(selectors))
+	keys := []interface{}{}
+	keyIndexes := make(map[string][]int)
+
+	for index, selector := range selectors {
+		results[index].Selector = selector
+
+		if key, ok := this.storedKey(selector); ok {
+			if _, ok := keyIndexes[key]; !ok {
+				keys = append(keys, key)
+			}
+
+			keyIndexes[key] = append(keyIndexes[key], index)
+		}
+	}
+
+	for start := 0; start < len(keys); start += MaxBatchKeys {
+		end := start + MaxBatchKeys
+
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		if err := this.selectKeys(resolutionContext, keys[start:end], keyIndexes, results); err != nil {
+			for _, key := range keys[start:end] {
+				for _, index := range keyIndexes[key.(string)] {
+					results[index].Err = err
+				}
+			}
+		}
+	}
+
+	for index, selector := range selectors {
+		if results[index].Entity != nil || results[index].Err != nil {
+			continue
+		}
+
+		if _, ok := this.storedKey(selector); ok {
+			results[index].Err = resolvers.NewEntityNotFound(fmt.Sprintf("Can't resolve entity for %v", selector), nil)
+		} else {
+			results[index].Entity, results[index].Err = this.Get(resolutionContext, selector)
+		}
+	}
+
+	return results
+}
+
+func (this *SqlResolver) selectKeys(resolutionContext context.Context, keys []interface{}, keyIndexes map[string][]int, results []resolvers.Result) error {
+	placeholders := make([]string, len(keys))
+
+	for index := range keys {
+		placeholders[index] = this.placeholder(index + 1)
+	}
+
+	rows, err := this.db.QueryContext(resolutionContext,
+		fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s IN (%s)", this.keyColumn, this.payloadColumn, this.table,
+			this.keyColumn, strings.Join(placeholders, ", ")), keys...)
+
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		var payload []byte
+
+		if err := rows.Scan(&key, &payload); err != nil {
+			return err
+		}
+
+		for _, index := range keyIndexes[key] {
+			entity, err := this.decode(resolutionContext, key, payload, results[index].Selector.Type())
+
+			if err != nil {
+				results[index].Err = err
+			} else if results[index].Selector.Test(entity) {
+				results[index].Entity = entity
+			}
+		}
+	}
+
+	return rows.Err()
+}
+
+func (this *SqlResolver) Resolve(resolutionContext context.Context, selector resolvers.Selector) (chan interface{}, chan error) {
+	cres, cerr := make(chan interface{}, 1), make(chan error, 1)
+
+	go func() {
+		entity, err := this.Get(resolutionContext, selector)
+
+		if err != nil {
+			cerr <- err
+		} else {
+			cres <- entity
+		}
+
+		close(cres)
+		close(cerr)
+	}()
+
+	return cres, cerr
+}
+
+func (this *SqlResolver) Query(queryContext context.Context, selector resolvers.Selector, opts resolvers.QueryOpts) (<-chan interface{}, <-chan error) {
+	stream := resolvers.NewQueryStream(queryContext, opts, this.resolverInfo.KeyExtractor())
+
+	go func() {
+		conditions, _ := this.conditions(selector)
+
+		stream.Close(this.selectWhere(queryContext, selector, conditions, func(key string, entity interface{}) bool {
+			return stream.AddKeyed(key, entity)
+		}))
+	}()
+
+	return stream.Results()
+}
+
+// encode converts an entity to the json map it is stored as
+func (this *SqlResolver) encode(encodeContext context.Context, entity interface{}) (map[string]interface{}, error) {
+	return translators.ToMap(encodeContext, entity, this.resolverInfo.ResolvableTypes()...)
+}
+
+var untypedLocalDomain []byte = domain.MustDecodeId(encodertype.BASE62, "3", "")
+
+// decode translates a stored json payload to targetType
+func (this *SqlResolver) decode(decodeContext context.Context, key string, payload []byte, targetType ids.TypeIdentifier) (interface{}, error) {
+	var jsonEntity map[string]interface{}
+
+	if err := json.Unmarshal(payload, &jsonEntity); err != nil {
+		return nil, fmt.Errorf("Invalid payload for %s: %s", key, err)
+	}
+
+	if targetType == nil || targetType.Equals(contentType) || !translators.Exists(contentType, targetType) {
+		return jsonEntity, nil
+	}
+
+	entityId, err := identifier.New(untypedLocalDomain, []byte(key), nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	decodeContext = context.WithValue(decodeContext, "resolverInfo", this.resolverInfo)
+
+	return translators.TranslateFuture(decodeContext, contentType, entityId, jsonEntity, targetType).Await()
+}
+
+func (this *SqlResolver) write(resolutionContext context.Context, entity interface{}, mustExist bool) (interface{}, error) {
+	key, ok := this.resolverInfo.KeyExtractor()(entity)
+
+	if !ok {
+		return nil, fmt.Errorf("Cannot extract key from: %v", entity)
+	}
+
+	jsonEntity, err := this.encode(resolutionContext, entity)
+
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(jsonEntity)
+
+	if err != nil {
+		return nil, err
+	}
+
+	columns := []string{this.payloadColumn}
+	values := []interface{}{payload}
+
+	for field, column := range this.columns {
+		if value, ok := jsonEntity[field]; ok {
+			columns = append(columns, column)
+			values = append(values, fmt.Sprint(value))
+		}
+	}
+
+	tx, err := this.db.BeginTx(resolutionContext, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	assignments := make([]string, len(columns))
+
+	for index, column := range columns {
+		assignments[index] = fmt.Sprintf("%s = %s", column, this.placeholder(index+1))
+	}
+
+	result, err := tx.ExecContext(resolutionContext,
+		fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s", this.table, strings.Join(assignments, ", "),
+			this.keyColumn, this.placeholder(len(columns)+1)),
+		append(values, resolvers.KeyString(key))...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	changeType := changetype.UPDATE
+
+	if updated, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if updated == 0 {
+		changeType = changetype.PUT
+
+		if mustExist {
+			return nil, resolvers.NewEntityNotFound(fmt.Sprintf("Can't resolve entity for %v", key), nil)
+		}
+
+		placeholders := make([]string, len(columns)+1)
+
+		for index := range placeholders {
+			placeholders[index] = this.placeholder(index + 1)
+		}
+
+		if _, err := tx.ExecContext(resolutionContext,
+			fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES (%s)", this.table, this.keyColumn,
+				strings.Join(columns, ", "), strings.Join(placeholders, ", ")),
+			append([]interface{}{resolvers.KeyString(key)}, values...)...); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	this.Publish(changeType, key, entity)
+
+	return entity, nil
+}
+
+func (this *SqlResolver) Put(resolutionContext context.Context, entity interface{}) (interface{}, error) {
+	return this.write(resolutionContext, entity, false)
+}
+
+func (this *SqlResolver) Post(resolutionContext context.Context, entity interface{}) (interface{}, error) {
+	return this.write(resolutionContext, entity, true)
+}
+
+// Delete removes the selected entity, deleting a missing entity succeeds but
+// reports no change
+func (this *SqlResolver) Delete(resolutionContext context.Context, selector resolvers.Selector) error {
+	result, err := this.db.ExecContext(resolutionContext,
+		fmt.Sprintf("DELETE FROM %s WHERE %s = %s", this.table, this.keyColumn, this.placeholder(1)),
+		resolvers.KeyString(selector.Key()))
+
+	if err != nil {
+		return err
+	}
+
+	if deleted, err := result.RowsAffected(); err != nil || deleted == 0 {
+		return err
+	}
+
+	this.Publish(changetype.DELETE, selector.Key(), nil)
+
+	return nil
+}
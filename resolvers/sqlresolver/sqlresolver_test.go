@@ -0,0 +1,295 @@
+package sqlresolver_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/distributed-vision/go-resources/encoding/base62"
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/ids/scheme"
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/changetype"
+	"github.com/distributed-vision/go-resources/resolvers/operatortype"
+	"github.com/distributed-vision/go-resources/resolvers/sqlresolver"
+	"github.com/distributed-vision/go-resources/translators"
+	"github.com/distributed-vision/go-resources/types/gotypeid"
+	"github.com/distributed-vision/go-resources/util"
+
+	_ "modernc.org/sqlite"
+)
+
+var contentType = gotypeid.IdOf(reflect.TypeOf(map[string]interface{}{}))
+var testContext = context.Background()
+
+func idExtractor(e ...interface{}) (interface{}, bool) {
+	if entity, ok := e[0].(map[string]interface{}); ok {
+		id, ok := entity["id"].(string)
+		return id, ok
+	}
+
+	return nil, false
+}
+
+type selector struct {
+	key string
+}
+
+func (this *selector) Type() ids.TypeIdentifier {
+	return contentType
+}
+
+func (this *selector) Key() interface{} {
+	return this.key
+}
+
+func (this *selector) Test(candidate interface{}) bool {
+	entity, ok := candidate.(map[string]interface{})
+	return ok && (this.key == "" || entity["id"] == this.key)
+}
+
+func newScheme(id string, name string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         id,
+		"name":       name,
+		"visibility": "PUBLIC",
+		"format":     "FIXED",
+		"domainInfo": map[string]interface{}{}}
+}
+
+func TestSqlResolver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlresolver")
+
+	if err != nil {
+		t.Fatal("TestSqlResolver: TempDir failed:", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	dsn := filepath.Join(dir, "schemes.db")
+	db, err := sql.Open("sqlite", dsn)
+
+	if err != nil {
+		t.Fatal("TestSqlResolver: sql.Open failed:", err)
+	}
+
+	defer db.Close()
+
+	if _, err := db.Exec("CREATE TABLE schemes (id TEXT PRIMARY KEY, name TEXT, payload TEXT)"); err != nil {
+		t.Fatal("TestSqlResolver: CREATE TABLE failed:", err)
+	}
+
+	info := sqlresolver.NewResolverInfo([]ids.TypeIdentifier{contentType}, nil, idExtractor,
+		map[interface{}]interface{}{
+			"driver":  "sqlite",
+			"dsn":     dsn,
+			"table":   "schemes",
+			"columns": map[string]string{"name": "name"}})
+
+	factory, err := resolvers.NewResolverFactory(info)
+
+	if err != nil {
+		t.Fatal("TestSqlResolver: NewResolverFactory failed:", err)
+	}
+
+	component, err := factory.New(testContext)
+
+	if err != nil {
+		t.Fatal("TestSqlResolver: factory.New failed:", err)
+	}
+
+	resolver := component.(*sqlresolver.SqlResolver)
+	defer resolver.Close()
+
+	alpha, beta := base62.Encode([]byte("alpha")), base62.Encode([]byte("beta"))
+
+	if _, err := resolver.Post(testContext, newScheme(alpha, "Alpha")); err == nil {
+		t.Fatal("TestSqlResolver: Post of a missing entity should fail")
+	} else if _, ok := err.(*resolvers.EntityNotFound); !ok {
+		t.Fatal("TestSqlResolver: expected EntityNotFound got:", err)
+	}
+
+	for _, entity := range []map[string]interface{}{newScheme(alpha, "Alpha"), newScheme(beta, "Old")} {
+		if _, err := resolver.Put(testContext, entity); err != nil {
+			t.Fatal("TestSqlResolver: Put failed:", err)
+		}
+	}
+
+	if _, err := resolver.Post(testContext, newScheme(beta, "Beta")); err != nil {
+		t.Fatal("TestSqlResolver: Post failed:", err)
+	}
+
+	var name string
+
+	if err := db.QueryRow("SELECT name FROM schemes WHERE id = ?", beta).Scan(&name); err != nil || name != "Beta" {
+		t.Fatal("TestSqlResolver: expected the name column to be written got:", name, err)
+	}
+
+	resolved, err := resolver.Get(testContext, &scheme.Selector{Name: "beta", Opts: scheme.SelectorOpts{IgnoreCase: true}})
+
+	if err != nil {
+		t.Fatal("TestSqlResolver: structured Get failed:", err)
+	}
+
+	if resolvedScheme, ok := resolved.(ids.Scheme); !ok || resolvedScheme.Name() != "Beta" {
+		t.Fatal("TestSqlResolver: expected scheme Beta got:", resolved)
+	}
+
+	if entity, err := resolver.Get(testContext, &selector{alpha}); err != nil || entity.(map[string]interface{})["name"] != "Alpha" {
+		t.Fatal("TestSqlResolver: Get by key failed:", entity, err)
+	}
+
+	entities, err := util.AwaitAll(resolver.Query(testContext, &selector{}, resolvers.QueryOpts{Order: resolvers.KEY_ASCENDING}))
+
+	if err != nil || len(entities) != 2 || entities[0].(map[string]interface{})["id"] != beta {
+		t.Fatal("TestSqlResolver: unexpected query result:", entities, err)
+	}
+
+	entities, err = util.AwaitAll(resolver.Query(testContext,
+		resolvers.And(resolvers.Where("name", operatortype.PREFIX, "al").WithOpts(resolvers.SelectorOpts{IgnoreCase: true}),
+			resolvers.Where("name", operatortype.NE, "Beta")), resolvers.QueryOpts{}))
+
+	if err != nil || len(entities) != 1 || entities[0].(map[string]interface{})["id"] != alpha {
+		t.Fatal("TestSqlResolver: unexpected field query result:", entities, err)
+	}
+
+	if entity, err := resolver.Get(testContext, resolvers.Typed(contentType, beta, resolvers.Eq("name", "Beta"))); err != nil ||
+		entity.(map[string]interface{})["id"] != beta {
+		t.Fatal("TestSqlResolver: typed Get failed:", entity, err)
+	}
+
+	results := resolver.GetMany(testContext, []resolvers.Selector{&selector{beta}, &selector{"missing"}, &selector{alpha}})
+
+	if len(results) != 3 || results[0].Err != nil || results[0].Entity.(map[string]interface{})["id"] != beta ||
+		!resolvers.IsNotFound(results[1].Err) || results[2].Err != nil || results[2].Entity.(map[string]interface{})["id"] != alpha {
+		t.Fatal("TestSqlResolver: unexpected GetMany results:", results)
+	}
+
+	deletes := 0
+	resolver.OnChange(func(event resolvers.ChangeEvent) {
+		if event.Type == changetype.DELETE {
+			deletes++
+		}
+	})
+
+	if err := resolver.Delete(testContext, &selector{alpha}); err != nil {
+		t.Fatal("TestSqlResolver: Delete failed:", err)
+	}
+
+	if err := resolver.Delete(testContext, &selector{"missing"}); err != nil || deletes != 1 {
+		t.Fatal("TestSqlResolver: Delete of a missing entity should not report a change:", deletes, err)
+	}
+
+	if _, err := resolver.Get(testContext, &selector{alpha}); err == nil {
+		t.Fatal("TestSqlResolver: expected deleted entity to be missing")
+	}
+}
+
+func TestSqlResolverIdentifiers(t *testing.T) {
+	for name, values := range map[string]map[interface{}]interface{}{
+		"table":         {"table": "schemes; DROP TABLE schemes"},
+		"keyColumn":     {"table": "schemes", "keyColumn": "id --"},
+		"payloadColumn": {"table": "schemes", "payloadColumn": "1payload"},
+		"columns":       {"table": "schemes", "columns": map[string]string{"name": "name, payload"}}} {
+		if _, err := sqlresolver.New(nil, sqlresolver.NewResolverInfo(nil, nil, idExtractor, values)); err == nil {
+			t.Fatal("TestSqlResolverIdentifiers: expected invalid", name, "to fail")
+		}
+	}
+
+	if _, err := sqlresolver.New(nil, sqlresolver.NewResolverInfo(nil, nil, idExtractor,
+		map[interface{}]interface{}{"table": "_schemes2", "columns": map[string]string{"name": "Name_1"}})); err != nil {
+		t.Fatal("TestSqlResolverIdentifiers: valid identifiers failed:", err)
+	}
+}
+
+type untranslatedEntity struct {
+	id string
+}
+
+func TestSqlResolverUntranslated(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+
+	if err != nil {
+		t.Fatal("TestSqlResolverUntranslated: sql.Open failed:", err)
+	}
+
+	defer db.Close()
+
+	resolver, err := sqlresolver.New(db, sqlresolver.NewResolverInfo(nil, nil,
+		func(e ...interface{}) (interface{}, bool) { return "untranslated", true },
+		map[interface{}]interface{}{"table": "entities"}))
+
+	if err != nil {
+		t.Fatal("TestSqlResolverUntranslated: New failed:", err)
+	}
+
+	if _, err := resolver.Put(testContext, untranslatedEntity{"untranslated"}); !errors.Is(err, translators.ErrNoTranslator) {
+		t.Fatal("TestSqlResolverUntranslated: expected NoTranslator got:", err)
+	}
+}
+
+func TestSqlResolverFieldSemantics(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+
+	if err != nil {
+		t.Fatal("TestSqlResolverFieldSemantics: sql.Open failed:", err)
+	}
+
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec("CREATE TABLE entities (id TEXT PRIMARY KEY, name TEXT, rank TEXT, payload TEXT)"); err != nil {
+		t.Fatal("TestSqlResolverFieldSemantics: CREATE TABLE failed:", err)
+	}
+
+	resolver, err := sqlresolver.New(db, sqlresolver.NewResolverInfo([]ids.TypeIdentifier{contentType}, nil, idExtractor,
+		map[interface{}]interface{}{
+			"table":   "entities",
+			"columns": map[string]string{"name": "name", "rank": "rank"}}))
+
+	if err != nil {
+		t.Fatal("TestSqlResolverFieldSemantics: New failed:", err)
+	}
+
+	for _, entity := range []map[string]interface{}{
+		{"id": "nine", "name": "Nine", "rank": 9},
+		{"id": "ten", "name": "Ten", "rank": 10},
+		{"id": "unnamed", "rank": 1}} {
+		if _, err := resolver.Put(testContext, entity); err != nil {
+			t.Fatal("TestSqlResolverFieldSemantics: Put failed:", err)
+		}
+	}
+
+	keysOf := func(selector resolvers.Selector) []interface{} {
+		entities, err := util.AwaitAll(resolver.Query(testContext, selector, resolvers.QueryOpts{Order: resolvers.KEY_ASCENDING}))
+
+		if err != nil {
+			t.Fatal("TestSqlResolverFieldSemantics: Query failed:", err)
+		}
+
+		keys := []interface{}{}
+
+		for _, entity := range entities {
+			keys = append(keys, entity.(map[string]interface{})["id"])
+		}
+
+		return keys
+	}
+
+	if keys := keysOf(resolvers.Where("name", operatortype.NE, "Nine")); !reflect.DeepEqual(keys, []interface{}{"ten", "unnamed"}) {
+		t.Fatal("TestSqlResolverFieldSemantics: expected NE to select entities without the field got:", keys)
+	}
+
+	if keys := keysOf(resolvers.Where("rank", operatortype.GT, 9)); !reflect.DeepEqual(keys, []interface{}{"ten"}) {
+		t.Fatal("TestSqlResolverFieldSemantics: expected GT to compare numbers got:", keys)
+	}
+
+	if keys := keysOf(resolvers.Eq("name", "Ten")); !reflect.DeepEqual(keys, []interface{}{"ten"}) {
+		t.Fatal("TestSqlResolverFieldSemantics: unexpected EQ result:", keys)
+	}
+}
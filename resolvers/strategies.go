@@ -0,0 +1,200 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/distributed-vision/go-resources/resolvers/infokeys"
+)
+
+var DefaultHedgeDelay = 50 * time.Millisecond
+
+type componentResult struct {
+	entry  *componentEntry
+	result interface{}
+	err    error
+}
+
+func (this *componentResult) componentError() error {
+	return &ComponentError{this.entry.ResolverInfo().ResolverType(), this.err}
+}
+
+// sortByPriority orders entries so that components declaring a higher
+// priority info value are consulted first, entries of equal priority keep
+// their registration order
+func sortByPriority(entries []*componentEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return intInfoValue(entries[i].ResolverInfo(), infokeys.PRIORITY, 0) >
+			intInfoValue(entries[j].ResolverInfo(), infokeys.PRIORITY, 0)
+	})
+}
+
+// resolveRace queries all entries at once and returns the first result
+func (this *CompositeResolver) resolveRace(resolutionContext context.Context, entries []*componentEntry, selector Selector) (*componentResult, []error) {
+	results := make(chan *componentResult, len(entries))
+	errors := []error{}
+
+	for _, entry := range entries {
+		go func(entry *componentEntry) {
+			results <- this.resolveComponent(resolutionContext, entry, selector)
+		}(entry)
+	}
+
+	for range entries {
+		result := <-results
+
+		if result.err == nil {
+			return result, nil
+		}
+
+		errors = append(errors, result.componentError())
+	}
+
+	return nil, errors
+}
+
+// resolveOrdered queries entries one at a time in priority order, falling
+// back to the next entry only if the current one fails
+func (this *CompositeResolver) resolveOrdered(resolutionContext context.Context, entries []*componentEntry, selector Selector) (*componentResult, []error) {
+	errors := []error{}
+
+	for _, entry := range entries {
+		result := this.resolveComponent(resolutionContext, entry, selector)
+
+		if result.err == nil {
+			return result, nil
+		}
+
+		errors = append(errors, result.componentError())
+
+		if resolutionContext.Err() != nil {
+			break
+		}
+	}
+
+	return nil, errors
+}
+
+// resolveHedged queries entries in priority order, starting the next entry
+// if no answer has arrived after delay, or as soon as an outstanding query
+// fails. The first result returned wins
+func (this *CompositeResolver) resolveHedged(resolutionContext context.Context, entries []*componentEntry, selector Selector, delay time.Duration) (*componentResult, []error) {
+	results := make(chan *componentResult, len(entries))
+	errors := []error{}
+	next, pending := 0, 0
+
+	start := func() {
+		go func(entry *componentEntry) {
+			results <- this.resolveComponent(resolutionContext, entry, selector)
+		}(entries[next])
+		next++
+		pending++
+	}
+
+	start()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for pending > 0 {
+		select {
+		case result := <-results:
+			pending--
+
+			if result.err == nil {
+				return result, nil
+			}
+
+			errors = append(errors, result.componentError())
+
+			if next < len(entries) && resolutionContext.Err() == nil {
+				start()
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(delay)
+			}
+		case <-timer.C:
+			if next < len(entries) {
+				start()
+				timer.Reset(delay)
+			}
+		}
+	}
+
+	return nil, errors
+}
+
+// resolveQuorum queries all entries at once and returns a result once quorum
+// entries have returned equal results
+func (this *CompositeResolver) resolveQuorum(resolutionContext context.Context, entries []*componentEntry, selector Selector, quorum int) (*componentResult, []error) {
+	type vote struct {
+		result *componentResult
+		count  int
+	}
+
+	results := make(chan *componentResult, len(entries))
+	errors := []error{}
+	votes := []*vote{}
+
+	if quorum < 1 {
+		quorum = 1
+	}
+
+	for _, entry := range entries {
+		go func(entry *componentEntry) {
+			results <- this.resolveComponent(resolutionContext, entry, selector)
+		}(entry)
+	}
+
+	for remaining := len(entries); remaining > 0; remaining-- {
+		result := <-results
+
+		if result.err != nil {
+			errors = append(errors, result.componentError())
+		} else {
+			var matched *vote
+
+			for _, vote := range votes {
+				if reflect.DeepEqual(vote.result.result, result.result) {
+					matched = vote
+					break
+				}
+			}
+
+			if matched == nil {
+				matched = &vote{result, 0}
+				votes = append(votes, matched)
+			}
+
+			matched.count++
+
+			if matched.count >= quorum {
+				return matched.result, nil
+			}
+		}
+
+		best := 0
+
+		for _, vote := range votes {
+			if vote.count > best {
+				best = vote.count
+			}
+		}
+
+		if best+remaining-1 < quorum {
+			break
+		}
+	}
+
+	if len(votes) > 1 {
+		return nil, append(errors, NewAmbiguous(fmt.Sprintf("Resolve Failed: quorum of %d not reached for %v, components returned %d different results", quorum, selector, len(votes)), nil))
+	}
+
+	return nil, append(errors, NewEntityNotFound(fmt.Sprintf("Resolve Failed: quorum of %d not reached for %v", quorum, selector), nil))
+}
@@ -0,0 +1,209 @@
+package resolvers_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/infokeys"
+	"github.com/distributed-vision/go-resources/resolvers/strategytype"
+	"github.com/distributed-vision/go-resources/util"
+	"github.com/distributed-vision/go-resources/util/random"
+)
+
+type stubResolver struct {
+	resolverInfo resolvers.ResolverInfo
+	delay        time.Duration
+	value        string
+	err          error
+	calls        int32
+}
+
+func newStubResolver(priority int, delay time.Duration, value string, err error) *stubResolver {
+	return &stubResolver{
+		resolverInfo: resolvers.NewResolverInfo(testResolverType, testResolvableTypes, nil, testExtractor, nil).
+			WithValue(infokeys.PRIORITY, priority),
+		delay: delay,
+		value: value,
+		err:   err}
+}
+
+func (this *stubResolver) ResolverInfo() resolvers.ResolverInfo {
+	return this.resolverInfo
+}
+
+func (this *stubResolver) Get(resolutionContext context.Context, selector resolvers.Selector) (interface{}, error) {
+	return util.Await(this.Resolve(resolutionContext, selector))
+}
+
+func (this *stubResolver) Resolve(resolutionContext context.Context, selector resolvers.Selector) (chan interface{}, chan error) {
+	cres, cerr := make(chan interface{}, 1), make(chan error, 1)
+	atomic.AddInt32(&this.calls, 1)
+
+	go func() {
+		select {
+		case <-time.After(this.delay):
+			if this.err != nil {
+				cerr <- this.err
+			} else {
+				cres <- entity{selector.Key().(string), this.value}
+			}
+		case <-resolutionContext.Done():
+			cerr <- resolutionContext.Err()
+		}
+
+		close(cres)
+		close(cerr)
+	}()
+
+	return cres, cerr
+}
+
+func newStrategyResolver(t *testing.T, info resolvers.ResolverInfo, components ...*stubResolver) *resolvers.CompositeResolver {
+	resolver, err := resolvers.NewCompositeResolver(info)
+
+	if err != nil {
+		t.Fatal("NewCompositeResolver failed:", err)
+	}
+
+	for _, component := range components {
+		if err := resolver.RegisterComponent(component); err != nil {
+			t.Fatal("RegisterComponent failed:", err)
+		}
+	}
+
+	return resolver
+}
+
+func resolveValue(t *testing.T, resolver resolvers.Resolver) (string, error) {
+	resolved, err := resolver.Get(testContext, &typedSelector{key: random.RandomString(20)})
+
+	if err != nil {
+		return "", err
+	}
+
+	return resolved.(entity).value, nil
+}
+
+func TestRaceStrategy(t *testing.T) {
+	resolver := newStrategyResolver(t, testInfo,
+		newStubResolver(2, 100*time.Millisecond, "slow", nil),
+		newStubResolver(1, 0, "fast", nil))
+
+	if value, err := resolveValue(t, resolver); err != nil || value != "fast" {
+		t.Fatalf("TestRaceStrategy: expected fast got: %v, %v", value, err)
+	}
+}
+
+func TestOrderedStrategy(t *testing.T) {
+	failing := newStubResolver(3, 0, "", fmt.Errorf("unavailable"))
+	preferred := newStubResolver(2, 20*time.Millisecond, "preferred", nil)
+	fallback := newStubResolver(1, 0, "fallback", nil)
+
+	resolver := newStrategyResolver(t,
+		testInfo.WithValue(infokeys.RESOLVE_STRATEGY, strategytype.ORDERED),
+		fallback, preferred, failing)
+
+	if value, err := resolveValue(t, resolver); err != nil || value != "preferred" {
+		t.Fatalf("TestOrderedStrategy: expected preferred got: %v, %v", value, err)
+	}
+
+	if failing.calls != 1 || preferred.calls != 1 || fallback.calls != 0 {
+		t.Fatalf("TestOrderedStrategy: unexpected calls: %d, %d, %d", failing.calls, preferred.calls, fallback.calls)
+	}
+
+	resolver = newStrategyResolver(t,
+		testInfo.WithValue(infokeys.RESOLVE_STRATEGY, "ordered"),
+		newStubResolver(2, 0, "", fmt.Errorf("unavailable")),
+		newStubResolver(1, 0, "", fmt.Errorf("unavailable")))
+
+	if _, err := resolveValue(t, resolver); err == nil {
+		t.Fatal("TestOrderedStrategy: expected failure when all components fail")
+	}
+}
+
+func TestHedgedStrategy(t *testing.T) {
+	primary := newStubResolver(2, 200*time.Millisecond, "primary", nil)
+	secondary := newStubResolver(1, 0, "secondary", nil)
+
+	resolver := newStrategyResolver(t,
+		testInfo.WithValues(map[interface{}]interface{}{
+			infokeys.RESOLVE_STRATEGY: strategytype.HEDGED,
+			infokeys.HEDGE_DELAY:      10 * time.Millisecond}),
+		primary, secondary)
+
+	if value, err := resolveValue(t, resolver); err != nil || value != "secondary" {
+		t.Fatalf("TestHedgedStrategy: expected secondary got: %v, %v", value, err)
+	}
+
+	primary = newStubResolver(2, 10*time.Millisecond, "primary", nil)
+	secondary = newStubResolver(1, 0, "secondary", nil)
+
+	resolver = newStrategyResolver(t,
+		testInfo.WithValues(map[interface{}]interface{}{
+			"resolveStrategy": "hedged",
+			"hedgeDelay":      "1s"}),
+		primary, secondary)
+
+	if value, err := resolveValue(t, resolver); err != nil || value != "primary" {
+		t.Fatalf("TestHedgedStrategy: expected primary got: %v, %v", value, err)
+	}
+
+	if secondary.calls != 0 {
+		t.Fatal("TestHedgedStrategy: secondary should not be called before the hedge delay")
+	}
+
+	primary = newStubResolver(2, 10*time.Millisecond, "primary", nil)
+	secondary = newStubResolver(1, 0, "secondary", nil)
+
+	resolver = newStrategyResolver(t,
+		testInfo.WithValues(map[interface{}]interface{}{
+			infokeys.RESOLVE_STRATEGY: strategytype.HEDGED,
+			infokeys.HEDGE_DELAY:      int64(1000)}),
+		primary, secondary)
+
+	if value, err := resolveValue(t, resolver); err != nil || value != "primary" {
+		t.Fatalf("TestHedgedStrategy: expected primary with an int64 delay got: %v, %v", value, err)
+	}
+
+	if secondary.calls != 0 {
+		t.Fatal("TestHedgedStrategy: secondary should not be called before an int64 hedge delay")
+	}
+}
+
+func TestQuorumStrategy(t *testing.T) {
+	resolver := newStrategyResolver(t,
+		testInfo.WithValue(infokeys.RESOLVE_STRATEGY, strategytype.QUORUM),
+		newStubResolver(0, 0, "minority", nil),
+		newStubResolver(0, 10*time.Millisecond, "majority", nil),
+		newStubResolver(0, 20*time.Millisecond, "majority", nil))
+
+	if value, err := resolveValue(t, resolver); err != nil || value != "majority" {
+		t.Fatalf("TestQuorumStrategy: expected majority got: %v, %v", value, err)
+	}
+
+	resolver = newStrategyResolver(t,
+		testInfo.WithValues(map[interface{}]interface{}{
+			infokeys.RESOLVE_STRATEGY: strategytype.QUORUM,
+			infokeys.QUORUM:           3}),
+		newStubResolver(0, 0, "minority", nil),
+		newStubResolver(0, 0, "majority", nil),
+		newStubResolver(0, 0, "majority", nil))
+
+	if _, err := resolveValue(t, resolver); err == nil {
+		t.Fatal("TestQuorumStrategy: expected failure when quorum can't be reached")
+	}
+
+	resolver = newStrategyResolver(t,
+		testInfo.WithValue(infokeys.RESOLVE_STRATEGY, strategytype.QUORUM),
+		newStubResolver(0, 0, "", resolvers.NewEntityNotFound("missing", nil)),
+		newStubResolver(0, 0, "", resolvers.NewEntityNotFound("missing", nil)),
+		newStubResolver(0, 0, "", resolvers.NewEntityNotFound("missing", nil)))
+
+	if _, err := resolveValue(t, resolver); !resolvers.IsNotFound(err) {
+		t.Fatalf("TestQuorumStrategy: expected not found when every component misses got: %v", err)
+	}
+}
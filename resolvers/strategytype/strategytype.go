@@ -0,0 +1,45 @@
+package strategytype
+
+import (
+	"errors"
+	"strings"
+)
+
+type StrategyType int
+
+const (
+	RACE StrategyType = iota
+	ORDERED
+	HEDGED
+	QUORUM
+)
+
+func (this StrategyType) String() string {
+	switch this {
+	case RACE:
+		return "race"
+	case ORDERED:
+		return "ordered"
+	case HEDGED:
+		return "hedged"
+	case QUORUM:
+		return "quorum"
+	default:
+		return "invalid"
+	}
+}
+
+func Parse(value string) (StrategyType, error) {
+	switch strings.ToUpper(value) {
+	case "RACE":
+		return RACE, nil
+	case "ORDERED":
+		return ORDERED, nil
+	case "HEDGED":
+		return HEDGED, nil
+	case "QUORUM":
+		return QUORUM, nil
+	default:
+		return -1, errors.New("Unknown resolve strategy: " + value)
+	}
+}
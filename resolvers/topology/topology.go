@@ -0,0 +1,639 @@
+package topology
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/ids/domain"
+	"github.com/distributed-vision/go-resources/ids/scheme"
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/types"
+	"github.com/distributed-vision/go-resources/types/gotypeid"
+	"github.com/distributed-vision/go-resources/types/publictypeid"
+	"github.com/distributed-vision/go-resources/version"
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes a resolver graph. Each composite names the entity types
+// it resolves and the components it resolves them from, a component is
+// either a resolver type whose factory is created with the component's info
+// values, or a reference to another composite in the same config.
+//
+// Info strings may reference environment variables as $NAME, ${NAME} or
+// ${NAME:-default}. A "paths" info value may be a list or a path list
+// string, relative paths are resolved against the directory of the config
+// file.
+//
+// A composite is registered with the targets named by its register list,
+// when the list is omitted composites which are not components of another
+// composite are registered with the root resolver
+type Config struct {
+	Composites []CompositeConfig `json:"composites" yaml:"composites"`
+	dir        string
+}
+
+type CompositeConfig struct {
+	Name        string                 `json:"name" yaml:"name"`
+	EntityTypes []string               `json:"entityTypes" yaml:"entityTypes"`
+	Info        map[string]interface{} `json:"info,omitempty" yaml:"info,omitempty"`
+	Register    []string               `json:"register,omitempty" yaml:"register,omitempty"`
+	Components  []ComponentConfig      `json:"components" yaml:"components"`
+}
+
+type ComponentConfig struct {
+	ResolverType string                 `json:"resolverType,omitempty" yaml:"resolverType,omitempty"`
+	Composite    string                 `json:"composite,omitempty" yaml:"composite,omitempty"`
+	EntityTypes  []string               `json:"entityTypes,omitempty" yaml:"entityTypes,omitempty"`
+	Info         map[string]interface{} `json:"info,omitempty" yaml:"info,omitempty"`
+	Initialise   bool                   `json:"initialise,omitempty" yaml:"initialise,omitempty"`
+}
+
+type entityType struct {
+	typeId       ids.TypeIdentifier
+	keyExtractor resolvers.KeyExtractor
+}
+
+var compositeVersion = version.New(0, 0, 1)
+
+var entityTypes = make(map[string]*entityType)
+var targets = make(map[string]func(resolver resolvers.Resolver) error)
+var registryMutex = &sync.Mutex{}
+
+func init() {
+	RegisterEntityType("json", gotypeid.IdOf(reflect.TypeOf(map[string]interface{}{})), idExtractor)
+	RegisterEntityType("scheme", gotypeid.IdOf(reflect.TypeOf((*ids.Scheme)(nil)).Elem()), scheme.KeyExtractor)
+	RegisterEntityType("domain", gotypeid.IdOf(reflect.TypeOf((*ids.Domain)(nil)).Elem()), domain.KeyExtractor)
+
+	RegisterTarget("root", func(resolver resolvers.Resolver) error {
+		resolvers.RegisterResolver(resolver)
+		return nil
+	})
+	RegisterTarget("scheme", func(resolver resolvers.Resolver) error {
+		return scheme.RegisterResolverFactory(&instanceFactory{resolver})
+	})
+	RegisterTarget("domain", func(resolver resolvers.Resolver) error {
+		return domain.RegisterResolverFactory(&instanceFactory{resolver})
+	})
+}
+
+func idExtractor(entity ...interface{}) (interface{}, bool) {
+	if len(entity) > 0 {
+		if jsonEntity, ok := entity[0].(map[string]interface{}); ok {
+			key, ok := jsonEntity["id"]
+			return key, ok
+		}
+	}
+
+	return nil, false
+}
+
+// RegisterEntityType makes an entity type available to configs by name
+func RegisterEntityType(name string, typeId ids.TypeIdentifier, keyExtractor resolvers.KeyExtractor) {
+	registryMutex.Lock()
+	entityTypes[name] = &entityType{typeId, keyExtractor}
+	registryMutex.Unlock()
+}
+
+// RegisterTarget makes a registration target available to configs by name
+func RegisterTarget(name string, register func(resolver resolvers.Resolver) error) {
+	registryMutex.Lock()
+	targets[name] = register
+	registryMutex.Unlock()
+}
+
+// instanceFactory adapts a built composite to targets which register
+// factories
+type instanceFactory struct {
+	resolver resolvers.Resolver
+}
+
+func (this *instanceFactory) ResolverType() ids.TypeIdentifier {
+	return this.resolver.ResolverInfo().ResolverType()
+}
+
+func (this *instanceFactory) ResolverInfo() resolvers.ResolverInfo {
+	return this.resolver.ResolverInfo()
+}
+
+func (this *instanceFactory) New(resolutionContext context.Context) (resolvers.Resolver, error) {
+	return this.resolver, nil
+}
+
+// Read reads a config from a .json, .yaml or .yml file
+func Read(configPath string) (*Config, error) {
+	data, err := ioutil.ReadFile(configPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := Parse(data, strings.TrimPrefix(filepath.Ext(configPath), "."))
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't read topology %s: %s", configPath, err)
+	}
+
+	if config.dir, err = filepath.Abs(filepath.Dir(configPath)); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// Parse decodes a config in the given format, "json", "yaml" or "yml".
+// Unknown fields are rejected
+func Parse(data []byte, format string) (*Config, error) {
+	config := &Config{}
+
+	switch format {
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+
+		if err := decoder.Decode(config); err != nil {
+			return nil, err
+		}
+	case "yaml", "yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+
+		if err := decoder.Decode(config); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("Unknown topology format: %s", format)
+	}
+
+	return config, nil
+}
+
+// Load reads, builds and registers the topology in configPath
+func Load(configPath string) (*Topology, error) {
+	config, err := Read(configPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	topology, err := Build(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := topology.Register(); err != nil {
+		return nil, err
+	}
+
+	return topology, nil
+}
+
+// Topology holds the composites built from a config
+type Topology struct {
+	config     *Config
+	composites map[string]*resolvers.CompositeResolver
+	referenced map[string]bool
+}
+
+func (this *Topology) Composite(name string) *resolvers.CompositeResolver {
+	return this.composites[name]
+}
+
+// Names returns the names of the topology's composites in the order they
+// were built, components before the composites which refer to them
+func (this *Topology) Names() []string {
+	names := make([]string, 0, len(this.composites))
+
+	for _, composite := range this.config.Composites {
+		names = append(names, composite.Name)
+	}
+
+	return names
+}
+
+// Register registers the topology's composites with their targets
+func (this *Topology) Register() error {
+	for _, composite := range this.config.Composites {
+		registerWith := composite.Register
+
+		if registerWith == nil && !this.referenced[composite.Name] {
+			registerWith = []string{"root"}
+		}
+
+		for _, targetName := range registerWith {
+			registryMutex.Lock()
+			register := targets[targetName]
+			registryMutex.Unlock()
+
+			if err := register(this.composites[composite.Name]); err != nil {
+				return fmt.Errorf("Can't register composite %s with %s: %s", composite.Name, targetName, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Build validates config and creates its composites and their component
+// factories, nothing is registered until the topology's Register is called
+func Build(config *Config) (*Topology, error) {
+	byName := make(map[string]*CompositeConfig, len(config.Composites))
+	referenced := make(map[string]bool)
+
+	for index := range config.Composites {
+		composite := &config.Composites[index]
+
+		if composite.Name == "" {
+			return nil, fmt.Errorf("Composite %d has no name", index)
+		}
+
+		if _, ok := byName[composite.Name]; ok {
+			return nil, fmt.Errorf("Composite %s is defined more than once", composite.Name)
+		}
+
+		byName[composite.Name] = composite
+	}
+
+	for _, composite := range config.Composites {
+		for _, targetName := range composite.Register {
+			registryMutex.Lock()
+			_, ok := targets[targetName]
+			registryMutex.Unlock()
+
+			if !ok {
+				return nil, fmt.Errorf("Composite %s: unknown register target %q, known targets: %s",
+					composite.Name, targetName, strings.Join(targetNames(), ", "))
+			}
+		}
+
+		for index, component := range composite.Components {
+			if (component.ResolverType == "") == (component.Composite == "") {
+				return nil, fmt.Errorf("Composite %s component %d: exactly one of resolverType or composite must be set", composite.Name, index)
+			}
+
+			if component.Composite != "" {
+				if _, ok := byName[component.Composite]; !ok {
+					return nil, fmt.Errorf("Composite %s component %d: unknown composite %q", composite.Name, index, component.Composite)
+				}
+
+				referenced[component.Composite] = true
+			}
+		}
+	}
+
+	order, err := buildOrder(config.Composites, byName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	topology := &Topology{
+		config:     &Config{order, config.dir},
+		composites: make(map[string]*resolvers.CompositeResolver),
+		referenced: referenced}
+
+	for _, composite := range order {
+		compositeResolver, err := topology.build(composite)
+
+		if err != nil {
+			return nil, err
+		}
+
+		topology.composites[composite.Name] = compositeResolver
+	}
+
+	return topology, nil
+}
+
+// buildOrder orders composites so that referenced composites are built
+// first, failing if the references form a cycle
+func buildOrder(composites []CompositeConfig, byName map[string]*CompositeConfig) ([]CompositeConfig, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	state := make(map[string]int)
+	order := make([]CompositeConfig, 0, len(composites))
+
+	var visit func(name string, path []string) error
+
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("Composite references form a cycle: %s", strings.Join(append(path, name), " -> "))
+		}
+
+		state[name] = visiting
+
+		for _, component := range byName[name].Components {
+			if component.Composite != "" {
+				if err := visit(component.Composite, append(path, name)); err != nil {
+					return err
+				}
+			}
+		}
+
+		state[name] = visited
+		order = append(order, *byName[name])
+
+		return nil
+	}
+
+	for _, composite := range composites {
+		if err := visit(composite.Name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+func (this *Topology) build(composite CompositeConfig) (*resolvers.CompositeResolver, error) {
+	resolvableTypes, keyExtractor, err := lookupEntityTypes(composite.EntityTypes)
+
+	if err != nil {
+		return nil, fmt.Errorf("Composite %s: %s", composite.Name, err)
+	}
+
+	values, err := infoValues(composite.Info, this.config.dir)
+
+	if err != nil {
+		return nil, fmt.Errorf("Composite %s: %s", composite.Name, err)
+	}
+
+	compositeType, err := types.NewId(publictypeid.ResolverDomain, []byte(composite.Name), compositeVersion)
+
+	if err != nil {
+		return nil, err
+	}
+
+	compositeResolver, err := resolvers.NewCompositeResolver(
+		resolvers.NewResolverInfo(compositeType, resolvableTypes, nil, keyExtractor, values))
+
+	if err != nil {
+		return nil, fmt.Errorf("Composite %s: %s", composite.Name, err)
+	}
+
+	for index, component := range composite.Components {
+		if component.Composite != "" {
+			err = compositeResolver.RegisterComponent(this.composites[component.Composite])
+		} else {
+			err = this.registerFactory(compositeResolver, composite, component)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("Composite %s component %d: %s", composite.Name, index, err)
+		}
+	}
+
+	return compositeResolver, nil
+}
+
+func (this *Topology) registerFactory(compositeResolver *resolvers.CompositeResolver, composite CompositeConfig, component ComponentConfig) error {
+	resolverType, err := lookupResolverType(component.ResolverType)
+
+	if err != nil {
+		return err
+	}
+
+	entityTypeNames := component.EntityTypes
+
+	if len(entityTypeNames) == 0 {
+		entityTypeNames = composite.EntityTypes
+	}
+
+	resolvableTypes, keyExtractor, err := lookupEntityTypes(entityTypeNames)
+
+	if err != nil {
+		return err
+	}
+
+	values, err := infoValues(component.Info, this.config.dir)
+
+	if err != nil {
+		return err
+	}
+
+	factory, err := resolvers.NewResolverFactory(
+		resolvers.NewResolverInfo(resolverType, resolvableTypes, nil, keyExtractor, values))
+
+	if err != nil {
+		return err
+	}
+
+	return compositeResolver.RegisterComponentFactory(factory, component.Initialise)
+}
+
+func lookupEntityTypes(names []string) ([]ids.TypeIdentifier, resolvers.KeyExtractor, error) {
+	if len(names) == 0 {
+		return nil, nil, fmt.Errorf("No entityTypes defined")
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	typeIds := make([]ids.TypeIdentifier, 0, len(names))
+	var keyExtractor resolvers.KeyExtractor
+
+	for _, name := range names {
+		entityType, ok := entityTypes[name]
+
+		if !ok {
+			known := make([]string, 0, len(entityTypes))
+
+			for name := range entityTypes {
+				known = append(known, name)
+			}
+
+			sort.Strings(known)
+
+			return nil, nil, fmt.Errorf("Unknown entity type %q, known entity types: %s", name, strings.Join(known, ", "))
+		}
+
+		if keyExtractor == nil {
+			keyExtractor = entityType.keyExtractor
+		}
+
+		typeIds = append(typeIds, entityType.typeId)
+	}
+
+	return typeIds, keyExtractor, nil
+}
+
+func typeName(typeId ids.TypeIdentifier) string {
+	if typeVersion := typeId.Version(); typeVersion != nil {
+		return string(typeId.Id()) + "-" + typeVersion.String()
+	}
+
+	return string(typeId.Id())
+}
+
+// lookupResolverType finds the registered factory type for a resolver type
+// written as Name-Version, or as Name to select its latest version
+func lookupResolverType(resolverType string) (ids.TypeIdentifier, error) {
+	name, versionPart := resolverType, ""
+
+	if index := strings.Index(resolverType, "-"); index >= 0 {
+		name, versionPart = resolverType[:index], resolverType[index+1:]
+	}
+
+	var typeVersion version.Version
+
+	if versionPart != "" {
+		var err error
+
+		if typeVersion, err = version.Parse(versionPart); err != nil {
+			return nil, fmt.Errorf("Invalid resolver type version %q: %s", resolverType, err)
+		}
+	}
+
+	factoryTypes := resolvers.FactoryTypes()
+
+	var match ids.TypeIdentifier
+
+	for _, factoryType := range factoryTypes {
+		if string(factoryType.Id()) != name {
+			continue
+		}
+
+		factoryVersion := factoryType.Version()
+
+		if typeVersion != nil {
+			if factoryVersion != nil && factoryVersion.Equals(typeVersion) {
+				return factoryType, nil
+			}
+		} else if match == nil || factoryVersion != nil && match.Version() != nil && factoryVersion.Compare(match.Version()) > 0 {
+			match = factoryType
+		}
+	}
+
+	if match != nil {
+		return match, nil
+	}
+
+	known := make([]string, 0, len(factoryTypes))
+
+	for _, factoryType := range factoryTypes {
+		known = append(known, typeName(factoryType))
+	}
+
+	sort.Strings(known)
+
+	return nil, fmt.Errorf("Unknown resolver type %q, known resolver types: %s (resolver packages must be imported to register their types)",
+		resolverType, strings.Join(known, ", "))
+}
+
+func targetNames() []string {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	names := make([]string, 0, len(targets))
+
+	for name := range targets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// expand replaces environment references in value, ${NAME:-default} uses
+// default when NAME is unset or empty
+func expand(value string) string {
+	return os.Expand(value, func(name string) string {
+		if index := strings.Index(name, ":-"); index >= 0 {
+			if value := os.Getenv(name[:index]); value != "" {
+				return value
+			}
+
+			return name[index+2:]
+		}
+
+		return os.Getenv(name)
+	})
+}
+
+func expandValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case string:
+		return expand(value)
+	case []interface{}:
+		strs := make([]string, 0, len(value))
+
+		for _, element := range value {
+			if str, ok := element.(string); ok {
+				strs = append(strs, expand(str))
+			}
+		}
+
+		if len(strs) == len(value) {
+			return strs
+		}
+
+		expanded := make([]interface{}, len(value))
+
+		for index, element := range value {
+			expanded[index] = expandValue(element)
+		}
+
+		return expanded
+	case map[string]interface{}:
+		expanded := make(map[string]interface{}, len(value))
+
+		for key, element := range value {
+			expanded[key] = expandValue(element)
+		}
+
+		return expanded
+	}
+
+	return value
+}
+
+// infoValues converts configured info to resolver info values
+func infoValues(info map[string]interface{}, dir string) (map[interface{}]interface{}, error) {
+	values := make(map[interface{}]interface{}, len(info))
+
+	for key, value := range info {
+		values[key] = expandValue(value)
+	}
+
+	if pathsValue, ok := values["paths"]; ok {
+		var paths []string
+
+		switch pathsValue := pathsValue.(type) {
+		case string:
+			paths = filepath.SplitList(pathsValue)
+		case []string:
+			for _, pathValue := range pathsValue {
+				paths = append(paths, filepath.SplitList(pathValue)...)
+			}
+		default:
+			return nil, fmt.Errorf("Info value 'paths' must be a string or a list of strings")
+		}
+
+		for index, pathValue := range paths {
+			if dir != "" && !filepath.IsAbs(pathValue) {
+				paths[index] = filepath.Join(dir, pathValue)
+			}
+		}
+
+		values["paths"] = paths
+	}
+
+	return values, nil
+}
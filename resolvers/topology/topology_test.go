@@ -0,0 +1,217 @@
+package topology_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/resolvers"
+	_ "github.com/distributed-vision/go-resources/resolvers/fileresolver"
+	"github.com/distributed-vision/go-resources/resolvers/localresolver"
+	"github.com/distributed-vision/go-resources/resolvers/topology"
+	"github.com/distributed-vision/go-resources/types/gotypeid"
+)
+
+var contentType = gotypeid.IdOf(reflect.TypeOf(map[string]interface{}{}))
+var testContext = context.Background()
+
+type selector struct {
+	key string
+}
+
+func (this *selector) Type() ids.TypeIdentifier {
+	return contentType
+}
+
+func (this *selector) Key() interface{} {
+	return this.key
+}
+
+func (this *selector) Test(candidate interface{}) bool {
+	entity, ok := candidate.(map[string]interface{})
+	return ok && entity["id"] == this.key
+}
+
+const config = `
+composites:
+  - name: all
+    entityTypes: [json]
+    info:
+      cacheSize: 10
+    register: [test]
+    components:
+      - composite: files
+      - resolverType: LocalResolver
+        initialise: true
+  - name: files
+    entityTypes: [json]
+    components:
+      - resolverType: FileResolver-0.0.1
+        info:
+          location: entities
+          paths: ["${TOPOLOGY_TEST_MISSING:-.}"]
+`
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "topology")
+
+	if err != nil {
+		t.Fatal("TestLoad: TempDir failed:", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	os.Mkdir(filepath.Join(dir, "entities"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "entities", "1.json"), []byte(`{"name": "file"}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "topology.yaml"), []byte(config), 0644)
+
+	var registered []resolvers.Resolver
+
+	topology.RegisterTarget("test", func(resolver resolvers.Resolver) error {
+		registered = append(registered, resolver)
+		return nil
+	})
+
+	loaded, err := topology.Load(filepath.Join(dir, "topology.yaml"))
+
+	if err != nil {
+		t.Fatal("TestLoad: Load failed:", err)
+	}
+
+	if names := loaded.Names(); !reflect.DeepEqual(names, []string{"files", "all"}) {
+		t.Fatal("TestLoad: Unexpected build order:", names)
+	}
+
+	all := loaded.Composite("all")
+
+	if len(registered) != 1 || registered[0] != all {
+		t.Fatal("TestLoad: Expected all to be registered with test, got:", registered)
+	}
+
+	entity, err := all.Get(testContext, &selector{"1"})
+
+	if err != nil || entity.(map[string]interface{})["name"] != "file" {
+		t.Fatal("TestLoad: Get from file component failed:", entity, err)
+	}
+
+	local := all.GetMutableComponents(testContext, &selector{"2"})
+
+	if len(local) != 1 {
+		t.Fatal("TestLoad: Expected one mutable component, got:", local)
+	}
+
+	if _, ok := local[0].(*localresolver.LocalResolver); !ok {
+		t.Fatal("TestLoad: Expected a local resolver, got:", reflect.TypeOf(local[0]))
+	}
+
+	if _, err := local[0].(resolvers.MutableResolver).Put(testContext, map[string]interface{}{"id": "2", "name": "local"}); err != nil {
+		t.Fatal("TestLoad: Put failed:", err)
+	}
+
+	if entity, err := all.Get(testContext, &selector{"2"}); err != nil || entity.(map[string]interface{})["name"] != "local" {
+		t.Fatal("TestLoad: Get from local component failed:", entity, err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	config, err := topology.Parse([]byte(`{"composites": [{"name": "json", "entityTypes": ["json"],
+		"register": [], "components": [{"resolverType": "LocalResolver-0.0.1"}]}]}`), "json")
+
+	if err != nil {
+		t.Fatal("TestJSON: Parse failed:", err)
+	}
+
+	if _, err := topology.Build(config); err != nil {
+		t.Fatal("TestJSON: Build failed:", err)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	invalid := map[string]struct {
+		config string
+		err    string
+	}{
+		"unknown field": {`
+composites:
+  - name: a
+    entityType: [json]
+`, "entityType"},
+		"unknown resolver type": {`
+composites:
+  - name: a
+    entityTypes: [json]
+    components:
+      - resolverType: NoSuchResolver-0.0.1
+`, "LocalResolver-0.0.1"},
+		"unknown version": {`
+composites:
+  - name: a
+    entityTypes: [json]
+    components:
+      - resolverType: LocalResolver-9.9.9
+`, "Unknown resolver type"},
+		"unknown entity type": {`
+composites:
+  - name: a
+    entityTypes: [thing]
+    components:
+      - resolverType: LocalResolver
+`, "known entity types: domain, json, scheme"},
+		"unknown composite": {`
+composites:
+  - name: a
+    entityTypes: [json]
+    components:
+      - composite: b
+`, "unknown composite \"b\""},
+		"unknown target": {`
+composites:
+  - name: a
+    entityTypes: [json]
+    register: [nowhere]
+`, "unknown register target"},
+		"cycle": {`
+composites:
+  - name: a
+    entityTypes: [json]
+    components:
+      - composite: b
+  - name: b
+    entityTypes: [json]
+    components:
+      - composite: a
+`, "a -> b -> a"},
+		"duplicate": {`
+composites:
+  - name: a
+    entityTypes: [json]
+  - name: a
+    entityTypes: [json]
+`, "more than once"},
+		"ambiguous component": {`
+composites:
+  - name: a
+    entityTypes: [json]
+    components:
+      - resolverType: LocalResolver
+        composite: a
+`, "exactly one"},
+	}
+
+	for name, test := range invalid {
+		config, err := topology.Parse([]byte(test.config), "yaml")
+
+		if err == nil {
+			_, err = topology.Build(config)
+		}
+
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("TestInvalid: %s: expected error containing %q, got: %v", name, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/resolvers/operationtype"
+)
+
+// Op is a write applied by Batch, Entity is the entity written by PUT and
+// POST operations and Selector selects the entity removed by DELETE
+type Op struct {
+	Operation operationtype.OperationType
+	Entity    interface{}
+	Selector  Selector
+}
+
+func PutOp(entity interface{}) Op {
+	return Op{Operation: operationtype.PUT, Entity: entity}
+}
+
+func PostOp(entity interface{}) Op {
+	return Op{Operation: operationtype.POST, Entity: entity}
+}
+
+func DeleteOp(selector Selector) Op {
+	return Op{Operation: operationtype.DELETE, Selector: selector}
+}
+
+// Transaction collects writes which are applied together when it is
+// committed, or discarded if it is rolled back. A transaction can't be used
+// once it is committed or rolled back
+type Transaction interface {
+	Put(transactionContext context.Context, entity interface{}) (interface{}, error)
+	Post(transactionContext context.Context, entity interface{}) (interface{}, error)
+	Delete(transactionContext context.Context, selector Selector) error
+	Commit(transactionContext context.Context) error
+	Rollback(transactionContext context.Context) error
+}
+
+// TransactionalResolver is implemented by mutable resolvers which can apply
+// several writes atomically
+type TransactionalResolver interface {
+	MutableResolver
+	Begin(transactionContext context.Context) (Transaction, error)
+}
+
+// BufferedTransaction is a Transaction which records its writes and passes
+// them to an apply function when it is committed, for resolvers which can
+// apply a list of writes atomically. Writes are only checked when they are
+// applied, so a POST of a missing entity fails the commit
+type BufferedTransaction struct {
+	mutex sync.Mutex
+	ops   []Op
+	apply func(transactionContext context.Context, ops []Op) error
+	done  bool
+}
+
+func NewBufferedTransaction(apply func(transactionContext context.Context, ops []Op) error) *BufferedTransaction {
+	return &BufferedTransaction{apply: apply}
+}
+
+func (this *BufferedTransaction) add(op Op) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if this.done {
+		return fmt.Errorf("Write Failed: transaction is complete")
+	}
+
+	this.ops = append(this.ops, op)
+
+	return nil
+}
+
+func (this *BufferedTransaction) Put(transactionContext context.Context, entity interface{}) (interface{}, error) {
+	if err := this.add(PutOp(entity)); err != nil {
+		return nil, err
+	}
+
+	return entity, nil
+}
+
+func (this *BufferedTransaction) Post(transactionContext context.Context, entity interface{}) (interface{}, error) {
+	if err := this.add(PostOp(entity)); err != nil {
+		return nil, err
+	}
+
+	return entity, nil
+}
+
+func (this *BufferedTransaction) Delete(transactionContext context.Context, selector Selector) error {
+	return this.add(DeleteOp(selector))
+}
+
+// Ops returns the writes recorded by the transaction
+func (this *BufferedTransaction) Ops() []Op {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	return append([]Op{}, this.ops...)
+}
+
+func (this *BufferedTransaction) complete() ([]Op, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if this.done {
+		return nil, fmt.Errorf("transaction is complete")
+	}
+
+	this.done = true
+
+	return this.ops, nil
+}
+
+func (this *BufferedTransaction) Commit(transactionContext context.Context) error {
+	ops, err := this.complete()
+
+	if err != nil {
+		return fmt.Errorf("Commit Failed: %v", err)
+	}
+
+	if len(ops) == 0 {
+		return nil
+	}
+
+	return this.apply(transactionContext, ops)
+}
+
+func (this *BufferedTransaction) Rollback(transactionContext context.Context) error {
+	if _, err := this.complete(); err != nil {
+		return fmt.Errorf("Rollback Failed: %v", err)
+	}
+
+	return nil
+}
+
+// writer is implemented by both MutableResolver and Transaction
+type writer interface {
+	Put(resolutionContext context.Context, entity interface{}) (interface{}, error)
+	Post(resolutionContext context.Context, entity interface{}) (interface{}, error)
+	Delete(resolutionContext context.Context, selector Selector) error
+}
+
+func applyOp(resolutionContext context.Context, writer writer, op Op) (interface{}, error) {
+	switch op.Operation {
+	case operationtype.PUT:
+		return writer.Put(resolutionContext, op.Entity)
+	case operationtype.POST:
+		return writer.Post(resolutionContext, op.Entity)
+	case operationtype.DELETE:
+		if op.Selector == nil {
+			return nil, fmt.Errorf("selector cannot be nil")
+		}
+		return nil, writer.Delete(resolutionContext, op.Selector)
+	default:
+		return nil, fmt.Errorf("Invalid write operation: %v", op.Operation)
+	}
+}
+
+// Batch applies ops to resolver, returning the entity written by each op or
+// nil for deletes. If resolver is a TransactionalResolver the ops are applied
+// in a single transaction, otherwise they are applied in order and if one
+// fails the ops which were applied are compensated, in reverse order, by
+// restoring the entities they replaced. Compensation is best effort, other
+// writers may see the intermediate states and an error is returned listing
+// any compensations which failed
+func Batch(resolutionContext context.Context, resolver MutableResolver, ops []Op) ([]interface{}, error) {
+	if transactional, ok := resolver.(TransactionalResolver); ok {
+		return batchTransaction(resolutionContext, transactional, ops)
+	}
+
+	results := make([]interface{}, len(ops))
+	undos := make([]*undo, 0, len(ops))
+
+	for index, op := range ops {
+		undo, err := prepareUndo(resolutionContext, resolver, op)
+
+		if err == nil {
+			results[index], err = applyOp(resolutionContext, resolver, op)
+		}
+
+		if err != nil {
+			return nil, compensate(resolutionContext, resolver, undos, fmt.Errorf("Batch Failed: op %d: %w", index, err))
+		}
+
+		undos = append(undos, undo)
+	}
+
+	return results, nil
+}
+
+func batchTransaction(resolutionContext context.Context, resolver TransactionalResolver, ops []Op) ([]interface{}, error) {
+	transaction, err := resolver.Begin(resolutionContext)
+
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]interface{}, len(ops))
+
+	for index, op := range ops {
+		if results[index], err = applyOp(resolutionContext, transaction, op); err != nil {
+			transaction.Rollback(resolutionContext)
+			return nil, fmt.Errorf("Batch Failed: op %d: %w", index, err)
+		}
+	}
+
+	if err := transaction.Commit(resolutionContext); err != nil {
+		return nil, fmt.Errorf("Batch Failed: %w", err)
+	}
+
+	return results, nil
+}
+
+// undo records the state an op replaced
+type undo struct {
+	selector Selector
+	prior    interface{}
+	existed  bool
+}
+
+// keySelector selects the entity with key using a resolver's key extractor
+type keySelector struct {
+	entityType   ids.TypeIdentifier
+	key          interface{}
+	keyExtractor KeyExtractor
+}
+
+func (this *keySelector) Type() ids.TypeIdentifier {
+	return this.entityType
+}
+
+func (this *keySelector) Key() interface{} {
+	return this.key
+}
+
+func (this *keySelector) Test(candidate interface{}) bool {
+	key, ok := this.keyExtractor(candidate)
+	return ok && KeyString(key) == KeyString(this.key)
+}
+
+func prepareUndo(resolutionContext context.Context, resolver MutableResolver, op Op) (*undo, error) {
+	selector := op.Selector
+
+	if op.Operation != operationtype.DELETE {
+		info := resolver.ResolverInfo()
+		keyExtractor := info.KeyExtractor()
+
+		if keyExtractor == nil {
+			return nil, fmt.Errorf("Cannot extract key from: %v", op.Entity)
+		}
+
+		key, ok := keyExtractor(op.Entity)
+
+		if !ok {
+			return nil, fmt.Errorf("Cannot extract key from: %v", op.Entity)
+		}
+
+		var entityType ids.TypeIdentifier
+
+		if resolvableTypes := info.ResolvableTypes(); len(resolvableTypes) == 1 {
+			entityType = resolvableTypes[0]
+		}
+
+		selector = &keySelector{entityType, key, keyExtractor}
+	}
+
+	if selector == nil {
+		return nil, fmt.Errorf("selector cannot be nil")
+	}
+
+	prior, err := resolver.Get(resolutionContext, selector)
+
+	if err != nil && !IsNotFound(err) {
+		return nil, err
+	}
+
+	return &undo{selector, prior, err == nil}, nil
+}
+
+// compensate reverses undos, returning err or if any compensation fails a
+// MultiError of err and the compensation errors
+func compensate(resolutionContext context.Context, resolver MutableResolver, undos []*undo, err error) error {
+	errors := []error{err}
+
+	for index := len(undos) - 1; index >= 0; index-- {
+		undo := undos[index]
+		var undoErr error
+
+		if undo.existed {
+			_, undoErr = resolver.Put(resolutionContext, undo.prior)
+		} else {
+			undoErr = resolver.Delete(resolutionContext, undo.selector)
+		}
+
+		if undoErr != nil && !IsNotFound(undoErr) {
+			errors = append(errors, fmt.Errorf("Compensation of op %d failed: %w", index, undoErr))
+		}
+	}
+
+	if len(errors) == 1 {
+		return err
+	}
+
+	return NewMultiError("Batch Failed: compensation failed", errors)
+}
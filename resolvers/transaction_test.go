@@ -0,0 +1,110 @@
+package resolvers_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/localresolver"
+)
+
+// failingResolver hides the transaction support of the resolver it wraps,
+// and fails puts of the entity with failKey
+type failingResolver struct {
+	resolvers.MutableResolver
+	failKey string
+}
+
+func (this *failingResolver) Put(resolutionContext context.Context, candidate interface{}) (interface{}, error) {
+	if candidate.(entity).key == this.failKey {
+		return nil, fmt.Errorf("put failed")
+	}
+
+	return this.MutableResolver.Put(resolutionContext, candidate)
+}
+
+func getValue(resolver resolvers.Resolver, key string) string {
+	found, err := resolver.Get(testContext, &typedSelector{key: key})
+
+	if err != nil {
+		return ""
+	}
+
+	return found.(entity).value
+}
+
+func TestBatchCompensation(t *testing.T) {
+	local, _ := localresolver.New(testInfo)
+	local.Put(testContext, entity{"1", "a"})
+
+	resolver := &failingResolver{local, "3"}
+
+	_, err := resolvers.Batch(testContext, resolver, []resolvers.Op{
+		resolvers.PutOp(entity{"1", "b"}),
+		resolvers.PutOp(entity{"2", "c"}),
+		resolvers.DeleteOp(&typedSelector{key: "1"}),
+		resolvers.PutOp(entity{"3", "d"})})
+
+	if err == nil {
+		t.Fatal("TestBatchCompensation: Expected batch to fail")
+	}
+
+	if value := getValue(local, "1"); value != "a" {
+		t.Fatal("TestBatchCompensation: Expected entity 1 to be restored, got:", value)
+	}
+
+	if _, err := local.Get(testContext, &typedSelector{key: "2"}); !resolvers.IsNotFound(err) {
+		t.Fatal("TestBatchCompensation: Expected entity 2 to be removed, got:", err)
+	}
+
+	results, err := resolvers.Batch(testContext, resolver, []resolvers.Op{
+		resolvers.PutOp(entity{"2", "c"}),
+		resolvers.PostOp(entity{"1", "b"})})
+
+	if err != nil || len(results) != 2 || getValue(local, "1") != "b" || getValue(local, "2") != "c" {
+		t.Fatal("TestBatchCompensation: Batch failed:", results, err)
+	}
+}
+
+func TestBatchTransaction(t *testing.T) {
+	local, _ := localresolver.New(testInfo)
+	local.Put(testContext, entity{"1", "a"})
+
+	events := 0
+	local.OnChange(func(event resolvers.ChangeEvent) { events++ })
+
+	_, err := resolvers.Batch(testContext, local, []resolvers.Op{
+		resolvers.PutOp(entity{"1", "b"}),
+		resolvers.PutOp(entity{"2", "c"}),
+		resolvers.PostOp(entity{"missing", "d"})})
+
+	if !resolvers.IsNotFound(err) {
+		t.Fatal("TestBatchTransaction: Expected not found, got:", err)
+	}
+
+	if getValue(local, "1") != "a" || getValue(local, "2") != "" || events != 0 {
+		t.Fatal("TestBatchTransaction: Expected failed transaction to leave entities unchanged")
+	}
+
+	if _, err := resolvers.Batch(testContext, local, []resolvers.Op{
+		resolvers.PutOp(entity{"2", "c"}),
+		resolvers.DeleteOp(&typedSelector{key: "1"})}); err != nil {
+		t.Fatal("TestBatchTransaction: Batch failed:", err)
+	}
+
+	if getValue(local, "1") != "" || getValue(local, "2") != "c" || events != 2 {
+		t.Fatal("TestBatchTransaction: Unexpected state after commit, events:", events)
+	}
+
+	transaction, _ := local.Begin(testContext)
+	transaction.Put(testContext, entity{"3", "e"})
+
+	if err := transaction.Rollback(testContext); err != nil || getValue(local, "3") != "" {
+		t.Fatal("TestBatchTransaction: Rollback failed:", err)
+	}
+
+	if err := transaction.Commit(testContext); err == nil {
+		t.Fatal("TestBatchTransaction: Expected commit after rollback to fail")
+	}
+}
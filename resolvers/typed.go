@@ -0,0 +1,138 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/distributed-vision/go-resources/util"
+)
+
+// As converts a resolved entity to T, failing if the entity isn't a T
+func As[T any](entity interface{}) (T, error) {
+	if value, ok := entity.(T); ok {
+		return value, nil
+	}
+
+	var zero T
+
+	return zero, fmt.Errorf("Resolver returned invalid type, expected: %s got: %s",
+		reflect.TypeOf((*T)(nil)).Elem(), reflect.TypeOf(entity))
+}
+
+// Convert converts the entity delivered on cres to T
+func Convert[T any](cres chan interface{}, cerr chan error) (chan T, chan error) {
+	cResOut, cErrOut := make(chan T, 1), make(chan error, 1)
+
+	go func() {
+		entity, err := util.Await(cres, cerr)
+
+		if err == nil {
+			var value T
+
+			if value, err = As[T](entity); err == nil {
+				cResOut <- value
+			}
+		}
+
+		if err != nil {
+			cErrOut <- err
+		}
+
+		close(cResOut)
+		close(cErrOut)
+	}()
+
+	return cResOut, cErrOut
+}
+
+// ConvertAll converts the entities delivered on cres to T, entities which
+// aren't a T are skipped
+func ConvertAll[T any](queryContext context.Context, cres <-chan interface{}, cerr <-chan error) (chan T, chan error) {
+	cResOut, cErrOut := make(chan T), make(chan error, 1)
+
+	go func() {
+		defer close(cErrOut)
+		defer close(cResOut)
+
+		for entity := range cres {
+			if value, ok := entity.(T); ok {
+				select {
+				case cResOut <- value:
+				case <-queryContext.Done():
+				}
+			}
+		}
+
+		if err, ok := <-cerr; ok && err != nil {
+			cErrOut <- err
+		}
+	}()
+
+	return cResOut, cErrOut
+}
+
+// GetAs resolves selector with the root resolver as a T
+func GetAs[T any](resolutionContext context.Context, selector Selector) (T, error) {
+	return NewTypedResolver[T](rootResolver).Get(resolutionContext, selector)
+}
+
+// ResolveAs resolves selector with the root resolver as a T
+func ResolveAs[T any](resolutionContext context.Context, selector Selector) (chan T, chan error) {
+	return NewTypedResolver[T](rootResolver).Resolve(resolutionContext, selector)
+}
+
+// TypedResolver adapts a Resolver to deliver its entities as T
+type TypedResolver[T any] struct {
+	resolver Resolver
+}
+
+func NewTypedResolver[T any](resolver Resolver) *TypedResolver[T] {
+	return &TypedResolver[T]{resolver}
+}
+
+// Resolver returns the adapted resolver
+func (this *TypedResolver[T]) Resolver() Resolver {
+	return this.resolver
+}
+
+func (this *TypedResolver[T]) Get(resolutionContext context.Context, selector Selector) (T, error) {
+	entity, err := this.resolver.Get(resolutionContext, selector)
+
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return As[T](entity)
+}
+
+func (this *TypedResolver[T]) Resolve(resolutionContext context.Context, selector Selector) (chan T, chan error) {
+	return this.ResolveFuture(resolutionContext, selector).Channels()
+}
+
+// ResolveFuture resolves selector as a T, cancelling the returned future
+// cancels the resolution
+func (this *TypedResolver[T]) ResolveFuture(resolutionContext context.Context, selector Selector) *util.Future[T] {
+	return util.Go(resolutionContext, func(resolutionContext context.Context) (T, error) {
+		return this.Get(resolutionContext, selector)
+	})
+}
+
+// Query streams the entities matching selector which are a T, the adapted
+// resolver must be a QueryResolver
+func (this *TypedResolver[T]) Query(queryContext context.Context, selector Selector, opts QueryOpts) (chan T, chan error) {
+	queryResolver, ok := this.resolver.(QueryResolver)
+
+	if !ok {
+		cResOut, cErrOut := make(chan T), make(chan error, 1)
+		cErrOut <- fmt.Errorf("Query Failed: %s doesn't support queries", reflect.TypeOf(this.resolver))
+		close(cResOut)
+		close(cErrOut)
+		return cResOut, cErrOut
+	}
+
+	cres, cerr := queryResolver.Query(queryContext, selector, opts)
+
+	return ConvertAll[T](queryContext, cres, cerr)
+}
@@ -0,0 +1,108 @@
+package resolvers_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/localresolver"
+	"github.com/distributed-vision/go-resources/util"
+	"github.com/distributed-vision/go-resources/util/random"
+)
+
+func TestAs(t *testing.T) {
+	if value, err := resolvers.As[entity](entity{"key", "value"}); err != nil || value.value != "value" {
+		t.Fatal("TestAs: As failed:", value, err)
+	}
+
+	if _, err := resolvers.As[entity]("value"); err == nil || !strings.Contains(err.Error(), "invalid type") {
+		t.Fatal("TestAs: Expected invalid type error, got:", err)
+	}
+}
+
+func TestTypedResolver(t *testing.T) {
+	typed := resolvers.NewTypedResolver[entity](newStrategyResolver(t, testInfo, newStubResolver(1, 0, "typed", nil)))
+
+	value, err := typed.Get(testContext, &typedSelector{key: random.RandomString(20)})
+
+	if err != nil || value.value != "typed" {
+		t.Fatal("TestTypedResolver: Get failed:", value, err)
+	}
+
+	value, err = util.Await(typed.Resolve(testContext, &typedSelector{key: random.RandomString(20)}))
+
+	if err != nil || value.value != "typed" {
+		t.Fatal("TestTypedResolver: Resolve failed:", value, err)
+	}
+
+	mistyped := resolvers.NewTypedResolver[string](typed.Resolver())
+
+	if _, err := mistyped.Get(testContext, &typedSelector{key: random.RandomString(20)}); err == nil {
+		t.Fatal("TestTypedResolver: Expected Get of wrong type to fail")
+	}
+
+	if _, err := util.AwaitAll(resolvers.NewTypedResolver[entity](newStubResolver(1, 0, "stub", nil)).Query(testContext, &typedSelector{}, resolvers.QueryOpts{})); err == nil {
+		t.Fatal("TestTypedResolver: Expected Query of non query resolver to fail")
+	}
+}
+
+func TestTypedQuery(t *testing.T) {
+	local, err := localresolver.New(resolvers.NewResolverInfo(testResolverType, testResolvableTypes, nil, testExtractor, nil))
+
+	if err != nil {
+		t.Fatal("TestTypedQuery: New failed:", err)
+	}
+
+	value := random.RandomString(20)
+
+	for i := 0; i < 3; i++ {
+		if _, err := local.Put(testContext, entity{random.RandomString(20), value}); err != nil {
+			t.Fatal("TestTypedQuery: Put failed:", err)
+		}
+	}
+
+	results, err := util.AwaitAll(resolvers.NewTypedResolver[entity](local).Query(testContext, &typedSelector{value: value}, resolvers.QueryOpts{}))
+
+	if err != nil || len(results) != 3 {
+		t.Fatal("TestTypedQuery: Expected 3 results, got:", results, err)
+	}
+}
+
+func TestAwaitClosed(t *testing.T) {
+	cres, cerr := make(chan entity), make(chan error)
+	close(cres)
+	close(cerr)
+
+	if _, err := util.Await(cres, cerr); err == nil {
+		t.Fatal("TestAwaitClosed: Expected Await of closed channels to fail")
+	}
+}
+
+func TestResolveFutureCancel(t *testing.T) {
+	resolver := newStrategyResolver(t, testInfo, newStubResolver(1, time.Hour, "slow", nil))
+	baseline := runtime.NumGoroutine()
+
+	future := resolver.ResolveFuture(testContext, &typedSelector{key: random.RandomString(20)})
+	typed := resolvers.NewTypedResolver[entity](resolver).ResolveFuture(testContext, &typedSelector{key: random.RandomString(20)})
+
+	future.Cancel()
+	typed.Cancel()
+
+	if _, err := future.Await(); !errors.Is(err, context.Canceled) {
+		t.Fatal("TestResolveFutureCancel: Expected cancellation, got:", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+
+	for runtime.NumGoroutine() > baseline {
+		if time.Now().After(deadline) {
+			t.Fatal("TestResolveFutureCancel: Goroutines leaked after cancellation:", runtime.NumGoroutine()-baseline)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
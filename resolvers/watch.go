@@ -0,0 +1,181 @@
+package resolvers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/distributed-vision/go-resources/resolvers/changetype"
+)
+
+var DefaultWatchBuffer = 64
+
+// ChangeFeed implements ChangeNotifier and Watchable for the resolvers
+// which embed it, its zero value is ready to use. Publishing never blocks on
+// watchers, events which don't fit in a watcher's buffer are queued and
+// delivered by the watcher's goroutine, with queued events for the same key
+// coalesced to the latest, so a slow watcher sees the final state of each key
+// but may miss intermediate changes
+type ChangeFeed struct {
+	mutex     sync.Mutex
+	listeners []ChangeListener
+	watchers  map[*watcher]bool
+}
+
+type watcher struct {
+	selector     Selector
+	events       chan ChangeEvent
+	watchContext context.Context
+	signal       chan struct{}
+	mutex        sync.Mutex
+	pending      []ChangeEvent
+	pendingKeys  map[string]int
+	delivering   bool
+	closed       bool
+}
+
+func (this *watcher) matches(event ChangeEvent) bool {
+	if this.selector == nil {
+		return true
+	}
+
+	if event.Entity != nil && this.selector.Test(event.Entity) {
+		return true
+	}
+
+	key := KeyString(this.selector.Key())
+
+	return key != "" && key == KeyString(event.Key)
+}
+
+// send delivers event directly if nothing is queued and the buffer has
+// space, otherwise it queues it for deliver
+func (this *watcher) send(event ChangeEvent) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if this.closed {
+		return
+	}
+
+	if !this.delivering && len(this.pending) == 0 {
+		select {
+		case this.events <- event:
+			return
+		default:
+		}
+	}
+
+	key := KeyString(event.Key)
+
+	if index, ok := this.pendingKeys[key]; ok && key != "" {
+		this.pending[index] = event
+	} else {
+		if this.pendingKeys == nil {
+			this.pendingKeys = make(map[string]int)
+		}
+		this.pendingKeys[key] = len(this.pending)
+		this.pending = append(this.pending, event)
+	}
+
+	select {
+	case this.signal <- struct{}{}:
+	default:
+	}
+}
+
+// deliver sends queued events until the watch context is done, then closes
+// the events channel
+func (this *watcher) deliver(feed *ChangeFeed) {
+	for {
+		select {
+		case <-this.signal:
+		case <-this.watchContext.Done():
+			this.close(feed)
+			return
+		}
+
+		for {
+			this.mutex.Lock()
+			pending := this.pending
+			this.pending, this.pendingKeys = nil, nil
+			this.delivering = len(pending) > 0
+			this.mutex.Unlock()
+
+			if len(pending) == 0 {
+				break
+			}
+
+			for _, event := range pending {
+				select {
+				case this.events <- event:
+				case <-this.watchContext.Done():
+					this.close(feed)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (this *watcher) close(feed *ChangeFeed) {
+	feed.mutex.Lock()
+	delete(feed.watchers, this)
+	feed.mutex.Unlock()
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.closed = true
+	close(this.events)
+}
+
+func (this *ChangeFeed) OnChange(listener ChangeListener) {
+	this.mutex.Lock()
+	this.listeners = append(this.listeners, listener)
+	this.mutex.Unlock()
+}
+
+func (this *ChangeFeed) Watch(watchContext context.Context, selector Selector) <-chan ChangeEvent {
+	subscription := &watcher{
+		selector:     selector,
+		events:       make(chan ChangeEvent, DefaultWatchBuffer),
+		watchContext: watchContext,
+		signal:       make(chan struct{}, 1)}
+
+	this.mutex.Lock()
+	if this.watchers == nil {
+		this.watchers = make(map[*watcher]bool)
+	}
+	this.watchers[subscription] = true
+	this.mutex.Unlock()
+
+	go subscription.deliver(this)
+
+	return subscription.events
+}
+
+// Publish reports a change to the feed's listeners and to the watchers
+// whose selectors match it
+func (this *ChangeFeed) Publish(changeType changetype.ChangeType, key interface{}, entity interface{}) {
+	this.publish(ChangeEvent{changeType, key, entity})
+}
+
+func (this *ChangeFeed) publish(event ChangeEvent) {
+	this.mutex.Lock()
+	listeners := this.listeners
+	watchers := make([]*watcher, 0, len(this.watchers))
+	for watcher := range this.watchers {
+		watchers = append(watchers, watcher)
+	}
+	this.mutex.Unlock()
+
+	for _, listener := range listeners {
+		listener(event)
+	}
+
+	for _, watcher := range watchers {
+		if watcher.matches(event) {
+			watcher.send(event)
+		}
+	}
+}
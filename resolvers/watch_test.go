@@ -0,0 +1,157 @@
+package resolvers_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/distributed-vision/go-resources/resolvers"
+	"github.com/distributed-vision/go-resources/resolvers/changetype"
+	"github.com/distributed-vision/go-resources/resolvers/localresolver"
+	"github.com/distributed-vision/go-resources/util/random"
+)
+
+// watchOnly hides a resolver's OnChange so that it is only Watchable
+type watchOnly struct {
+	*localresolver.LocalResolver
+}
+
+func (this *watchOnly) OnChange() {}
+
+func nextEvent(t *testing.T, events <-chan resolvers.ChangeEvent) resolvers.ChangeEvent {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a change event")
+		return resolvers.ChangeEvent{}
+	}
+}
+
+func newLocalComponent(t *testing.T) *localresolver.LocalResolver {
+	component, err := localresolver.New(resolvers.NewResolverInfo(testResolverType, testResolvableTypes, nil, testExtractor, nil))
+
+	if err != nil {
+		t.Fatal("localresolver.New failed:", err)
+	}
+
+	return component
+}
+
+func TestWatch(t *testing.T) {
+	component := newLocalComponent(t)
+	watchContext, cancel := context.WithCancel(testContext)
+
+	key := random.RandomString(20)
+	events := component.Watch(watchContext, &typedSelector{key: key})
+
+	component.Put(testContext, entity{random.RandomString(20), "other"})
+	component.Put(testContext, entity{key, "first"})
+	component.Post(testContext, entity{key, "second"})
+	component.Delete(testContext, &typedSelector{key: key})
+
+	for _, expected := range []changetype.ChangeType{changetype.PUT, changetype.UPDATE, changetype.DELETE} {
+		if event := nextEvent(t, events); event.Type != expected || event.Key != key {
+			t.Fatalf("TestWatch: expected %s for %s got: %+v", expected, key, event)
+		}
+	}
+
+	cancel()
+
+	select {
+	case event, ok := <-events:
+		if ok {
+			t.Fatal("TestWatch: unexpected event:", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("TestWatch: expected the events channel to be closed")
+	}
+}
+
+func TestCompositeWatch(t *testing.T) {
+	first, second := newLocalComponent(t), newLocalComponent(t)
+
+	resolver, err := resolvers.NewCompositeResolver(testInfo)
+
+	if err != nil {
+		t.Fatal("TestCompositeWatch: NewCompositeResolver failed:", err)
+	}
+
+	resolver.RegisterComponent(first)
+	resolver.RegisterComponent(&watchOnly{second})
+
+	watchContext, cancel := context.WithCancel(testContext)
+	defer cancel()
+
+	events := resolver.Watch(watchContext, nil)
+
+	firstKey, secondKey := random.RandomString(20), random.RandomString(20)
+
+	first.Put(testContext, entity{firstKey, "first"})
+
+	if event := nextEvent(t, events); event.Key != firstKey || event.Type != changetype.PUT {
+		t.Fatal("TestCompositeWatch: unexpected event:", event)
+	}
+
+	second.Put(testContext, entity{secondKey, "first"})
+
+	if event := nextEvent(t, events); event.Key != secondKey {
+		t.Fatal("TestCompositeWatch: unexpected event:", event)
+	}
+
+	if resolved, err := resolver.Get(testContext, &typedSelector{key: secondKey}); err != nil || resolved.(entity).value != "first" {
+		t.Fatal("TestCompositeWatch: Get failed:", resolved, err)
+	}
+
+	second.Post(testContext, entity{secondKey, "second"})
+
+	if event := nextEvent(t, events); event.Type != changetype.UPDATE {
+		t.Fatal("TestCompositeWatch: expected an update got:", event)
+	}
+
+	if resolved, err := resolver.Get(testContext, &typedSelector{key: secondKey}); err != nil || resolved.(entity).value != "second" {
+		t.Fatal("TestCompositeWatch: watched changes should invalidate the cache got:", resolved, err)
+	}
+}
+
+func TestWatchSlowWatcher(t *testing.T) {
+	feed := &resolvers.ChangeFeed{}
+	watchContext, cancel := context.WithCancel(testContext)
+	defer cancel()
+
+	events := feed.Watch(watchContext, nil)
+	published := make(chan bool)
+	keys := 10
+
+	go func() {
+		for i := 0; i < resolvers.DefaultWatchBuffer*4; i++ {
+			feed.Publish(changetype.UPDATE, i%keys, i)
+		}
+		published <- true
+	}()
+
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("TestWatchSlowWatcher: Publish blocked on a watcher which isn't reading")
+	}
+
+	latest := make(map[interface{}]interface{})
+
+	for len(latest) < keys || !finalValues(latest, resolvers.DefaultWatchBuffer*4, keys) {
+		event := nextEvent(t, events)
+		latest[event.Key] = event.Entity
+	}
+}
+
+// finalValues returns true if latest holds the last value published for each
+// of the keys
+func finalValues(latest map[interface{}]interface{}, count int, keys int) bool {
+	for i := count - keys; i < count; i++ {
+		if latest[i%keys] != i {
+			return false
+		}
+	}
+
+	return true
+}
@@ -2,12 +2,62 @@ package translators
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
 	"sync"
+	"sync/atomic"
 
 	"github.com/distributed-vision/go-resources/ids"
+	"github.com/distributed-vision/go-resources/util"
 )
 
+// ErrNoTranslator is matched by errors.Is against NoTranslator errors
+var ErrNoTranslator = errors.New("No translator")
+
+// NoTranslator reports a translation between types which no translator is
+// registered for
+type NoTranslator struct {
+	FromType ids.TypeIdentifier
+	ToType   ids.TypeIdentifier
+}
+
+func (e *NoTranslator) Error() string {
+	return fmt.Sprintf("Can't find translator for: %v to %v", e.FromType, e.ToType)
+}
+
+func (e *NoTranslator) Is(target error) bool {
+	return target == ErrNoTranslator
+}
+
+func NewNoTranslator(fromType ids.TypeIdentifier, toType ids.TypeIdentifier) *NoTranslator {
+	return &NoTranslator{fromType, toType}
+}
+
+// Span is ended with the error, if any, of the translation it measures
+type Span interface {
+	End(err error)
+}
+
+// SpanStarter starts the span around a translation to toType
+type SpanStarter func(spanContext context.Context, toType ids.TypeIdentifier) (context.Context, Span)
+
+type spanStarterHolder struct {
+	SpanStarter
+}
+
+var spanStarter atomic.Value
+
+func init() {
+	spanStarter.Store(spanStarterHolder{})
+}
+
+// SetSpanStarter installs the span starter used to measure translations,
+// passing nil removes it
+func SetSpanStarter(starter SpanStarter) {
+	spanStarter.Store(spanStarterHolder{starter})
+}
+
 type TranslationFunction func(translationContext context.Context, fromId ids.Identifier, fromValue interface{}) (chan interface{}, chan error)
 
 type translationEntry struct {
@@ -33,18 +83,42 @@ func Translate(translationContext context.Context, fromType ids.TypeIdentifier,
 
 	if translator != nil {
 		//fmt.Printf("TRANS\n")
+		if starter := spanStarter.Load().(spanStarterHolder).SpanStarter; starter != nil {
+			return instrumented(translationContext, starter, translator, fromId, fromValue, toType)
+		}
+
 		return translator(translationContext, fromId, fromValue)
 	}
 	//fmt.Printf("NO TRANS\n")
 	cres := make(chan interface{}, 1)
 	cerr := make(chan error, 1)
 
-	cerr <- fmt.Errorf("Can't find transtaor for: %v to %v", fromType, toType)
+	cerr <- NewNoTranslator(fromType, toType)
 	close(cres)
 	close(cerr)
 	return cres, cerr
 }
 
+// TranslateFuture translates fromValue as Translate does, cancelling the
+// returned future abandons the translation
+func TranslateFuture(translationContext context.Context, fromType ids.TypeIdentifier, fromId ids.Identifier, fromValue interface{}, toType ids.TypeIdentifier) *util.Future[interface{}] {
+	cres, cerr := Translate(translationContext, fromType, fromId, fromValue, toType)
+	return util.FromChannels[interface{}](translationContext, cres, cerr)
+}
+
+// Exists returns true if a translator is registered from fromType to toType
+func Exists(fromType ids.TypeIdentifier, toType ids.TypeIdentifier) bool {
+	translatorMutex.Lock()
+	defer translatorMutex.Unlock()
+
+	if entryMap, ok := translators[string(fromType.Value())]; ok {
+		_, ok := entryMap[string(toType.Value())]
+		return ok
+	}
+
+	return false
+}
+
 func Register(translationContext context.Context, fromType ids.TypeIdentifier, toType ids.TypeIdentifier, translator TranslationFunction) TranslationFunction {
 	var previous TranslationFunction
 	//fmt.Printf("TRNSREG: %v -> %v\n", fromType, toType)
@@ -65,3 +139,59 @@ func Register(translationContext context.Context, fromType ids.TypeIdentifier, t
 
 	return previous
 }
+
+// instrumented runs translator inside a span which ends when the translation
+// delivers its result
+func instrumented(translationContext context.Context, starter SpanStarter, translator TranslationFunction, fromId ids.Identifier, fromValue interface{}, toType ids.TypeIdentifier) (chan interface{}, chan error) {
+	spanContext, span := starter(translationContext, toType)
+	cres, cerr := translator(spanContext, fromId, fromValue)
+	translation := util.FromChannels[interface{}](spanContext, cres, cerr)
+
+	// the span ends before the result is delivered
+	return util.Go(spanContext, func(context.Context) (interface{}, error) {
+		result, err := translation.Await()
+		span.End(err)
+		return result, err
+	}).Channels()
+}
+
+var mapGoType = reflect.TypeOf(map[string]interface{}{})
+
+// ToMap translates entity to its json map form using a translator from its
+// go type, or from the first of fromTypes which has one. Maps are returned
+// unchanged, other entities fail with NoTranslator if no translator is
+// registered as most entities have unexported fields which don't marshal
+func ToMap(translationContext context.Context, entity interface{}, fromTypes ...ids.TypeIdentifier) (map[string]interface{}, error) {
+	if jsonEntity, ok := entity.(map[string]interface{}); ok {
+		return jsonEntity, nil
+	}
+
+	if entity == nil {
+		return nil, fmt.Errorf("Can't translate a nil entity")
+	}
+
+	mapType := ids.NewLocalTypeId(mapGoType)
+	entityType := ids.NewLocalTypeId(reflect.TypeOf(entity))
+
+	for _, fromType := range append([]ids.TypeIdentifier{entityType}, fromTypes...) {
+		if fromType == nil || !Exists(fromType, mapType) {
+			continue
+		}
+
+		translated, err := TranslateFuture(translationContext, fromType, nil, entity, mapType).Await()
+
+		if err != nil {
+			return nil, err
+		}
+
+		jsonEntity, ok := translated.(map[string]interface{})
+
+		if !ok {
+			return nil, fmt.Errorf("Translator from %v returned %T, expected a json map", fromType, translated)
+		}
+
+		return jsonEntity, nil
+	}
+
+	return nil, NewNoTranslator(entityType, mapType)
+}
@@ -2,28 +2,29 @@ package util
 
 import "fmt"
 
-func Await(cres chan interface{}, cerr chan error) (result interface{}, err error) {
+// Await waits for the first value or error delivered on cres or cerr, it
+// fails if both channels are closed without delivering either
+func Await[T any](cres chan T, cerr chan error) (result T, err error) {
 	if cres == nil || cerr == nil {
-		return nil, fmt.Errorf("Await Failed: channels are undefined")
+		return result, fmt.Errorf("Await Failed: channels are undefined")
 	}
 
-	resolved := false
-	for !resolved {
+	for cres != nil || cerr != nil {
 		select {
 		case res, ok := <-cres:
 			if ok {
-				result = res
-				resolved = true
+				return res, nil
 			}
+			cres = nil
 		case error, ok := <-cerr:
 			if ok {
-				err = error
-				resolved = true
+				return result, error
 			}
+			cerr = nil
 		}
 	}
 
-	return result, err
+	return result, fmt.Errorf("Await Failed: channels closed without a result")
 }
 
 func AwaitError(cerr chan error) (err error) {
@@ -35,3 +36,32 @@ func AwaitError(cerr chan error) (err error) {
 
 	return err
 }
+
+// AwaitAll collects all of the results delivered on cres, returning them
+// along with any error delivered on cerr
+func AwaitAll[T any](cres <-chan T, cerr <-chan error) (results []T, err error) {
+	if cres == nil || cerr == nil {
+		return nil, fmt.Errorf("Await Failed: channels are undefined")
+	}
+
+	for cres != nil || cerr != nil {
+		select {
+		case res, ok := <-cres:
+			if ok {
+				results = append(results, res)
+			} else {
+				cres = nil
+			}
+		case error, ok := <-cerr:
+			if ok {
+				if err == nil {
+					err = error
+				}
+			} else {
+				cerr = nil
+			}
+		}
+	}
+
+	return results, err
+}
@@ -0,0 +1,89 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of time used by components which expire or time out
+// state, it allows tests to control the passage of time
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+var System Clock = systemClock{}
+
+type waiter struct {
+	deadline time.Time
+	c        chan time.Time
+}
+
+// Manual is a Clock which only moves when it is advanced
+type Manual struct {
+	mutex   *sync.Mutex
+	now     time.Time
+	waiters []waiter
+}
+
+func NewManual(now time.Time) *Manual {
+	return &Manual{mutex: &sync.Mutex{}, now: now}
+}
+
+func (this *Manual) Now() time.Time {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.now
+}
+
+func (this *Manual) After(d time.Duration) <-chan time.Time {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	c := make(chan time.Time, 1)
+
+	if d <= 0 {
+		c <- this.now
+	} else {
+		this.waiters = append(this.waiters, waiter{this.now.Add(d), c})
+	}
+
+	return c
+}
+
+// Advance moves the clock forward by d, firing any channels returned by
+// After whose deadline has been reached
+func (this *Manual) Advance(d time.Duration) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.now = this.now.Add(d)
+	waiters := this.waiters[:0]
+
+	for _, waiter := range this.waiters {
+		if waiter.deadline.After(this.now) {
+			waiters = append(waiters, waiter)
+		} else {
+			waiter.c <- this.now
+		}
+	}
+
+	this.waiters = waiters
+}
+
+// Waiters returns the number of After channels which have not yet fired
+func (this *Manual) Waiters() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return len(this.waiters)
+}
@@ -0,0 +1,358 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Future is the pending result of an asynchronous call. A future completes
+// once, with either a result or an error, and can be awaited any number of
+// times. Cancelling a future completes it with context.Canceled and cancels
+// the call producing it
+type Future[T any] struct {
+	done   chan struct{}
+	once   sync.Once
+	result T
+	err    error
+	cancel context.CancelFunc
+}
+
+func newFuture[T any](cancel context.CancelFunc) *Future[T] {
+	return &Future[T]{done: make(chan struct{}), cancel: cancel}
+}
+
+// complete sets the future's result, returning false if it had already
+// completed
+func (this *Future[T]) complete(result T, err error) bool {
+	completed := false
+
+	this.once.Do(func() {
+		this.result, this.err = result, err
+		completed = true
+		close(this.done)
+
+		if this.cancel != nil {
+			this.cancel()
+		}
+	})
+
+	return completed
+}
+
+// Done returns a channel which is closed when the future completes
+func (this *Future[T]) Done() <-chan struct{} {
+	return this.done
+}
+
+// Await waits for the future's result
+func (this *Future[T]) Await() (T, error) {
+	<-this.done
+	return this.result, this.err
+}
+
+// AwaitContext waits for the future's result or for awaitContext to be done,
+// in which case it returns the context's error and leaves the future running
+func (this *Future[T]) AwaitContext(awaitContext context.Context) (T, error) {
+	select {
+	case <-this.done:
+		return this.result, this.err
+	case <-awaitContext.Done():
+		var zero T
+		return zero, awaitContext.Err()
+	}
+}
+
+// Cancel completes the future with context.Canceled, if it hasn't already
+// completed, and cancels the call producing it
+func (this *Future[T]) Cancel() {
+	var zero T
+	this.complete(zero, context.Canceled)
+}
+
+// deliver sends the result of a completed future on cres and cerr, then
+// closes them
+func (this *Future[T]) deliver(cres chan T, cerr chan error) {
+	if this.err != nil {
+		cerr <- this.err
+	} else if cres != nil {
+		cres <- this.result
+	}
+
+	if cres != nil {
+		close(cres)
+	}
+	close(cerr)
+}
+
+// Channels delivers the future's result on a pair of result and error
+// channels, each is buffered and closed after the future completes. Completed
+// futures fill the channels directly, otherwise a goroutine waits for the
+// future to complete, so futures which may never complete should be
+// cancelled when their channels are abandoned
+func (this *Future[T]) Channels() (chan T, chan error) {
+	cres, cerr := make(chan T, 1), make(chan error, 1)
+
+	select {
+	case <-this.done:
+		this.deliver(cres, cerr)
+	default:
+		go func() {
+			<-this.done
+			this.deliver(cres, cerr)
+		}()
+	}
+
+	return cres, cerr
+}
+
+// ErrorChannel delivers the future's error, if any, on a buffered channel
+// which is closed after the future completes. Like Channels it waits for
+// pending futures in a goroutine which exits when the future completes
+func (this *Future[T]) ErrorChannel() chan error {
+	cerr := make(chan error, 1)
+
+	select {
+	case <-this.done:
+		this.deliver(nil, cerr)
+	default:
+		go func() {
+			<-this.done
+			this.deliver(nil, cerr)
+		}()
+	}
+
+	return cerr
+}
+
+// Promise is the producing side of a future
+type Promise[T any] struct {
+	future *Future[T]
+}
+
+func NewPromise[T any]() *Promise[T] {
+	return &Promise[T]{newFuture[T](nil)}
+}
+
+func (this *Promise[T]) Future() *Future[T] {
+	return this.future
+}
+
+// Resolve completes the promise's future with result, returning false if it
+// had already completed
+func (this *Promise[T]) Resolve(result T) bool {
+	return this.future.complete(result, nil)
+}
+
+// Reject completes the promise's future with err, returning false if it had
+// already completed
+func (this *Promise[T]) Reject(err error) bool {
+	var zero T
+	return this.future.complete(zero, err)
+}
+
+func Resolved[T any](result T) *Future[T] {
+	future := newFuture[T](nil)
+	future.complete(result, nil)
+	return future
+}
+
+func Rejected[T any](err error) *Future[T] {
+	future := newFuture[T](nil)
+	var zero T
+	future.complete(zero, err)
+	return future
+}
+
+// Go runs call in a goroutine, the context passed to call is cancelled when
+// the returned future completes or is cancelled
+func Go[T any](callContext context.Context, call func(context.Context) (T, error)) *Future[T] {
+	callContext, cancel := context.WithCancel(callContext)
+	future := newFuture[T](cancel)
+
+	go func() {
+		result, err := call(callContext)
+		future.complete(result, err)
+	}()
+
+	return future
+}
+
+// FromChannels adapts a result and error channel pair to a future. The
+// future fails if both channels close without delivering a value, and is
+// cancelled if channelContext is done first
+func FromChannels[T any](channelContext context.Context, cres <-chan T, cerr <-chan error) *Future[T] {
+	if cres == nil || cerr == nil {
+		return Rejected[T](fmt.Errorf("Await Failed: channels are undefined"))
+	}
+
+	return Go(channelContext, func(channelContext context.Context) (result T, err error) {
+		for cres != nil || cerr != nil {
+			select {
+			case res, ok := <-cres:
+				if ok {
+					return res, nil
+				}
+				cres = nil
+			case err, ok := <-cerr:
+				if ok {
+					return result, err
+				}
+				cerr = nil
+			case <-channelContext.Done():
+				return result, channelContext.Err()
+			}
+		}
+
+		return result, fmt.Errorf("Await Failed: channels closed without a result")
+	})
+}
+
+// FromErrorChannel adapts an error channel to a future which succeeds if the
+// channel closes without delivering an error
+func FromErrorChannel(channelContext context.Context, cerr <-chan error) *Future[struct{}] {
+	if cerr == nil {
+		return Rejected[struct{}](fmt.Errorf("Await Failed: channel is undefined"))
+	}
+
+	return Go(channelContext, func(channelContext context.Context) (struct{}, error) {
+		select {
+		case err := <-cerr:
+			return struct{}{}, err
+		case <-channelContext.Done():
+			return struct{}{}, channelContext.Err()
+		}
+	})
+}
+
+// Then returns a future for the result of applying next to the result of
+// future. Errors are passed through without calling next, cancelling the
+// returned future cancels future
+func Then[T any, U any](future *Future[T], next func(T) (U, error)) *Future[U] {
+	then := newFuture[U](future.Cancel)
+
+	go func() {
+		select {
+		case <-future.done:
+		case <-then.done:
+			return
+		}
+
+		if future.err != nil {
+			var zero U
+			then.complete(zero, future.err)
+			return
+		}
+
+		result, err := next(future.result)
+		then.complete(result, err)
+	}()
+
+	return then
+}
+
+// cancelAll returns a function which cancels futures
+func cancelAll[T any](futures []*Future[T]) context.CancelFunc {
+	return func() {
+		for _, future := range futures {
+			future.Cancel()
+		}
+	}
+}
+
+// All returns a future for the results of futures, in the order of futures.
+// It fails with the first error, cancelling the futures which are still
+// pending. Cancelling the returned future cancels futures
+func All[T any](futures ...*Future[T]) *Future[[]T] {
+	all := newFuture[[]T](cancelAll(futures))
+	results := make([]T, len(futures))
+	var wg sync.WaitGroup
+
+	wg.Add(len(futures))
+
+	for index, future := range futures {
+		go func(index int, future *Future[T]) {
+			defer wg.Done()
+
+			select {
+			case <-future.done:
+			case <-all.done:
+				return
+			}
+
+			if future.err != nil {
+				all.complete(nil, future.err)
+			} else {
+				results[index] = future.result
+			}
+		}(index, future)
+	}
+
+	go func() {
+		wg.Wait()
+		all.complete(results, nil)
+	}()
+
+	return all
+}
+
+// Any returns a future for the first of futures to succeed, cancelling the
+// others. It fails if all of futures fail. Cancelling the returned future
+// cancels futures
+func Any[T any](futures ...*Future[T]) *Future[T] {
+	first := newFuture[T](cancelAll(futures))
+	errors := make([]error, len(futures))
+	var wg sync.WaitGroup
+
+	wg.Add(len(futures))
+
+	for index, future := range futures {
+		go func(index int, future *Future[T]) {
+			defer wg.Done()
+
+			select {
+			case <-future.done:
+			case <-first.done:
+				return
+			}
+
+			if future.err == nil {
+				first.complete(future.result, nil)
+			} else {
+				errors[index] = future.err
+			}
+		}(index, future)
+	}
+
+	go func() {
+		wg.Wait()
+		var zero T
+		first.complete(zero, fmt.Errorf("Await Failed: all futures failed with the following errors %v", errors))
+	}()
+
+	return first
+}
+
+// WithTimeout returns a future for the result of future which fails with
+// context.DeadlineExceeded, and cancels future, if it doesn't complete within
+// timeout
+func WithTimeout[T any](future *Future[T], timeout time.Duration) *Future[T] {
+	timed := newFuture[T](future.Cancel)
+
+	go func() {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case <-future.done:
+			timed.complete(future.result, future.err)
+		case <-timer.C:
+			var zero T
+			timed.complete(zero, fmt.Errorf("Await Failed: timed out after %v: %w", timeout, context.DeadlineExceeded))
+		case <-timed.done:
+		}
+	}()
+
+	return timed
+}
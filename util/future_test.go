@@ -0,0 +1,207 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/distributed-vision/go-resources/util"
+)
+
+// awaitGoroutines waits for the number of running goroutines to fall to
+// baseline, failing if it doesn't within a second
+func awaitGoroutines(t *testing.T, test string, baseline int) {
+	deadline := time.Now().Add(time.Second)
+
+	for runtime.NumGoroutine() > baseline {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("%s: %d goroutines leaked:\n%s", test, runtime.NumGoroutine()-baseline, buf[:runtime.Stack(buf, true)])
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// blocked returns a future which only completes when it is cancelled
+func blocked(callContext context.Context) *util.Future[int] {
+	return util.Go(callContext, func(callContext context.Context) (int, error) {
+		<-callContext.Done()
+		return 0, callContext.Err()
+	})
+}
+
+func after(delay time.Duration, value int, err error) *util.Future[int] {
+	return util.Go(context.Background(), func(callContext context.Context) (int, error) {
+		select {
+		case <-time.After(delay):
+			return value, err
+		case <-callContext.Done():
+			return 0, callContext.Err()
+		}
+	})
+}
+
+func TestPromise(t *testing.T) {
+	promise := util.NewPromise[int]()
+
+	if !promise.Resolve(1) || promise.Reject(fmt.Errorf("late")) {
+		t.Fatal("TestPromise: Expected only the first completion to succeed")
+	}
+
+	if value, err := promise.Future().Await(); err != nil || value != 1 {
+		t.Fatal("TestPromise: Await failed:", value, err)
+	}
+
+	if value, err := util.Await(promise.Future().Channels()); err != nil || value != 1 {
+		t.Fatal("TestPromise: Channels failed:", value, err)
+	}
+
+	if err := util.AwaitError(util.Rejected[int](fmt.Errorf("failed")).ErrorChannel()); err == nil {
+		t.Fatal("TestPromise: Expected ErrorChannel to deliver error")
+	}
+}
+
+func TestThen(t *testing.T) {
+	future := util.Then(util.Resolved(2), func(value int) (string, error) {
+		return fmt.Sprint(value * 2), nil
+	})
+
+	if value, err := future.Await(); err != nil || value != "4" {
+		t.Fatal("TestThen: Await failed:", value, err)
+	}
+
+	called := false
+	future = util.Then(util.Rejected[int](fmt.Errorf("failed")), func(value int) (string, error) {
+		called = true
+		return "", nil
+	})
+
+	if _, err := future.Await(); err == nil || called {
+		t.Fatal("TestThen: Expected error to pass through without calling next")
+	}
+}
+
+func TestAll(t *testing.T) {
+	values, err := util.All(after(20*time.Millisecond, 1, nil), util.Resolved(2), after(0, 3, nil)).Await()
+
+	if err != nil || fmt.Sprint(values) != "[1 2 3]" {
+		t.Fatal("TestAll: Await failed:", values, err)
+	}
+
+	baseline := runtime.NumGoroutine()
+	pending := blocked(context.Background())
+
+	if _, err := util.All(pending, after(0, 0, fmt.Errorf("failed"))).Await(); err == nil {
+		t.Fatal("TestAll: Expected All to fail")
+	}
+
+	if _, err := pending.Await(); !errors.Is(err, context.Canceled) {
+		t.Fatal("TestAll: Expected pending future to be cancelled, got:", err)
+	}
+
+	awaitGoroutines(t, "TestAll", baseline)
+}
+
+func TestAny(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	pending := blocked(context.Background())
+
+	value, err := util.Any(pending, after(0, 0, fmt.Errorf("failed")), after(10*time.Millisecond, 2, nil)).Await()
+
+	if err != nil || value != 2 {
+		t.Fatal("TestAny: Await failed:", value, err)
+	}
+
+	awaitGoroutines(t, "TestAny", baseline)
+
+	if _, err := util.Any(util.Rejected[int](fmt.Errorf("a")), util.Rejected[int](fmt.Errorf("b"))).Await(); err == nil {
+		t.Fatal("TestAny: Expected Any to fail when all futures fail")
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	if _, err := util.WithTimeout(blocked(context.Background()), 10*time.Millisecond).Await(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("TestWithTimeout: Expected deadline exceeded, got:", err)
+	}
+
+	if value, err := util.WithTimeout(util.Resolved(1), time.Second).Await(); err != nil || value != 1 {
+		t.Fatal("TestWithTimeout: Await failed:", value, err)
+	}
+
+	awaitGoroutines(t, "TestWithTimeout", baseline)
+}
+
+func TestAwaitContext(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	awaitContext, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	future := blocked(context.Background())
+
+	if _, err := future.AwaitContext(awaitContext); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("TestAwaitContext: Expected deadline exceeded, got:", err)
+	}
+
+	select {
+	case <-future.Done():
+		t.Fatal("TestAwaitContext: Expected future to be left running")
+	default:
+	}
+
+	future.Cancel()
+
+	awaitGoroutines(t, "TestAwaitContext", baseline)
+}
+
+func TestFromChannels(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	channelContext, cancel := context.WithCancel(context.Background())
+
+	// nothing is ever delivered on the channels
+	future := util.FromChannels(channelContext, make(chan int), make(chan error))
+	cancel()
+
+	if _, err := future.Await(); !errors.Is(err, context.Canceled) {
+		t.Fatal("TestFromChannels: Expected cancellation, got:", err)
+	}
+
+	cres, cerr := make(chan int), make(chan error)
+	close(cres)
+	close(cerr)
+
+	if _, err := util.FromChannels(context.Background(), cres, cerr).Await(); err == nil {
+		t.Fatal("TestFromChannels: Expected closed channels to fail")
+	}
+
+	awaitGoroutines(t, "TestFromChannels", baseline)
+}
+
+func TestChannels(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	cres, cerr := util.Resolved(1).Channels()
+
+	if runtime.NumGoroutine() != baseline {
+		t.Fatal("TestChannels: Channels of a completed future should not start a goroutine")
+	}
+
+	if value, err := util.Await(cres, cerr); err != nil || value != 1 {
+		t.Fatal("TestChannels: Channels failed:", value, err)
+	}
+
+	promise := util.NewPromise[int]()
+	cres, cerr = promise.Future().Channels()
+	promise.Future().Cancel()
+
+	if _, err := util.Await(cres, cerr); !errors.Is(err, context.Canceled) {
+		t.Fatal("TestChannels: Expected cancellation, got:", err)
+	}
+
+	awaitGoroutines(t, "TestChannels", baseline)
+}
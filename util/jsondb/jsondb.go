@@ -2,7 +2,9 @@ package jsondb
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 
@@ -35,8 +37,16 @@ func (this *row) UnmarshalJSON(b []byte) error {
 	if err != nil {
 		return err
 	}
-	this.index = uint(array[0].(float64))
-	this.key = array[1].(string)
+	if len(array) != 3 {
+		return fmt.Errorf("Invalid row: %s", string(b))
+	}
+	index, indexOk := array[0].(float64)
+	key, keyOk := array[1].(string)
+	if !indexOk || !keyOk {
+		return fmt.Errorf("Invalid row: %s", string(b))
+	}
+	this.index = uint(index)
+	this.key = key
 	this.val = array[2]
 	return nil
 }
@@ -45,6 +55,10 @@ type entry struct {
 	position uint
 	block    uint
 	row      *row
+	// prior is the entry replaced by this one, it is kept until this entry's
+	// row is written so that a failed write can restore it
+	prior   *entry
+	written bool
 }
 
 type JsonDb struct {
@@ -57,6 +71,12 @@ type JsonDb struct {
 	allocMutex  sync.Mutex
 	taskQueue   chan func()
 	syncOnWrite bool
+	// pending holds the latest entry staged for each key whose row hasn't
+	// been written
+	pending map[string]*entry
+	// shared counts the rows in each block written by SetAll, the block is
+	// freed when all of them have been replaced
+	shared map[uint]int
 }
 
 func NewJsonDb(storage util.Storage, syncOnWrite bool) *JsonDb {
@@ -67,7 +87,9 @@ func NewJsonDb(storage util.Storage, syncOnWrite bool) *JsonDb {
 		lastIndex:   0,
 		entries:     make(map[string]*entry),
 		freelists:   [][]uint{},
-		syncOnWrite: syncOnWrite}
+		syncOnWrite: syncOnWrite,
+		pending:     make(map[string]*entry),
+		shared:      make(map[uint]int)}
 }
 
 func (this *JsonDb) Open() chan error {
@@ -116,110 +138,280 @@ func (this *JsonDb) Set(key string, val interface{}) chan error {
 	this.entryMutex.Lock()
 	this.allocMutex.Lock()
 
-	var ent = this.entries[key]
-	var oldFreelist *[]uint
-	var oldPointer uint
+	write := this.stage([]Write{{key, val}})
+
+	this.entryMutex.Unlock()
+	this.allocMutex.Unlock()
+
+	return this.enqueue(write)
+}
+
+// ErrIndexMismatch is returned by SetIfIndex when the key has been written
+// since the expected index
+var ErrIndexMismatch = errors.New("Index mismatch")
+
+// SetIfIndex sets the value of key if the index of the write which last set
+// it is index, an index of 0 matches only a missing key. It returns the
+// key's new index, or its current index if it doesn't match, in which case
+// the error channel delivers ErrIndexMismatch
+func (this *JsonDb) SetIfIndex(key string, val interface{}, index uint) (uint, chan error) {
+	if !this.IsOpen() {
+		return 0, util.Rejected[struct{}](fmt.Errorf("database is not open")).ErrorChannel()
+	}
+
+	this.entryMutex.Lock()
+	this.allocMutex.Lock()
+
+	var current uint
+
+	if ent, ok := this.entries[key]; ok {
+		current = ent.row.index
+	}
+
+	if current != index {
+		this.entryMutex.Unlock()
+		this.allocMutex.Unlock()
+		return current, util.Rejected[struct{}](ErrIndexMismatch).ErrorChannel()
+	}
+
+	write := this.stage([]Write{{key, val}})
+	written := this.lastIndex
+
+	this.entryMutex.Unlock()
+	this.allocMutex.Unlock()
+
+	return written, this.enqueue(write)
+}
 
-	if ent != nil {
-		oldPointer = ent.position
-		oldFreelist = &this.freelists[ent.block]
-		ent.row.val = val
-	} else {
-		ent = &entry{
-			position: 0,
-			block:    0,
-			row:      &row{0, key, val}}
-		this.entries[key] = ent
+// Write is a key and value set by SetAll, a nil value deletes the key
+type Write struct {
+	Key   string
+	Value interface{}
+}
+
+// SetAll applies writes together, readers see either none or all of them.
+// If a key is written more than once its last value is set. The rows are
+// written to storage as a single record, which is ignored when the database
+// is opened if it was only partly written, and if the write fails the
+// previous values are restored
+func (this *JsonDb) SetAll(writes []Write) chan error {
+	cerr := make(chan error, 1)
+
+	if !this.IsOpen() {
+		cerr <- fmt.Errorf("database is not open")
+		close(cerr)
+		return cerr
 	}
 
-	this.lastIndex++
-	ent.row.index = this.lastIndex
-	if val == nil {
-		delete(this.entries, key)
+	last := make(map[string]int, len(writes))
+
+	for index, write := range writes {
+		last[write.Key] = index
+	}
+
+	deduplicated := make([]Write, 0, len(last))
+
+	for index, write := range writes {
+		if last[write.Key] == index {
+			deduplicated = append(deduplicated, write)
+		}
 	}
 
+	this.entryMutex.Lock()
+	this.allocMutex.Lock()
+
+	write := this.stage(deduplicated)
+
 	this.entryMutex.Unlock()
 	this.allocMutex.Unlock()
 
+	return this.enqueue(write)
+}
+
+// enqueue queues a task which calls write
+func (this *JsonDb) enqueue(write func() error) chan error {
+	cerr := make(chan error, 1)
+
 	this.taskQueue <- func() {
+		defer close(cerr)
+
+		if err := write(); err != nil {
+			cerr <- err
+		}
+	}
+
+	return cerr
+}
+
+// stage updates the entries for writes, it must be called with the entry and
+// alloc mutexes held. It returns a function which writes their rows to
+// storage as a single record, which must be called from the task queue
+// without the mutexes held. If the record can't be written the entries it
+// staged are restored, unless they have been replaced by a later write
+func (this *JsonDb) stage(writes []Write) func() error {
+	staged := make([]*entry, len(writes))
+
+	for index, write := range writes {
+		prior, ok := this.pending[write.Key]
+
+		if !ok {
+			prior = this.entries[write.Key]
+		}
+
+		this.lastIndex++
+		ent := &entry{row: &row{this.lastIndex, write.Key, write.Value}, prior: prior}
+
+		if write.Value == nil {
+			delete(this.entries, write.Key)
+		} else {
+			this.entries[write.Key] = ent
+		}
+
+		this.pending[write.Key] = ent
+		staged[index] = ent
+	}
+
+	return func() error {
+		if len(staged) == 0 {
+			return nil
+		}
+
+		err := this.writeRecord(staged)
+
+		this.entryMutex.Lock()
+		defer this.entryMutex.Unlock()
 		this.allocMutex.Lock()
-		defer close(cerr) //fmt.Printf("row=%v\n", ent.row)
+		defer this.allocMutex.Unlock()
 
-		row, err := json.Marshal(&ent.row)
-		if err == nil {
-			buf := bytes.Join([][]byte{[]byte{TAB}, row, []byte{NEWLINE}}, []byte{})
-			//fmt.Printf("buf=%v\n", buf)
+		for _, ent := range staged {
+			latest := this.pending[ent.row.key] == ent
 
-			if uint(len(buf)) > (BLOCK_SIZE << ent.block) {
-				ent.block = nextBlockSize(uint(len(buf)))
+			if latest {
+				delete(this.pending, ent.row.key)
 			}
 
-			ent.position = this.alloc(ent.block)
+			// the rows of entries whose writes failed were never stored, so
+			// the entry to free or restore is the last one written
+			prior := ent.prior
 
-			err = this.write(buf, ent)
-			if oldFreelist != nil {
-				*oldFreelist = append(*oldFreelist, oldPointer)
+			for prior != nil && !prior.written {
+				prior = prior.prior
+			}
+
+			if err == nil {
+				ent.prior = nil
+				ent.written = true
+
+				if prior != nil {
+					this.release(prior)
+				}
+			} else if latest {
+				if prior == nil || prior.row.val == nil {
+					delete(this.entries, ent.row.key)
+				} else {
+					this.entries[ent.row.key] = prior
+				}
 			}
-		} else {
-			cerr <- err
 		}
 
-		this.allocMutex.Unlock()
+		return err
 	}
-
-	return cerr
 }
 
-func (this *JsonDb) Delete(key string) chan error {
-	return this.Set(key, nil)
-}
+// writeRecord writes the rows of entries to storage on a single line, so
+// that a partly written record can't be parsed
+func (this *JsonDb) writeRecord(entries []*entry) error {
+	var record interface{} = entries[0].row
 
-func (this *JsonDb) Clear() chan error {
-	cErrOut := make(chan error, 1)
+	if len(entries) > 1 {
+		rows := make([]*row, len(entries))
 
-	if !this.IsOpen() {
-		cErrOut <- fmt.Errorf("database is not open")
-		close(cErrOut)
-		return cErrOut
+		for index, ent := range entries {
+			rows[index] = ent.row
+		}
+
+		record = rows
 	}
 
-	var wg sync.WaitGroup
-	errors := make([]error, 0)
+	data, err := json.Marshal(record)
 
-	delete := func(key string) {
-		defer wg.Done()
+	if err != nil {
+		return err
+	}
 
-		cerr := this.Delete(key)
+	buf := bytes.Join([][]byte{[]byte{TAB}, data, []byte{NEWLINE}}, []byte{})
 
-		if cerr == nil {
-			errors = append(errors, fmt.Errorf("Clear Failed: Error channel is undefined"))
-		}
+	this.allocMutex.Lock()
+	defer this.allocMutex.Unlock()
 
-		if err, ok := <-cerr; ok {
-			errors = append(errors, err)
+	block := nextBlockSize(uint(len(buf)))
+	position := this.alloc(block)
+
+	if err := this.write(buf, position); err != nil {
+		this.freelists[block] = append(this.freelists[block], position)
+		return err
+	}
+
+	for _, ent := range entries {
+		ent.position, ent.block = position, block
+	}
+
+	if len(entries) > 1 {
+		this.shared[position] = len(entries)
+	}
+
+	return nil
+}
+
+// release frees the block holding an entry's row, blocks holding the rows of
+// a record are freed when the last of them is released. It must be called
+// with the alloc mutex held
+func (this *JsonDb) release(ent *entry) {
+	if count, ok := this.shared[ent.position]; ok {
+		if count > 1 {
+			this.shared[ent.position] = count - 1
 			return
 		}
+
+		delete(this.shared, ent.position)
+	}
+
+	for uint(len(this.freelists)) <= ent.block {
+		this.freelists = append(this.freelists, []uint{})
+	}
+
+	this.freelists[ent.block] = append(this.freelists[ent.block], ent.position)
+}
+
+func (this *JsonDb) Delete(key string) chan error {
+	return this.Set(key, nil)
+}
+
+func (this *JsonDb) Clear() chan error {
+	if !this.IsOpen() {
+		return util.Rejected[struct{}](fmt.Errorf("database is not open")).ErrorChannel()
 	}
 
-	wg.Add(len(this.entries))
+	this.entryMutex.Lock()
 	keys := make([]string, 0, len(this.entries))
 	for key, _ := range this.entries {
 		keys = append(keys, key)
 	}
+	this.entryMutex.Unlock()
+
+	deletes := make([]*util.Future[struct{}], 0, len(keys))
 
 	for _, key := range keys {
-		go delete(key)
+		deletes = append(deletes, util.FromErrorChannel(context.Background(), this.Delete(key)))
 	}
 
-	go func() {
-		wg.Wait()
-		if len(errors) > 0 {
-			cErrOut <- fmt.Errorf("Clear failed with the following errors %v", errors)
+	return util.Go(context.Background(), func(context.Context) (struct{}, error) {
+		if _, err := util.All(deletes...).Await(); err != nil {
+			return struct{}{}, fmt.Errorf("Clear Failed: %v", err)
 		}
-		close(cErrOut)
-	}()
 
-	return cErrOut
+		return struct{}{}, nil
+	}).ErrorChannel()
 }
 
 func (this *JsonDb) Get(key string) (interface{}, bool) {
@@ -237,6 +429,23 @@ func (this *JsonDb) Get(key string) (interface{}, bool) {
 	return nil, false
 }
 
+// GetIndexed returns the value of key with the index of the write which set
+// it, indexes increase with every write
+func (this *JsonDb) GetIndexed(key string) (interface{}, uint, bool) {
+	if !this.IsOpen() {
+		return nil, 0, false
+	}
+
+	this.entryMutex.Lock()
+	defer this.entryMutex.Unlock()
+
+	if entry, ok := this.entries[key]; ok {
+		return entry.row.val, entry.row.index, true
+	}
+
+	return nil, 0, false
+}
+
 func (this *JsonDb) Has(key string) bool {
 	if !this.IsOpen() {
 		return false
@@ -254,9 +463,18 @@ func (this *JsonDb) Len() int {
 }
 
 func (this *JsonDb) ForEach(callback func(key string, val interface{})) {
+	this.ForEachIndexed(func(key string, val interface{}, index uint) {
+		callback(key, val)
+	})
+}
+
+// ForEachIndexed calls callback with each key, its value and the index of
+// the write which set it
+func (this *JsonDb) ForEachIndexed(callback func(key string, val interface{}, index uint)) {
 	type entry struct {
 		key   string
 		value interface{}
+		index uint
 	}
 
 	this.entryMutex.Lock()
@@ -264,13 +482,13 @@ func (this *JsonDb) ForEach(callback func(key string, val interface{})) {
 	entries := make([]entry, 0, len(this.entries))
 
 	for k, e := range this.entries {
-		entries = append(entries, entry{k, e.row.val})
+		entries = append(entries, entry{k, e.row.val, e.row.index})
 	}
 
 	this.entryMutex.Unlock()
 
 	for _, entry := range entries {
-		callback(entry.key, entry.value)
+		callback(entry.key, entry.value, entry.index)
 	}
 }
 
@@ -297,6 +515,8 @@ func (this *JsonDb) Close() chan error {
 		this.lastIndex = 0
 		this.entries = make(map[string]*entry)
 		this.freelists = [][]uint{}
+		this.pending = make(map[string]*entry)
+		this.shared = make(map[uint]int)
 	}
 
 	return cErrOut
@@ -352,25 +572,26 @@ func (this *JsonDb) parseDatabase(data []byte) error {
 
 		if data[i] == '\n' {
 			var buf = data[pointer:i]
-			var row = tryParse(buf)
-
-			if row != nil {
+			// the rows of a record share its block
+			for _, row := range tryParse(buf) {
 				var entry = &entry{
 					position: uint(pointer),
-					block:    nextBlockSize(uint(len(buf))),
-					row:      row}
+					block:    nextBlockSize(uint(len(buf) + 1)),
+					row:      row,
+					written:  true}
 				entries = append(entries, entry)
 			}
 			pointer = uint(i + 1)
 		}
 	}
 
+	// the newest row for a key decides its value, so a tombstone only
+	// deletes the rows written before it
+	newest := make(map[string]*entry)
+
 	for _, entry := range entries {
-		var key = entry.row.key
-		if lentry, _ := latest[key]; lentry == nil || lentry.row.index < entry.row.index {
-			if entry.row.val != nil {
-				latest[key] = entry
-			}
+		if current := newest[entry.row.key]; current == nil || current.row.index < entry.row.index {
+			newest[entry.row.key] = entry
 		}
 
 		if entry.row.index > lastIndex {
@@ -378,28 +599,41 @@ func (this *JsonDb) parseDatabase(data []byte) error {
 		}
 	}
 
-	filtered := make([]*entry, 0, len(entries))
-
-	for _, entry := range entries {
-		if latest[entry.row.key] != entry {
-			filtered = append(filtered, entry)
+	for key, entry := range newest {
+		if entry.row.val != nil {
+			latest[key] = entry
+		} else {
+			delete(latest, key)
 		}
 	}
 
-	entries = filtered
+	// older rows are free, the newest row is kept even if it is a tombstone
+	// as the rows it deletes may still be readable in freed blocks
+	occupied := make([]*entry, 0, len(newest))
+	rows := make(map[uint]int)
 
 	for _, entry := range entries {
-		//fmt.Printf("filt [%v]=%v,latest []=%v\n", entry.row.val, entry, latest[entry.row.key])
-		if entry.row.val == nil {
-			delete(latest, entry.row.key)
+		if newest[entry.row.key] == entry {
+			if rows[entry.position] == 0 {
+				occupied = append(occupied, entry)
+			}
+			rows[entry.position]++
+		}
+	}
+
+	for position, count := range rows {
+		if count > 1 {
+			this.shared[position] = count
 		}
 	}
 
 	this.lastIndex = lastIndex
-	this.populateFreelist(entries)
+	this.populateFreelist(occupied)
 	return nil
 }
 
+// populateFreelist frees the space between the occupied entries, which are
+// ordered by position
 func (this *JsonDb) populateFreelist(entries []*entry) {
 
 	var free = func(from, to, block uint) uint {
@@ -433,10 +667,10 @@ func (this *JsonDb) populateFreelist(entries []*entry) {
 	}
 }
 
-func (this *JsonDb) write(buf []byte, entry *entry) error {
+func (this *JsonDb) write(buf []byte, position uint) error {
 
 	//fmt.Printf("write=%s\n", string(buf))
-	err := this.storage.Write(buf, entry.position)
+	err := this.storage.Write(buf, position)
 
 	if err != nil {
 		return err
@@ -460,13 +694,20 @@ func nextBlockSize(length uint) uint {
 	return i
 }
 
-func tryParse(data []byte) *row {
+// tryParse parses a line holding a row, or the rows written by SetAll,
+// lines which can't be parsed hold no rows
+func tryParse(data []byte) []*row {
 	var r row
-	err := json.Unmarshal(data, &r)
 
-	if err != nil {
+	if err := json.Unmarshal(data, &r); err == nil {
+		return []*row{&r}
+	}
+
+	var rows []*row
+
+	if err := json.Unmarshal(data, &rows); err != nil {
 		return nil
 	}
 
-	return &r
+	return rows
 }
@@ -1,7 +1,10 @@
 package jsondb
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
@@ -368,3 +371,265 @@ func TestBigWrite(t *testing.T) {
 		t.Error("Failed to update doc")
 	}
 }
+
+func TestSetAll(t *testing.T) {
+	file, err := reset(filepath.Join(os.TempDir(), "test-file-setall.db"))
+
+	if err != nil {
+		t.Fatal("Reset failed:", err)
+	}
+
+	db := NewJsonDb(util.NewFileStorage(file, os.ModePerm), true)
+
+	if err := util.AwaitError(db.Open()); err != nil {
+		t.Fatal("Open failed:", err)
+	}
+
+	if err := util.AwaitError(db.Set("a", "1")); err != nil {
+		t.Fatal("Set failed:", err)
+	}
+
+	err = util.AwaitError(db.SetAll([]Write{{"b", "1"}, {"a", nil}, {"b", "2"}, {"c", "3"}}))
+
+	if err != nil {
+		t.Fatal("SetAll failed:", err)
+	}
+
+	if err := util.AwaitError(db.Close()); err != nil {
+		t.Fatal("Close failed:", err)
+	}
+
+	db = NewJsonDb(util.NewFileStorage(file, os.ModePerm), true)
+
+	if err := util.AwaitError(db.Open()); err != nil {
+		t.Fatal("Reopen failed:", err)
+	}
+
+	defer db.Close()
+
+	if val, ok := db.Get("b"); !ok || val != "2" {
+		t.Fatal("TestSetAll: Expected last write to b, got:", val)
+	}
+
+	if db.Has("a") || !db.Has("c") || db.Len() != 2 {
+		t.Fatal("TestSetAll: Unexpected entries after SetAll, len:", db.Len())
+	}
+}
+
+func TestSetIfIndex(t *testing.T) {
+	file, err := reset(filepath.Join(os.TempDir(), "test-file-setifindex.db"))
+
+	if err != nil {
+		t.Fatal("Reset failed:", err)
+	}
+
+	db := NewJsonDb(util.NewFileStorage(file, os.ModePerm), true)
+
+	if err := util.AwaitError(db.Open()); err != nil {
+		t.Fatal("Open failed:", err)
+	}
+
+	defer db.Close()
+
+	index, cerr := db.SetIfIndex("a", "1", 0)
+
+	if err := util.AwaitError(cerr); err != nil || index == 0 {
+		t.Fatal("TestSetIfIndex: create failed:", index, err)
+	}
+
+	if current, cerr := db.SetIfIndex("a", "2", 0); !errors.Is(util.AwaitError(cerr), ErrIndexMismatch) || current != index {
+		t.Fatal("TestSetIfIndex: Expected create of existing key to fail, got index:", current)
+	}
+
+	updated, cerr := db.SetIfIndex("a", "2", index)
+
+	if err := util.AwaitError(cerr); err != nil || updated == index {
+		t.Fatal("TestSetIfIndex: update failed:", updated, err)
+	}
+
+	if _, cerr := db.SetIfIndex("a", "3", index); !errors.Is(util.AwaitError(cerr), ErrIndexMismatch) {
+		t.Fatal("TestSetIfIndex: Expected stale index to fail")
+	}
+
+	if val, current, ok := db.GetIndexed("a"); !ok || val != "2" || current != updated {
+		t.Fatal("TestSetIfIndex: Unexpected value:", val, current, ok)
+	}
+}
+
+func TestDeleteThenSet(t *testing.T) {
+	file, err := reset(filepath.Join(os.TempDir(), "test-file-delset.db"))
+
+	if err != nil {
+		t.Fatal("Reset failed:", err)
+	}
+
+	reopen := func(db *JsonDb) *JsonDb {
+		if db != nil {
+			if err := util.AwaitError(db.Close()); err != nil {
+				t.Fatal("Close failed:", err)
+			}
+		}
+
+		db = NewJsonDb(util.NewFileStorage(file, os.ModePerm), true)
+
+		if err := util.AwaitError(db.Open()); err != nil {
+			t.Fatal("Open failed:", err)
+		}
+
+		return db
+	}
+
+	db := reopen(nil)
+
+	for _, err := range []error{
+		util.AwaitError(db.Set("a", "1")),
+		util.AwaitError(db.Delete("a")),
+		util.AwaitError(db.Set("a", "2")),
+		util.AwaitError(db.Set("b", "1")),
+		util.AwaitError(db.Delete("b"))} {
+		if err != nil {
+			t.Fatal("TestDeleteThenSet: write failed:", err)
+		}
+	}
+
+	db = reopen(db)
+
+	if val, ok := db.Get("a"); !ok || val != "2" {
+		t.Fatal("TestDeleteThenSet: Expected a to be rewritten after delete, got:", val)
+	}
+
+	if db.Has("b") {
+		t.Fatal("TestDeleteThenSet: Expected b to be deleted")
+	}
+
+	// writes after reopening must not reuse the space of live rows
+	for _, key := range []string{"c", "d", "e"} {
+		if err := util.AwaitError(db.Set(key, key)); err != nil {
+			t.Fatal("TestDeleteThenSet: Set failed:", err)
+		}
+	}
+
+	db = reopen(db)
+	defer db.Close()
+
+	if val, ok := db.Get("a"); !ok || val != "2" || db.Has("b") || db.Len() != 4 {
+		t.Fatal("TestDeleteThenSet: Unexpected entries after second reopen, len:", db.Len())
+	}
+}
+
+// failingStorage fails writes while fail is set
+type failingStorage struct {
+	util.Storage
+	fail bool
+}
+
+func (this *failingStorage) Write(buffer []byte, position uint) error {
+	if this.fail {
+		return fmt.Errorf("write failed")
+	}
+
+	return this.Storage.Write(buffer, position)
+}
+
+func TestSetAllFailure(t *testing.T) {
+	file, err := reset(filepath.Join(os.TempDir(), "test-file-setallfail.db"))
+
+	if err != nil {
+		t.Fatal("Reset failed:", err)
+	}
+
+	storage := &failingStorage{Storage: util.NewFileStorage(file, os.ModePerm)}
+	db := NewJsonDb(storage, true)
+
+	if err := util.AwaitError(db.Open()); err != nil {
+		t.Fatal("Open failed:", err)
+	}
+
+	if err := util.AwaitError(db.SetAll([]Write{{"a", "1"}, {"b", "1"}})); err != nil {
+		t.Fatal("SetAll failed:", err)
+	}
+
+	storage.fail = true
+
+	if err := util.AwaitError(db.SetAll([]Write{{"a", "2"}, {"b", nil}, {"c", "2"}})); err == nil {
+		t.Fatal("TestSetAllFailure: Expected SetAll to fail")
+	}
+
+	if val, ok := db.Get("a"); !ok || val != "1" || !db.Has("b") || db.Has("c") {
+		t.Fatal("TestSetAllFailure: Expected failed writes to be restored, got a:", val)
+	}
+
+	storage.fail = false
+
+	if err := util.AwaitError(db.Set("c", "3")); err != nil {
+		t.Fatal("Set failed:", err)
+	}
+
+	if err := util.AwaitError(db.Close()); err != nil {
+		t.Fatal("Close failed:", err)
+	}
+
+	db = NewJsonDb(util.NewFileStorage(file, os.ModePerm), true)
+
+	if err := util.AwaitError(db.Open()); err != nil {
+		t.Fatal("Reopen failed:", err)
+	}
+
+	defer db.Close()
+
+	if val, ok := db.Get("a"); !ok || val != "1" || !db.Has("b") || db.Len() != 3 {
+		t.Fatal("TestSetAllFailure: Unexpected entries after reopen, len:", db.Len())
+	}
+}
+
+func TestPartialSetAll(t *testing.T) {
+	file, err := reset(filepath.Join(os.TempDir(), "test-file-partial.db"))
+
+	if err != nil {
+		t.Fatal("Reset failed:", err)
+	}
+
+	db := NewJsonDb(util.NewFileStorage(file, os.ModePerm), true)
+
+	if err := util.AwaitError(db.Open()); err != nil {
+		t.Fatal("Open failed:", err)
+	}
+
+	for _, err := range []error{
+		util.AwaitError(db.Set("a", "1")),
+		util.AwaitError(db.SetAll([]Write{{"a", "2"}, {"b", "2"}})),
+		util.AwaitError(db.Close())} {
+		if err != nil {
+			t.Fatal("TestPartialSetAll: write failed:", err)
+		}
+	}
+
+	data, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		t.Fatal("ReadFile failed:", err)
+	}
+
+	record := bytes.Index(data, []byte("\t[["))
+
+	if record < 0 {
+		t.Fatal("TestPartialSetAll: Expected SetAll to write a single record:", string(data))
+	}
+
+	// simulate a crash part way through writing the record
+	if err := ioutil.WriteFile(file, data[:record+12], os.ModePerm); err != nil {
+		t.Fatal("WriteFile failed:", err)
+	}
+
+	db = NewJsonDb(util.NewFileStorage(file, os.ModePerm), true)
+
+	if err := util.AwaitError(db.Open()); err != nil {
+		t.Fatal("Reopen failed:", err)
+	}
+
+	defer db.Close()
+
+	if val, ok := db.Get("a"); !ok || val != "1" || db.Has("b") {
+		t.Fatal("TestPartialSetAll: Expected a partly written SetAll to be ignored, got a:", val)
+	}
+}